@@ -1,11 +1,23 @@
 package main
 
 import (
+	"database/sql"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
+	"time"
+
+	"github.com/firerockets/chirpy/internal/database"
+	"github.com/firerockets/chirpy/internal/entities"
+	"github.com/google/uuid"
 )
 
+// chirpRetentionDays is how long deleted chirps can still be restored before
+// the purger removes them for good.
+const chirpRetentionDays = 30
+
 func (apiCfg *apiConfig) metricsHandler(w http.ResponseWriter, req *http.Request) {
 	w.Header().Set("Content-Type", "text/html")
 	w.WriteHeader(http.StatusOK)
@@ -36,3 +48,169 @@ func (apiCfg *apiConfig) resetHandler(w http.ResponseWriter, req *http.Request)
 
 	apiCfg.fileserverHits.Store(0)
 }
+
+// authenticateAdmin writes the error response itself and returns false when
+// the request doesn't come from an admin.
+func (apiCfg *apiConfig) authenticateAdmin(w http.ResponseWriter, req *http.Request) (database.User, bool) {
+	usrID, ok := apiCfg.authenticateUser(w, req)
+
+	if !ok {
+		return database.User{}, false
+	}
+
+	usr, err := apiCfg.dbQueries.GetUserById(req.Context(), usrID)
+
+	if err != nil {
+		respondWithError(w, "Unauthorized", http.StatusUnauthorized)
+		log.Printf("Error looking up user: %s\n", err)
+		return database.User{}, false
+	}
+
+	if !usr.IsAdmin {
+		respondWithError(w, "This operation is forbiden", http.StatusForbidden)
+		log.Printf("User %s tried to run an admin operation\n", usr.ID)
+		return database.User{}, false
+	}
+
+	return usr, true
+}
+
+type denylistedHashtagResponse struct {
+	Tag       string    `json:"tag"`
+	CreatedAt time.Time `json:"created_at"`
+	Reason    string    `json:"reason"`
+}
+
+func (apiCfg *apiConfig) getHashtagDenylistHandler(w http.ResponseWriter, req *http.Request) {
+	if _, ok := apiCfg.authenticateAdmin(w, req); !ok {
+		return
+	}
+
+	denylist, err := apiCfg.dbQueries.GetDenylistedHashtags(req.Context())
+
+	if err != nil {
+		respondWithError(w, "Something went wrong", http.StatusInternalServerError)
+		log.Printf("Error fetching hashtag denylist: %s\n", err)
+		return
+	}
+
+	response := []denylistedHashtagResponse{}
+
+	for _, d := range denylist {
+		response = append(response, denylistedHashtagResponse{
+			Tag:       d.Tag,
+			CreatedAt: d.CreatedAt,
+			Reason:    d.Reason,
+		})
+	}
+
+	respondWithJSON(w, http.StatusOK, response)
+}
+
+func (apiCfg *apiConfig) denylistHashtagHandler(w http.ResponseWriter, req *http.Request) {
+	if _, ok := apiCfg.authenticateAdmin(w, req); !ok {
+		return
+	}
+
+	type parameters struct {
+		Tag    string `json:"tag"`
+		Reason string `json:"reason"`
+	}
+
+	var params parameters
+
+	decoder := json.NewDecoder(req.Body)
+	err := decoder.Decode(&params)
+
+	if err != nil {
+		respondWithError(w, "Something went wrong while parsing the request body", http.StatusBadRequest)
+		log.Printf("Error decoding json request: %s\n", err)
+		return
+	}
+
+	tag := entities.NormalizeTag(params.Tag)
+
+	if tag == "" {
+		respondWithError(w, "Invalid hashtag", http.StatusBadRequest)
+		return
+	}
+
+	denylisted, err := apiCfg.dbQueries.CreateDenylistedHashtag(req.Context(), database.CreateDenylistedHashtagParams{
+		Tag:    tag,
+		Reason: params.Reason,
+	})
+
+	if err != nil {
+		respondWithError(w, "Something went wrong", http.StatusInternalServerError)
+		log.Printf("Error adding hashtag to the denylist: %s\n", err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusCreated, denylistedHashtagResponse{
+		Tag:       denylisted.Tag,
+		CreatedAt: denylisted.CreatedAt,
+		Reason:    denylisted.Reason,
+	})
+}
+
+func (apiCfg *apiConfig) removeDenylistedHashtagHandler(w http.ResponseWriter, req *http.Request) {
+	if _, ok := apiCfg.authenticateAdmin(w, req); !ok {
+		return
+	}
+
+	err := apiCfg.dbQueries.DeleteDenylistedHashtag(req.Context(), entities.NormalizeTag(req.PathValue("tag")))
+
+	if err != nil {
+		respondWithError(w, "Something went wrong", http.StatusInternalServerError)
+		log.Printf("Error removing hashtag from the denylist: %s\n", err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (apiCfg *apiConfig) restoreChirpHandler(w http.ResponseWriter, req *http.Request) {
+	if _, ok := apiCfg.authenticateAdmin(w, req); !ok {
+		return
+	}
+
+	chirpID, err := uuid.Parse(req.PathValue("chirpID"))
+
+	if err != nil {
+		respondWithError(w, "Invalid ID", http.StatusBadRequest)
+		log.Printf("Error validating UUID: %s\n", err)
+		return
+	}
+
+	chirp, err := apiCfg.dbQueries.GetChirpById(req.Context(), chirpID)
+
+	if errors.Is(err, sql.ErrNoRows) {
+		respondWithError(w, "Chirp not found", http.StatusNotFound)
+		return
+	} else if err != nil {
+		respondWithError(w, "Something went wrong", http.StatusInternalServerError)
+		log.Printf("Error getting chirp: %s\n", err)
+		return
+	}
+
+	if !chirp.DeletedAt.Valid {
+		respondWithError(w, "This chirp isn't deleted", http.StatusConflict)
+		return
+	}
+
+	_, err = apiCfg.dbQueries.RestoreChirp(req.Context(), database.RestoreChirpParams{
+		ID:            chirp.ID,
+		RetentionDays: chirpRetentionDays,
+	})
+
+	if errors.Is(err, sql.ErrNoRows) {
+		respondWithError(w, "This chirp was deleted too long ago to be restored", http.StatusGone)
+		return
+	} else if err != nil {
+		respondWithError(w, "Something went wrong", http.StatusInternalServerError)
+		log.Printf("Error restoring chirp: %s\n", err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
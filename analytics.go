@@ -0,0 +1,156 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/firerockets/chirpy/internal/analytics"
+	"github.com/firerockets/chirpy/internal/database"
+	"github.com/firerockets/chirpy/internal/entitlements"
+)
+
+const (
+	defaultAnalyticsDays = 30
+	maxAnalyticsDays     = 90
+)
+
+type chirpAnalyticsResponse struct {
+	ID             string    `json:"id"`
+	Body           string    `json:"body"`
+	PublishedAt    time.Time `json:"published_at"`
+	Impressions    int64     `json:"impressions"`
+	Engagements    int64     `json:"engagements"`
+	EngagementRate float64   `json:"engagement_rate"`
+}
+
+type followerCountResponse struct {
+	Day       string `json:"day"`
+	Followers int64  `json:"followers"`
+	Change    int64  `json:"change"`
+}
+
+type analyticsResponse struct {
+	Since     string                   `json:"since"`
+	Chirps    []chirpAnalyticsResponse `json:"chirps"`
+	Followers []followerCountResponse  `json:"followers"`
+}
+
+// parseAnalyticsDays reads how many days back, today included, the
+// analytics go.
+func parseAnalyticsDays(req *http.Request) (int, error) {
+	days := defaultAnalyticsDays
+
+	if value := req.URL.Query().Get("days"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 1 || parsed > maxAnalyticsDays {
+			return 0, fmt.Errorf("days must be between 1 and %d", maxAnalyticsDays)
+		}
+		days = parsed
+	}
+
+	return days, nil
+}
+
+func (apiCfg *apiConfig) getAnalyticsHandler(w http.ResponseWriter, req *http.Request) {
+	usrID, ok := apiCfg.authenticateUser(w, req)
+
+	if !ok || !apiCfg.requireEntitlement(w, req, usrID, entitlements.Analytics) {
+		return
+	}
+
+	days, err := parseAnalyticsDays(req)
+
+	if err != nil {
+		respondWithError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	limit, offset, err := parsePagination(req)
+
+	if err != nil {
+		respondWithError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	today := analytics.Day(time.Now())
+	since := today.AddDate(0, 0, 1-days)
+
+	chirps, err := apiCfg.dbQueries.GetChirpAnalytics(req.Context(), database.GetChirpAnalyticsParams{
+		Since:  since,
+		UserID: usrID,
+		Limit:  limit,
+		Offset: offset,
+	})
+
+	if err != nil {
+		respondWithError(w, "Error getting analytics", http.StatusInternalServerError)
+		log.Printf("Error fetching chirp analytics: %s\n", err)
+		return
+	}
+
+	// The day before the range is only read for the first day's change.
+	counts, err := apiCfg.dbQueries.GetFollowerCounts(req.Context(), database.GetFollowerCountsParams{
+		UserID: usrID,
+		Since:  since.AddDate(0, 0, -1),
+	})
+
+	if err != nil {
+		respondWithError(w, "Error getting analytics", http.StatusInternalServerError)
+		log.Printf("Error fetching follower counts: %s\n", err)
+		return
+	}
+
+	response := analyticsResponse{
+		Since:     since.Format(time.DateOnly),
+		Chirps:    []chirpAnalyticsResponse{},
+		Followers: followerGrowth(counts, since, today),
+	}
+
+	for _, c := range chirps {
+		rate := 0.0
+		if c.Impressions > 0 {
+			rate = float64(c.Engagements) / float64(c.Impressions)
+		}
+
+		response.Chirps = append(response.Chirps, chirpAnalyticsResponse{
+			ID:             c.ID.String(),
+			Body:           c.Body,
+			PublishedAt:    c.PublishedAt.Time,
+			Impressions:    c.Impressions,
+			Engagements:    c.Engagements,
+			EngagementRate: rate,
+		})
+	}
+
+	respondWithJSON(w, http.StatusOK, response)
+}
+
+// followerGrowth lists the follower count of every day from since to today.
+// The rollup skips users without followers, so a missing day means none.
+func followerGrowth(counts []database.GetFollowerCountsRow, since, today time.Time) []followerCountResponse {
+	byDay := map[string]int64{}
+	for _, c := range counts {
+		byDay[c.Day.Format(time.DateOnly)] = c.Followers
+	}
+
+	growth := []followerCountResponse{}
+	previous := byDay[since.AddDate(0, 0, -1).Format(time.DateOnly)]
+
+	for day := since; !day.After(today); day = day.AddDate(0, 0, 1) {
+		key := day.Format(time.DateOnly)
+		followers := byDay[key]
+
+		growth = append(growth, followerCountResponse{
+			Day:       key,
+			Followers: followers,
+			Change:    followers - previous,
+		})
+
+		previous = followers
+	}
+
+	return growth
+}
@@ -1,17 +1,26 @@
 package main
 
 import (
+	"context"
 	"database/sql"
 	"encoding/json"
+	"errors"
 	"log"
 	"net/http"
 	"sort"
 	"strings"
 	"time"
 
+	"github.com/firerockets/chirpy/internal/activitypub"
 	"github.com/firerockets/chirpy/internal/auth"
+	"github.com/firerockets/chirpy/internal/chirptext"
 	"github.com/firerockets/chirpy/internal/database"
+	"github.com/firerockets/chirpy/internal/entitlements"
+	"github.com/firerockets/chirpy/internal/filter"
+	"github.com/firerockets/chirpy/internal/handles"
+	"github.com/firerockets/chirpy/internal/visibility"
 	"github.com/google/uuid"
+	"github.com/lib/pq"
 )
 
 func (apiCfg *apiConfig) healthzHandler(w http.ResponseWriter, req *http.Request) {
@@ -39,24 +48,30 @@ func (apiCfg *apiConfig) createUserHandler(w http.ResponseWriter, req *http.Requ
 		return
 	}
 
+	params.Handle = strings.TrimPrefix(params.Handle, "@")
+
+	if params.Handle == "" {
+		params.Handle = handles.Generate()
+	} else if !checkHandle(w, params.Handle) {
+		return
+	}
+
 	usr, err := apiCfg.dbQueries.CreateUser(req.Context(), database.CreateUserParams{
 		Email:          params.Email,
 		HashedPassword: hashedPass,
+		Handle:         params.Handle,
 	})
 
-	if err != nil {
+	if isHandleTaken(err) {
+		respondWithError(w, "Handle is already taken", http.StatusConflict)
+		return
+	} else if err != nil {
 		respondWithError(w, "Something went wrong while creating the user in the db", http.StatusInternalServerError)
 		log.Printf("Error creating user: %s\n", err)
 		return
 	}
 
-	respondWithJSON(w, http.StatusCreated, userResponse{
-		ID:          usr.ID.String(),
-		CreatedAt:   usr.CreatedAt,
-		UpdatedAt:   usr.UpdatedAt,
-		Email:       usr.Email,
-		IsChirpyRed: usr.IsChirpyRed,
-	})
+	respondWithJSON(w, http.StatusCreated, apiCfg.newUserResponse(usr, entitlements.Free()))
 
 	log.Println("User created sucessfully.")
 }
@@ -114,18 +129,99 @@ func (apiCfg *apiConfig) updateUserHandler(w http.ResponseWriter, req *http.Requ
 		return
 	}
 
-	respondWithJSON(w, http.StatusOK, userResponse{
-		ID:          usr.ID.String(),
-		CreatedAt:   usr.CreatedAt,
-		UpdatedAt:   usr.UpdatedAt,
-		Email:       usr.Email,
-		IsChirpyRed: usr.IsChirpyRed,
-	})
+	ents, err := apiCfg.userEntitlements(req.Context(), usr.ID)
+
+	if err != nil {
+		respondWithError(w, "Something went wrong", http.StatusInternalServerError)
+		log.Printf("Error looking up entitlements: %s\n", err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, apiCfg.newUserResponse(usr, ents))
 
 	log.Println("User updated sucessfully.")
 
 }
 
+// profileResponse is the public view of a user, so it leaves out the email.
+type profileResponse struct {
+	ID           string          `json:"id"`
+	CreatedAt    time.Time       `json:"created_at"`
+	IsChirpyRed  bool            `json:"is_chirpy_red"`
+	Handle       string          `json:"handle"`
+	DisplayName  string          `json:"display_name"`
+	Bio          string          `json:"bio"`
+	AvatarURL    string          `json:"avatar_url,omitempty"`
+	PinnedChirps []chirpResponse `json:"pinned_chirps"`
+}
+
+func (apiCfg *apiConfig) getUserHandler(w http.ResponseWriter, req *http.Request) {
+	usr, err := apiCfg.lookupUser(req.Context(), req.PathValue("handle"))
+
+	if errors.Is(err, sql.ErrNoRows) {
+		respondWithError(w, "User not found", http.StatusNotFound)
+		return
+	} else if err != nil {
+		respondWithError(w, "Something went wrong", http.StatusInternalServerError)
+		log.Printf("Error looking up user: %s\n", err)
+		return
+	}
+
+	viewerID := apiCfg.viewerID(req)
+	rel, err := apiCfg.relations(req.Context(), viewerID)
+
+	if err != nil {
+		respondWithError(w, "Something went wrong", http.StatusInternalServerError)
+		log.Printf("Error loading blocks and mutes: %s\n", err)
+		return
+	}
+
+	if !rel.CanSeeUser(usr.ID, visibility.Direct) {
+		respondWithError(w, "User not found", http.StatusNotFound)
+		return
+	}
+
+	if usr.SuspendedAt.Valid {
+		respondWithTombstone(w, usr.ID, usr.SuspendedAt.Time, "This account is suspended")
+		return
+	}
+
+	pinned, err := apiCfg.dbQueries.GetPinnedChirpsByUserId(req.Context(), usr.ID)
+
+	if err != nil {
+		respondWithError(w, "Something went wrong", http.StatusInternalServerError)
+		log.Printf("Error getting pinned chirps: %s\n", err)
+		return
+	}
+
+	pinnedResponse, err := apiCfg.chirpResponses(req.Context(), pinned, viewerID, visibility.Direct)
+
+	if err != nil {
+		respondWithError(w, "Something went wrong", http.StatusInternalServerError)
+		log.Printf("Error building chirps response: %s\n", err)
+		return
+	}
+
+	ents, err := apiCfg.userEntitlements(req.Context(), usr.ID)
+
+	if err != nil {
+		respondWithError(w, "Something went wrong", http.StatusInternalServerError)
+		log.Printf("Error looking up entitlements: %s\n", err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, profileResponse{
+		ID:           usr.ID.String(),
+		CreatedAt:    usr.CreatedAt,
+		IsChirpyRed:  ents.Plan == entitlements.PlanChirpyRed,
+		Handle:       usr.Handle,
+		DisplayName:  usr.DisplayName,
+		Bio:          usr.Bio,
+		AvatarURL:    apiCfg.avatarURL(usr),
+		PinnedChirps: pinnedResponse,
+	})
+}
+
 func (apiCfg *apiConfig) loginHandler(w http.ResponseWriter, req *http.Request) {
 	type loginRequest struct {
 		Password string `json:"password"`
@@ -159,7 +255,20 @@ func (apiCfg *apiConfig) loginHandler(w http.ResponseWriter, req *http.Request)
 		return
 	}
 
-	jwt, err := auth.MakeJWT(user.ID, apiCfg.secret, time.Duration(1)*time.Hour)
+	if user.SuspendedAt.Valid {
+		respondWithError(w, "This account is suspended", http.StatusForbidden)
+		return
+	}
+
+	ents, err := apiCfg.userEntitlements(req.Context(), user.ID)
+
+	if err != nil {
+		respondWithError(w, "Something went wrong", http.StatusInternalServerError)
+		log.Printf("Error looking up entitlements: %s\n", err)
+		return
+	}
+
+	jwt, err := auth.MakeJWT(user.ID, ents.Plan, ents.Names(), apiCfg.secret, time.Duration(1)*time.Hour)
 
 	if err != nil {
 		respondWithError(w, "Something went wrong", http.StatusInternalServerError)
@@ -189,23 +298,15 @@ func (apiCfg *apiConfig) loginHandler(w http.ResponseWriter, req *http.Request)
 	}
 
 	type loginResponse struct {
-		ID           string    `json:"id"`
-		CreatedAt    time.Time `json:"created_at"`
-		UpdatedAt    time.Time `json:"updated_at"`
-		Email        string    `json:"email"`
-		Token        string    `json:"token"`
-		RefreshToken string    `json:"refresh_token"`
-		IsChirpyRed  bool      `json:"is_chirpy_red"`
+		userResponse
+		Token        string `json:"token"`
+		RefreshToken string `json:"refresh_token"`
 	}
 
 	respondWithJSON(w, http.StatusOK, loginResponse{
-		ID:           user.ID.String(),
-		CreatedAt:    user.CreatedAt,
-		UpdatedAt:    user.UpdatedAt,
-		Email:        user.Email,
 		Token:        jwt,
 		RefreshToken: refreshTokenString,
-		IsChirpyRed:  user.IsChirpyRed,
+		userResponse: apiCfg.newUserResponse(user, ents),
 	})
 }
 
@@ -244,7 +345,20 @@ func (apiCfg *apiConfig) refreshTokenHandler(w http.ResponseWriter, req *http.Re
 		return
 	}
 
-	jwt, err := auth.MakeJWT(usr.ID, apiCfg.secret, time.Duration(1)*time.Hour)
+	if usr.SuspendedAt.Valid {
+		respondWithError(w, "This account is suspended", http.StatusForbidden)
+		return
+	}
+
+	ents, err := apiCfg.userEntitlements(req.Context(), usr.ID)
+
+	if err != nil {
+		respondWithError(w, "Something went wrong", http.StatusInternalServerError)
+		log.Printf("Error looking up entitlements: %s\n", err)
+		return
+	}
+
+	jwt, err := auth.MakeJWT(usr.ID, ents.Plan, ents.Names(), apiCfg.secret, time.Duration(1)*time.Hour)
 
 	if err != nil {
 		respondWithError(w, "Something went wrong", http.StatusInternalServerError)
@@ -299,7 +413,11 @@ func (apiCfg *apiConfig) createChirpHandler(w http.ResponseWriter, req *http.Req
 	}
 
 	type parameters struct {
-		Body string `json:"body"`
+		Body      string       `json:"body"`
+		MediaIDs  []uuid.UUID  `json:"media_ids"`
+		PublishAt *time.Time   `json:"publish_at"`
+		Draft     bool         `json:"draft"`
+		Poll      *pollRequest `json:"poll"`
 	}
 
 	decoder := json.NewDecoder(req.Body)
@@ -312,17 +430,92 @@ func (apiCfg *apiConfig) createChirpHandler(w http.ResponseWriter, req *http.Req
 		return
 	}
 
-	if len(params.Body) > 140 {
-		respondWithError(w, "Chirp is too long", http.StatusBadRequest)
-		log.Println("Message too long received")
+	usr, err := apiCfg.dbQueries.GetUserById(req.Context(), usrID)
+
+	if err != nil {
+		respondWithError(w, "Unauthorized", http.StatusUnauthorized)
+		log.Printf("Error looking up user: %s\n", err)
+		return
+	}
+
+	if usr.SuspendedAt.Valid {
+		respondWithError(w, "This account is suspended", http.StatusForbidden)
+		return
+	}
+
+	ents, err := apiCfg.userEntitlements(req.Context(), usrID)
+
+	if err != nil {
+		respondWithError(w, "Error creating Chirp", http.StatusInternalServerError)
+		log.Printf("Error looking up entitlements: %s\n", err)
+		return
+	}
+
+	filtered, ok := checkChirpBody(w, apiCfg.contentFilter, ents, params.Body)
+
+	if !ok {
 		return
 	}
 
+	publishAt, ok := checkPublishAt(w, params.PublishAt)
+
+	if !ok {
+		return
+	}
+
+	var pollOptions []string
+
+	if params.Poll != nil {
+		opensAt := time.Now()
+		if publishAt.Valid {
+			opensAt = publishAt.Time
+		}
+
+		var pollMatches []filter.Match
+		pollOptions, pollMatches, ok = checkPoll(w, apiCfg.contentFilter, *params.Poll, opensAt)
+
+		if !ok {
+			return
+		}
+
+		// Flagged words in the options flag the chirp as a whole.
+		filtered.Matches = append(filtered.Matches, pollMatches...)
+	}
+
+	if len(params.MediaIDs) > maxAttachments {
+		respondWithError(w, "A chirp can have up to 4 attachments", http.StatusBadRequest)
+		return
+	}
+
+	for _, mediaID := range params.MediaIDs {
+		medium, err := apiCfg.dbQueries.GetMediaById(req.Context(), mediaID)
+
+		if err != nil || medium.UserID != usrID {
+			respondWithError(w, "Invalid media ID", http.StatusBadRequest)
+			log.Printf("Media %s not found for user: %v\n", mediaID, err)
+			return
+		}
+	}
+
 	log.Println("Valid message received")
 
-	chirp, err := apiCfg.dbQueries.CreateChirp(req.Context(), database.CreateChirpParams{
-		Body:   params.Body,
-		UserID: usrID,
+	tx, err := apiCfg.db.BeginTx(req.Context(), nil)
+
+	if err != nil {
+		respondWithError(w, "Error creating Chirp", http.StatusInternalServerError)
+		log.Printf("Error starting transaction: %s\n", err)
+		return
+	}
+
+	defer tx.Rollback()
+
+	qtx := apiCfg.dbQueries.WithTx(tx)
+
+	chirp, err := qtx.CreateChirp(req.Context(), database.CreateChirpParams{
+		Body:      filtered.Body,
+		UserID:    usrID,
+		IsDraft:   params.Draft,
+		PublishAt: publishAt,
 	})
 
 	if err != nil {
@@ -331,17 +524,119 @@ func (apiCfg *apiConfig) createChirpHandler(w http.ResponseWriter, req *http.Req
 		return
 	}
 
-	respondWithJSON(w, http.StatusCreated, chirpResponse{
-		ID:        chirp.ID.String(),
-		CreatedAt: chirp.CreatedAt,
-		UpdatedAt: chirp.UpdatedAt,
-		Body:      chirp.Body,
-		UserID:    chirp.UserID.String(),
-	})
+	if filtered.Flagged() {
+		err = qtx.CreateChirpFlag(req.Context(), database.CreateChirpFlagParams{
+			ChirpID: chirp.ID,
+			Reason:  flagReason(filtered),
+		})
+
+		if err != nil {
+			respondWithError(w, "Error creating Chirp", http.StatusInternalServerError)
+			log.Printf("Error flagging chirp for review: %s\n", err)
+			return
+		}
+	}
+
+	for i, mediaID := range params.MediaIDs {
+		err = qtx.AttachMediaToChirp(req.Context(), database.AttachMediaToChirpParams{
+			ChirpID:  chirp.ID,
+			MediaID:  mediaID,
+			Position: int32(i),
+		})
+
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == "23505" {
+			respondWithError(w, "Media is already attached to a chirp", http.StatusBadRequest)
+			return
+		} else if err != nil {
+			respondWithError(w, "Error creating Chirp", http.StatusInternalServerError)
+			log.Printf("Error attaching media to chirp: %s\n", err)
+			return
+		}
+	}
+
+	if params.Poll != nil {
+		err = createPoll(req.Context(), qtx, chirp.ID, pollOptions, params.Poll.ClosesAt)
+
+		if err != nil {
+			respondWithError(w, "Error creating Chirp", http.StatusInternalServerError)
+			log.Printf("Error creating poll: %s\n", err)
+			return
+		}
+	}
+
+	err = saveChirpEntities(req.Context(), qtx, chirp)
+
+	if err != nil {
+		respondWithError(w, "Error creating Chirp", http.StatusInternalServerError)
+		log.Printf("Error saving chirp entities: %s\n", err)
+		return
+	}
+
+	err = apiCfg.chirpPublished(req.Context(), qtx, chirp)
+
+	if err != nil {
+		respondWithError(w, "Error creating Chirp", http.StatusInternalServerError)
+		log.Printf("Error following up on published chirp: %s\n", err)
+		return
+	}
+
+	err = tx.Commit()
+
+	if err != nil {
+		respondWithError(w, "Error creating Chirp", http.StatusInternalServerError)
+		log.Printf("Error committing chirp: %s\n", err)
+		return
+	}
+
+	chirpsResponse, err := apiCfg.chirpResponses(req.Context(), []database.Chirp{chirp}, uuid.NullUUID{UUID: usrID, Valid: true}, visibility.Direct)
+
+	if err != nil {
+		respondWithError(w, "Error creating Chirp", http.StatusInternalServerError)
+		log.Printf("Error building chirp response: %s\n", err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusCreated, chirpsResponse[0])
 
 	log.Println("Chirp created in the database")
 }
 
+// checkChirpBody normalizes body and checks it against the content filter
+// and the user's length limit. The length is counted after masking, since
+// the masked body is what gets stored. It writes the error response itself
+// and returns false when the chirp can't be posted.
+func checkChirpBody(w http.ResponseWriter, contentFilter *filter.Filter, ents entitlements.Set, body string) (filter.Result, bool) {
+	filtered := contentFilter.Apply(chirptext.Normalize(body))
+
+	if filtered.Rejected() {
+		respondWithError(w, "Chirp contains words that are not allowed", http.StatusBadRequest)
+		log.Println("Message rejected by the content filter")
+		return filter.Result{}, false
+	}
+
+	length := chirptext.Length(filtered.Body)
+	limit := chirpLengthLimit(ents)
+
+	if length > limit {
+		type tooLongResponse struct {
+			Error  string `json:"error"`
+			Length int    `json:"length"`
+			Limit  int    `json:"limit"`
+		}
+
+		respondWithJSON(w, http.StatusBadRequest, tooLongResponse{
+			Error:  "Chirp is too long",
+			Length: length,
+			Limit:  limit,
+		})
+		log.Println("Message too long received")
+		return filter.Result{}, false
+	}
+
+	return filtered, true
+}
+
 func (apiCfg *apiConfig) getChirpsHandler(w http.ResponseWriter, req *http.Request) {
 	authorId := req.URL.Query().Get("author_id")
 
@@ -366,11 +661,11 @@ func (apiCfg *apiConfig) getChirpsHandler(w http.ResponseWriter, req *http.Reque
 	switch sortDirection {
 	case "asc", "":
 		sort.Slice(chirps, func(i, j int) bool {
-			return chirps[i].CreatedAt.Before(chirps[j].CreatedAt)
+			return chirps[i].PublishedAt.Time.Before(chirps[j].PublishedAt.Time)
 		})
 	case "desc":
 		sort.Slice(chirps, func(i, j int) bool {
-			return chirps[i].CreatedAt.After(chirps[j].CreatedAt)
+			return chirps[i].PublishedAt.Time.After(chirps[j].PublishedAt.Time)
 		})
 	default:
 		respondWithError(w, "Invalid sort parameter", http.StatusForbidden)
@@ -378,21 +673,85 @@ func (apiCfg *apiConfig) getChirpsHandler(w http.ResponseWriter, req *http.Reque
 		return
 	}
 
-	chirpsResponse := []chirpResponse{}
+	if authorId != "" && req.URL.Query().Get("pinned") == "first" {
+		userId, _ := uuid.Parse(authorId)
+		pinned, err := apiCfg.dbQueries.GetPinnedChirpsByUserId(req.Context(), userId)
 
-	for _, c := range chirps {
-		chirpsResponse = append(chirpsResponse, chirpResponse{
-			ID:        c.ID.String(),
-			CreatedAt: c.CreatedAt,
-			UpdatedAt: c.UpdatedAt,
-			Body:      c.Body,
-			UserID:    c.UserID.String(),
-		})
+		if err != nil {
+			respondWithError(w, "Error getting chirps", http.StatusInternalServerError)
+			log.Printf("Error fetching pinned chirps from database: %s\n", err)
+			return
+		}
+
+		chirps = pinnedFirst(chirps, pinned)
+	}
+
+	chirpsResponse, err := apiCfg.chirpResponses(req.Context(), chirps, apiCfg.viewerID(req), visibility.Feed)
+
+	if err != nil {
+		respondWithError(w, "Error getting chirps", http.StatusInternalServerError)
+		log.Printf("Error building chirps response: %s\n", err)
+		return
 	}
 
 	respondWithJSON(w, http.StatusOK, chirpsResponse)
 }
 
+var (
+	errChirpNotFound = errors.New("chirp not found")
+	errChirpDeleted  = errors.New("chirp was deleted")
+	errChirpRemoved  = errors.New("chirp was removed by a moderator")
+)
+
+// viewChirp loads a chirp the way getChirpByIdHandler shows it to viewerID.
+// Chirps the viewer can't see are reported as not found, deleted and removed
+// ones included, so a block or suspension isn't given away by a 410. The
+// chirp is returned with errChirpRemoved for its tombstone.
+func (apiCfg *apiConfig) viewChirp(ctx context.Context, chirpID uuid.UUID, viewerID uuid.NullUUID) (chirpResponse, database.Chirp, error) {
+	chirp, err := apiCfg.dbQueries.GetChirpById(ctx, chirpID)
+
+	if err != nil {
+		log.Printf("Chirp id not found: %s\n", err)
+		return chirpResponse{}, database.Chirp{}, errChirpNotFound
+	}
+
+	gone := (chirp.DeletedAt.Valid && chirp.PublishedAt.Valid) || chirp.RemovedAt.Valid
+
+	if gone {
+		visible, err := apiCfg.canSeeUser(ctx, chirp.UserID, viewerID, visibility.Direct)
+
+		if err != nil {
+			return chirpResponse{}, chirp, err
+		}
+
+		if !visible {
+			log.Println("Chirp is not visible to this viewer")
+			return chirpResponse{}, chirp, errChirpNotFound
+		}
+	}
+
+	if chirp.DeletedAt.Valid && chirp.PublishedAt.Valid {
+		return chirpResponse{}, chirp, errChirpDeleted
+	}
+
+	if chirp.RemovedAt.Valid {
+		return chirpResponse{}, chirp, errChirpRemoved
+	}
+
+	chirpsResponse, err := apiCfg.chirpResponses(ctx, []database.Chirp{chirp}, viewerID, visibility.Direct)
+
+	if err != nil {
+		return chirpResponse{}, chirp, err
+	}
+
+	if len(chirpsResponse) == 0 {
+		log.Println("Chirp is not visible to this viewer")
+		return chirpResponse{}, chirp, errChirpNotFound
+	}
+
+	return chirpsResponse[0], chirp, nil
+}
+
 func (apiCfg *apiConfig) getChirpByIdHandler(w http.ResponseWriter, req *http.Request) {
 	chirpID, err := uuid.Parse(req.PathValue("chirpID"))
 
@@ -402,21 +761,24 @@ func (apiCfg *apiConfig) getChirpByIdHandler(w http.ResponseWriter, req *http.Re
 		return
 	}
 
-	chirp, err := apiCfg.dbQueries.GetChirpById(req.Context(), chirpID)
+	response, chirp, err := apiCfg.viewChirp(req.Context(), chirpID, apiCfg.viewerID(req))
 
-	if err != nil {
+	if errors.Is(err, errChirpNotFound) {
 		respondWithError(w, "Error getting chirp from the database", http.StatusNotFound)
-		log.Printf("Chirp id not found: %s\n", err)
+		return
+	} else if errors.Is(err, errChirpDeleted) {
+		respondWithError(w, "This chirp was deleted", http.StatusGone)
+		return
+	} else if errors.Is(err, errChirpRemoved) {
+		respondWithTombstone(w, chirp.ID, chirp.RemovedAt.Time, "This chirp was removed by a moderator")
+		return
+	} else if err != nil {
+		respondWithError(w, "Error getting chirp from the database", http.StatusInternalServerError)
+		log.Printf("Error building chirp response: %s\n", err)
 		return
 	}
 
-	respondWithJSON(w, http.StatusOK, chirpResponse{
-		ID:        chirp.ID.String(),
-		CreatedAt: chirp.CreatedAt,
-		UpdatedAt: chirp.UpdatedAt,
-		Body:      chirp.Body,
-		UserID:    chirp.UserID.String(),
-	})
+	respondWithJSON(w, http.StatusOK, response)
 
 	log.Printf("Successfuly returned chirp object")
 }
@@ -460,7 +822,23 @@ func (apiCfg *apiConfig) deleteChirpByIdHandler(w http.ResponseWriter, req *http
 		return
 	}
 
-	err = apiCfg.dbQueries.DeleteChirpById(req.Context(), chirpID)
+	if chirp.DeletedAt.Valid {
+		respondWithError(w, "This chirp was deleted", http.StatusGone)
+		return
+	}
+
+	tx, err := apiCfg.db.BeginTx(req.Context(), nil)
+
+	if err != nil {
+		respondWithError(w, "Something went wrong", http.StatusInternalServerError)
+		log.Printf("Error starting transaction: %s\n", err)
+		return
+	}
+	defer tx.Rollback()
+
+	qtx := apiCfg.dbQueries.WithTx(tx)
+
+	err = qtx.DeleteChirpById(req.Context(), chirpID)
 
 	if err != nil {
 		respondWithError(w, "Error getting chirp from the database", http.StatusNotFound)
@@ -468,6 +846,37 @@ func (apiCfg *apiConfig) deleteChirpByIdHandler(w http.ResponseWriter, req *http
 		return
 	}
 
+	if chirp.PublishedAt.Valid {
+		err = apiCfg.federate(req.Context(), qtx, chirp.UserID, activitypub.NewDelete(apiCfg.apURLs, chirp))
+
+		if err != nil {
+			respondWithError(w, "Something went wrong", http.StatusInternalServerError)
+			log.Printf("Error federating chirp deletion: %s\n", err)
+			return
+		}
+	}
+
+	// Deleted chirps keep their row until they're purged, so drop the pin
+	// here or it would keep taking one of the user's slots.
+	err = qtx.DeletePinnedChirp(req.Context(), database.DeletePinnedChirpParams{
+		UserID:  usrID,
+		ChirpID: chirpID,
+	})
+
+	if err != nil {
+		respondWithError(w, "Something went wrong", http.StatusInternalServerError)
+		log.Printf("Error unpinning deleted chirp: %s\n", err)
+		return
+	}
+
+	err = tx.Commit()
+
+	if err != nil {
+		respondWithError(w, "Something went wrong", http.StatusInternalServerError)
+		log.Printf("Error committing transaction: %s\n", err)
+		return
+	}
+
 	w.WriteHeader(http.StatusNoContent)
 }
 
@@ -510,7 +919,12 @@ func (apiCfg *apiConfig) webhookHandler(w http.ResponseWriter, req *http.Request
 		return
 	}
 
-	err = apiCfg.dbQueries.UpgradeUserById(req.Context(), params.Data.UserID)
+	_, err = apiCfg.dbQueries.RenewSubscription(req.Context(), database.RenewSubscriptionParams{
+		UserID:     params.Data.UserID,
+		Plan:       entitlements.PlanChirpyRed,
+		PeriodDays: subscriptionPeriodDays,
+		Source:     entitlements.SourcePolka,
+	})
 
 	if err != nil {
 		respondWithError(w, "User not found", http.StatusNotFound)
@@ -522,45 +936,178 @@ func (apiCfg *apiConfig) webhookHandler(w http.ResponseWriter, req *http.Request
 }
 
 type chirpResponse struct {
-	ID        string    `json:"id"`
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
-	Body      string    `json:"body"`
-	UserID    string    `json:"user_id"`
+	ID          string               `json:"id"`
+	CreatedAt   time.Time            `json:"created_at"`
+	UpdatedAt   time.Time            `json:"updated_at"`
+	Body        string               `json:"body"`
+	UserID      string               `json:"user_id"`
+	Author      authorResponse       `json:"author"`
+	Entities    []chirpEntity        `json:"entities"`
+	Media       []mediaResponse      `json:"media"`
+	Bookmarked  bool                 `json:"bookmarked"`
+	PublishedAt *time.Time           `json:"published_at"`
+	PublishAt   *time.Time           `json:"publish_at,omitempty"`
+	Draft       bool                 `json:"draft,omitempty"`
+	Poll        *pollResponse        `json:"poll"`
+	Pinned      bool                 `json:"pinned"`
+	LinkPreview *linkPreviewResponse `json:"link_preview"`
+}
+
+// chirpResponses builds the responses for chirps as seen by viewerID, which
+// is null for anonymous requests. Every read path goes through here, so it
+// drops the chirps the viewer can't see in the given mode.
+func (apiCfg *apiConfig) chirpResponses(ctx context.Context, chirps []database.Chirp, viewerID uuid.NullUUID, mode visibility.Mode) ([]chirpResponse, error) {
+	rel, err := apiCfg.relations(ctx, viewerID)
+
+	if err != nil {
+		return nil, err
+	}
+
+	chirps = rel.Chirps(chirps, mode)
+
+	authors, err := apiCfg.loadChirpAuthors(ctx, chirps)
+
+	if err != nil {
+		return nil, err
+	}
+
+	// Suspended authors aren't loaded, which drops their chirps too.
+	withAuthor := []database.Chirp{}
+
+	for _, c := range chirps {
+		if _, ok := authors[c.UserID]; ok {
+			withAuthor = append(withAuthor, c)
+		}
+	}
+
+	chirps = withAuthor
+
+	entitiesByChirp, err := apiCfg.loadChirpEntities(ctx, chirps)
+
+	if err != nil {
+		return nil, err
+	}
+
+	mediaByChirp, err := apiCfg.loadChirpMedia(ctx, chirps)
+
+	if err != nil {
+		return nil, err
+	}
+
+	bookmarked, err := apiCfg.loadBookmarkedChirps(ctx, chirps, viewerID)
+
+	if err != nil {
+		return nil, err
+	}
+
+	polls, err := apiCfg.loadChirpPolls(ctx, chirps, viewerID)
+
+	if err != nil {
+		return nil, err
+	}
+
+	pinned, err := apiCfg.loadPinnedChirps(ctx, chirps)
+
+	if err != nil {
+		return nil, err
+	}
+
+	previews, err := apiCfg.loadLinkPreviews(ctx, chirps)
+
+	if err != nil {
+		return nil, err
+	}
+
+	chirpsResponse := []chirpResponse{}
+	viewed := []uuid.UUID{}
+
+	for _, c := range chirps {
+		// Authors looking at their own chirps don't count as an audience.
+		if c.PublishedAt.Valid && (!viewerID.Valid || viewerID.UUID != c.UserID) {
+			viewed = append(viewed, c.ID)
+		}
+
+		chirpEntities := entitiesByChirp[c.ID]
+		if chirpEntities == nil {
+			chirpEntities = []chirpEntity{}
+		}
+
+		chirpMedia := mediaByChirp[c.ID]
+		if chirpMedia == nil {
+			chirpMedia = []mediaResponse{}
+		}
+
+		response := chirpResponse{
+			ID:          c.ID.String(),
+			CreatedAt:   c.CreatedAt,
+			UpdatedAt:   c.UpdatedAt,
+			Body:        c.Body,
+			UserID:      c.UserID.String(),
+			Author:      authors[c.UserID],
+			Entities:    chirpEntities,
+			Media:       chirpMedia,
+			Bookmarked:  bookmarked[c.ID],
+			Draft:       c.IsDraft,
+			Poll:        polls[c.ID],
+			Pinned:      pinned[c.ID],
+			LinkPreview: previews[c.ID],
+		}
+
+		if c.PublishedAt.Valid {
+			response.PublishedAt = &c.PublishedAt.Time
+		}
+
+		if c.PublishAt.Valid {
+			response.PublishAt = &c.PublishAt.Time
+		}
+
+		chirpsResponse = append(chirpsResponse, response)
+	}
+
+	apiCfg.impressions.Add(viewed...)
+
+	return chirpsResponse, nil
 }
 
 type userRequest struct {
 	Password string `json:"password"`
 	Email    string `json:"email"`
+	Handle   string `json:"handle"`
 }
 
 type userResponse struct {
-	ID          string    `json:"id"`
-	CreatedAt   time.Time `json:"created_at"`
-	UpdatedAt   time.Time `json:"updated_at"`
-	Email       string    `json:"email"`
-	IsChirpyRed bool      `json:"is_chirpy_red"`
+	ID            string     `json:"id"`
+	CreatedAt     time.Time  `json:"created_at"`
+	UpdatedAt     time.Time  `json:"updated_at"`
+	Email         string     `json:"email"`
+	IsChirpyRed   bool       `json:"is_chirpy_red"`
+	Plan          string     `json:"plan"`
+	PlanExpiresAt *time.Time `json:"plan_expires_at"`
+	Entitlements  []string   `json:"entitlements"`
+	Handle        string     `json:"handle"`
+	DisplayName   string     `json:"display_name"`
+	Bio           string     `json:"bio"`
+	AvatarURL     string     `json:"avatar_url,omitempty"`
 }
 
-func cleanBody(body string) string {
-	splitted := strings.Split(body, " ")
-
-	for i, word := range splitted {
-		if isCurseWord(strings.ToLower(word)) {
-			splitted[i] = "****"
-		}
+func (apiCfg *apiConfig) newUserResponse(usr database.User, ents entitlements.Set) userResponse {
+	response := userResponse{
+		ID:           usr.ID.String(),
+		CreatedAt:    usr.CreatedAt,
+		UpdatedAt:    usr.UpdatedAt,
+		Email:        usr.Email,
+		IsChirpyRed:  ents.Plan == entitlements.PlanChirpyRed,
+		Plan:         ents.Plan,
+		Entitlements: ents.Names(),
+		Handle:       usr.Handle,
+		DisplayName:  usr.DisplayName,
+		Bio:          usr.Bio,
+		AvatarURL:    apiCfg.avatarURL(usr),
 	}
 
-	return strings.Join(splitted, " ")
-}
-
-func isCurseWord(word string) bool {
-	switch word {
-	case
-		"kerfuffle",
-		"sharbert",
-		"fornax":
-		return true
+	if !ents.ExpiresAt.IsZero() {
+		response.PlanExpiresAt = &ents.ExpiresAt
 	}
-	return false
+
+	return response
 }
@@ -0,0 +1,217 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/firerockets/chirpy/internal/auth"
+	"github.com/firerockets/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+// fakeResult is what fakeConn returns for one query: the rows to scan, in
+// the column order of the generated query.
+type fakeResult [][]driver.Value
+
+// fakeConn answers queries by their sqlc name, so handlers can run against
+// canned rows without a database.
+type fakeConn struct {
+	results map[string]fakeResult
+}
+
+func (c *fakeConn) Prepare(query string) (driver.Stmt, error) {
+	return nil, errors.New("prepared statements aren't supported")
+}
+
+func (c *fakeConn) Close() error { return nil }
+
+func (c *fakeConn) Begin() (driver.Tx, error) {
+	return nil, errors.New("transactions aren't supported")
+}
+
+func (c *fakeConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	name := strings.Fields(strings.TrimPrefix(query, "-- name: "))[0]
+	result, ok := c.results[name]
+	if !ok {
+		return nil, errors.New("unexpected query " + name)
+	}
+	return &fakeRows{rows: result}, nil
+}
+
+// ExecContext reports as many affected rows as the query has canned rows.
+func (c *fakeConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	name := strings.Fields(strings.TrimPrefix(query, "-- name: "))[0]
+	result, ok := c.results[name]
+	if !ok {
+		return nil, errors.New("unexpected query " + name)
+	}
+	return driver.RowsAffected(len(result)), nil
+}
+
+type fakeRows struct {
+	rows fakeResult
+}
+
+// Columns only needs the right count; the generated code scans by position.
+func (r *fakeRows) Columns() []string {
+	if len(r.rows) == 0 {
+		return nil
+	}
+	return make([]string, len(r.rows[0]))
+}
+
+func (r *fakeRows) Close() error { return nil }
+
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if len(r.rows) == 0 {
+		return io.EOF
+	}
+	copy(dest, r.rows[0])
+	r.rows = r.rows[1:]
+	return nil
+}
+
+type fakeDriver struct {
+	mu    sync.Mutex
+	conns map[string]*fakeConn
+}
+
+func (d *fakeDriver) Open(name string) (driver.Conn, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.conns[name], nil
+}
+
+var testDriver = &fakeDriver{conns: map[string]*fakeConn{}}
+
+func init() {
+	sql.Register("chirpyfake", testDriver)
+}
+
+func newTestConfig(t *testing.T, results map[string]fakeResult) *apiConfig {
+	testDriver.mu.Lock()
+	testDriver.conns[t.Name()] = &fakeConn{results: results}
+	testDriver.mu.Unlock()
+
+	db, err := sql.Open("chirpyfake", t.Name())
+	if err != nil {
+		t.Fatalf("Error opening fake database: %s", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	return &apiConfig{
+		db:        db,
+		dbQueries: database.New(db),
+		secret:    "test secret",
+	}
+}
+
+func TestLoginHandler(t *testing.T) {
+	userID := uuid.New()
+	createdAt := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	hash, err := auth.HashPassword("hunter2")
+	if err != nil {
+		t.Fatalf("Error hashing password: %s", err)
+	}
+
+	apiCfg := newTestConfig(t, map[string]fakeResult{
+		"GetUserByEmail": {{
+			userID.String(), createdAt, createdAt, "saul@bettercall.com", hash,
+			false, "saul", "Saul", "", nil, nil,
+		}},
+		"GetActiveSubscription": {},
+		"CreateRefreshToken": {{
+			"refresh", createdAt, createdAt, userID.String(), createdAt.Add(time.Hour), nil,
+		}},
+	})
+
+	body := `{"email": "saul@bettercall.com", "password": "hunter2"}`
+	req := httptest.NewRequest(http.MethodPost, "/api/login", strings.NewReader(body))
+	w := httptest.NewRecorder()
+
+	apiCfg.loginHandler(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", w.Code, w.Body)
+	}
+
+	var response map[string]any
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Error decoding response: %s", err)
+	}
+
+	if response["id"] != userID.String() {
+		t.Errorf("id = %v, want %s", response["id"], userID)
+	}
+	if response["email"] != "saul@bettercall.com" {
+		t.Errorf("email = %v, want saul@bettercall.com", response["email"])
+	}
+	if response["created_at"] != createdAt.Format(time.RFC3339) {
+		t.Errorf("created_at = %v, want %s", response["created_at"], createdAt.Format(time.RFC3339))
+	}
+	if response["token"] == "" || response["refresh_token"] == "" {
+		t.Errorf("Expected both tokens, got %v and %v", response["token"], response["refresh_token"])
+	}
+	if response["plan"] != "free" {
+		t.Errorf("plan = %v, want free", response["plan"])
+	}
+}
+
+func TestGetChirpByIdHidesDeletedChirpsFromBlockedViewers(t *testing.T) {
+	viewerID := uuid.New()
+	authorID := uuid.New()
+	chirpID := uuid.New()
+	now := time.Now()
+
+	cases := map[string]struct {
+		blocked  fakeResult
+		expected int
+	}{
+		"not blocked": {blocked: fakeResult{}, expected: http.StatusGone},
+		"blocked":     {blocked: fakeResult{{authorID.String()}}, expected: http.StatusNotFound},
+	}
+
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			apiCfg := newTestConfig(t, map[string]fakeResult{
+				"GetChirpById": {{
+					chirpID.String(), now, now, "hello", authorID.String(),
+					now, nil, false, nil, now,
+				}},
+				"GetBlockedUserIds": c.blocked,
+				"GetMutedUserIds":   {},
+				"GetUserById": {{
+					authorID.String(), now, now, "author@example.com", "unused",
+					false, "author", "Author", "", nil, nil,
+				}},
+			})
+
+			token, err := auth.MakeJWT(viewerID, "free", nil, apiCfg.secret, time.Hour)
+			if err != nil {
+				t.Fatalf("Error making token: %s", err)
+			}
+
+			req := httptest.NewRequest(http.MethodGet, "/api/chirps/"+chirpID.String(), nil)
+			req.SetPathValue("chirpID", chirpID.String())
+			req.Header.Set("Authorization", "Bearer "+token)
+			w := httptest.NewRecorder()
+
+			apiCfg.getChirpByIdHandler(w, req)
+
+			if w.Code != c.expected {
+				t.Errorf("Expected %d, got %d: %s", c.expected, w.Code, w.Body)
+			}
+		})
+	}
+}
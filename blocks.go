@@ -0,0 +1,293 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/firerockets/chirpy/internal/database"
+	"github.com/firerockets/chirpy/internal/visibility"
+	"github.com/google/uuid"
+)
+
+// relations loads who viewerID blocked, was blocked by or muted. Anonymous
+// viewers have no blocks or mutes. Suspended users are left out by the
+// queries, or by hiddenUser for single lookups.
+func (apiCfg *apiConfig) relations(ctx context.Context, viewerID uuid.NullUUID) (visibility.Relations, error) {
+	if !viewerID.Valid {
+		return visibility.New(viewerID, nil, nil), nil
+	}
+
+	blocked, err := apiCfg.dbQueries.GetBlockedUserIds(ctx, viewerID.UUID)
+
+	if err != nil {
+		return visibility.Relations{}, err
+	}
+
+	muted, err := apiCfg.dbQueries.GetMutedUserIds(ctx, viewerID.UUID)
+
+	if err != nil {
+		return visibility.Relations{}, err
+	}
+
+	return visibility.New(viewerID, blocked, muted), nil
+}
+
+// hiddenUser tells whether userID is suspended, or gone, which hides them
+// from everyone.
+func (apiCfg *apiConfig) hiddenUser(ctx context.Context, userID uuid.UUID) (bool, error) {
+	usr, err := apiCfg.dbQueries.GetUserById(ctx, userID)
+
+	if errors.Is(err, sql.ErrNoRows) {
+		return true, nil
+	} else if err != nil {
+		return false, err
+	}
+
+	return usr.SuspendedAt.Valid, nil
+}
+
+// canSeeUser is canSeeChirp for events that only carry who's behind them.
+func (apiCfg *apiConfig) canSeeUser(ctx context.Context, userID uuid.UUID, viewerID uuid.NullUUID, mode visibility.Mode) (bool, error) {
+	rel, err := apiCfg.relations(ctx, viewerID)
+
+	if err != nil {
+		return false, err
+	}
+
+	if !rel.CanSeeUser(userID, mode) {
+		return false, nil
+	}
+
+	hidden, err := apiCfg.hiddenUser(ctx, userID)
+
+	if err != nil {
+		return false, err
+	}
+
+	return !hidden, nil
+}
+
+// canSeeChirp is for handlers that act on a single chirp, which should look
+// like it doesn't exist to viewers who can't see it.
+func (apiCfg *apiConfig) canSeeChirp(ctx context.Context, chirp database.Chirp, viewerID uuid.NullUUID, mode visibility.Mode) (bool, error) {
+	rel, err := apiCfg.relations(ctx, viewerID)
+
+	if err != nil {
+		return false, err
+	}
+
+	if !rel.CanSeeChirp(chirp, mode) {
+		return false, nil
+	}
+
+	hidden, err := apiCfg.hiddenUser(ctx, chirp.UserID)
+
+	if err != nil {
+		return false, err
+	}
+
+	return !hidden, nil
+}
+
+// targetUser loads the user in the path for a block, mute or report by
+// usrID.
+func (apiCfg *apiConfig) targetUser(w http.ResponseWriter, req *http.Request, usrID uuid.UUID) (database.User, bool) {
+	target, err := apiCfg.lookupUser(req.Context(), req.PathValue("handle"))
+
+	if errors.Is(err, sql.ErrNoRows) {
+		respondWithError(w, "User not found", http.StatusNotFound)
+		return database.User{}, false
+	} else if err != nil {
+		respondWithError(w, "Something went wrong", http.StatusInternalServerError)
+		log.Printf("Error looking up user: %s\n", err)
+		return database.User{}, false
+	}
+
+	if target.ID == usrID {
+		respondWithError(w, "You can't do that to yourself", http.StatusBadRequest)
+		return database.User{}, false
+	}
+
+	return target, true
+}
+
+func (apiCfg *apiConfig) blockUserHandler(w http.ResponseWriter, req *http.Request) {
+	usrID, ok := apiCfg.authenticateUser(w, req)
+
+	if !ok {
+		return
+	}
+
+	target, ok := apiCfg.targetUser(w, req, usrID)
+
+	if !ok {
+		return
+	}
+
+	err := apiCfg.dbQueries.CreateBlock(req.Context(), database.CreateBlockParams{
+		BlockerID: usrID,
+		BlockedID: target.ID,
+	})
+
+	if err != nil {
+		respondWithError(w, "Something went wrong", http.StatusInternalServerError)
+		log.Printf("Error creating block: %s\n", err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (apiCfg *apiConfig) unblockUserHandler(w http.ResponseWriter, req *http.Request) {
+	usrID, ok := apiCfg.authenticateUser(w, req)
+
+	if !ok {
+		return
+	}
+
+	target, ok := apiCfg.targetUser(w, req, usrID)
+
+	if !ok {
+		return
+	}
+
+	err := apiCfg.dbQueries.DeleteBlock(req.Context(), database.DeleteBlockParams{
+		BlockerID: usrID,
+		BlockedID: target.ID,
+	})
+
+	if err != nil {
+		respondWithError(w, "Something went wrong", http.StatusInternalServerError)
+		log.Printf("Error deleting block: %s\n", err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (apiCfg *apiConfig) muteUserHandler(w http.ResponseWriter, req *http.Request) {
+	usrID, ok := apiCfg.authenticateUser(w, req)
+
+	if !ok {
+		return
+	}
+
+	target, ok := apiCfg.targetUser(w, req, usrID)
+
+	if !ok {
+		return
+	}
+
+	err := apiCfg.dbQueries.CreateMute(req.Context(), database.CreateMuteParams{
+		MuterID: usrID,
+		MutedID: target.ID,
+	})
+
+	if err != nil {
+		respondWithError(w, "Something went wrong", http.StatusInternalServerError)
+		log.Printf("Error creating mute: %s\n", err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (apiCfg *apiConfig) unmuteUserHandler(w http.ResponseWriter, req *http.Request) {
+	usrID, ok := apiCfg.authenticateUser(w, req)
+
+	if !ok {
+		return
+	}
+
+	target, ok := apiCfg.targetUser(w, req, usrID)
+
+	if !ok {
+		return
+	}
+
+	err := apiCfg.dbQueries.DeleteMute(req.Context(), database.DeleteMuteParams{
+		MuterID: usrID,
+		MutedID: target.ID,
+	})
+
+	if err != nil {
+		respondWithError(w, "Something went wrong", http.StatusInternalServerError)
+		log.Printf("Error deleting mute: %s\n", err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (apiCfg *apiConfig) getBlocksHandler(w http.ResponseWriter, req *http.Request) {
+	usrID, ok := apiCfg.authenticateUser(w, req)
+
+	if !ok {
+		return
+	}
+
+	limit, offset, err := parsePagination(req)
+
+	if err != nil {
+		respondWithError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	users, err := apiCfg.dbQueries.GetBlocks(req.Context(), database.GetBlocksParams{
+		BlockerID: usrID,
+		Limit:     limit,
+		Offset:    offset,
+	})
+
+	if err != nil {
+		respondWithError(w, "Something went wrong", http.StatusInternalServerError)
+		log.Printf("Error getting blocks: %s\n", err)
+		return
+	}
+
+	blocked := []authorResponse{}
+
+	for _, usr := range users {
+		blocked = append(blocked, apiCfg.newAuthorResponse(usr))
+	}
+
+	respondWithJSON(w, http.StatusOK, blocked)
+}
+
+func (apiCfg *apiConfig) getMutesHandler(w http.ResponseWriter, req *http.Request) {
+	usrID, ok := apiCfg.authenticateUser(w, req)
+
+	if !ok {
+		return
+	}
+
+	limit, offset, err := parsePagination(req)
+
+	if err != nil {
+		respondWithError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	users, err := apiCfg.dbQueries.GetMutes(req.Context(), database.GetMutesParams{
+		MuterID: usrID,
+		Limit:   limit,
+		Offset:  offset,
+	})
+
+	if err != nil {
+		respondWithError(w, "Something went wrong", http.StatusInternalServerError)
+		log.Printf("Error getting mutes: %s\n", err)
+		return
+	}
+
+	muted := []authorResponse{}
+
+	for _, usr := range users {
+		muted = append(muted, apiCfg.newAuthorResponse(usr))
+	}
+
+	respondWithJSON(w, http.StatusOK, muted)
+}
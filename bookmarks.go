@@ -0,0 +1,465 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/firerockets/chirpy/internal/database"
+	"github.com/firerockets/chirpy/internal/entitlements"
+	"github.com/firerockets/chirpy/internal/visibility"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+const maxCollectionNameLength = 100
+
+type collectionResponse struct {
+	ID        string    `json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+	Name      string    `json:"name"`
+}
+
+// loadBookmarkedChirps returns which of the chirps viewer has bookmarked.
+func (apiCfg *apiConfig) loadBookmarkedChirps(ctx context.Context, chirps []database.Chirp, viewerID uuid.NullUUID) (map[uuid.UUID]bool, error) {
+	bookmarked := map[uuid.UUID]bool{}
+
+	if !viewerID.Valid {
+		return bookmarked, nil
+	}
+
+	ids := make([]uuid.UUID, 0, len(chirps))
+
+	for _, c := range chirps {
+		ids = append(ids, c.ID)
+	}
+
+	bookmarkedIDs, err := apiCfg.dbQueries.GetBookmarkedChirpIds(ctx, database.GetBookmarkedChirpIdsParams{
+		UserID:   viewerID.UUID,
+		ChirpIds: ids,
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	for _, id := range bookmarkedIDs {
+		bookmarked[id] = true
+	}
+
+	return bookmarked, nil
+}
+
+func (apiCfg *apiConfig) createBookmarkHandler(w http.ResponseWriter, req *http.Request) {
+	usrID, ok := apiCfg.authenticateUser(w, req)
+
+	if !ok {
+		return
+	}
+
+	chirpID, err := uuid.Parse(req.PathValue("chirpID"))
+
+	if err != nil {
+		respondWithError(w, "Invalid ID", http.StatusBadRequest)
+		log.Printf("Error validating UUID: %s\n", err)
+		return
+	}
+
+	chirp, err := apiCfg.dbQueries.GetChirpById(req.Context(), chirpID)
+
+	if err != nil {
+		respondWithError(w, "Chirp not found", http.StatusNotFound)
+		log.Printf("Chirp id not found: %s\n", err)
+		return
+	}
+
+	visible, err := apiCfg.canSeeChirp(req.Context(), chirp, uuid.NullUUID{UUID: usrID, Valid: true}, visibility.Direct)
+
+	if err != nil {
+		respondWithError(w, "Something went wrong", http.StatusInternalServerError)
+		log.Printf("Error loading blocks and mutes: %s\n", err)
+		return
+	}
+
+	if !visible {
+		respondWithError(w, "Chirp not found", http.StatusNotFound)
+		return
+	}
+
+	err = apiCfg.dbQueries.CreateBookmark(req.Context(), database.CreateBookmarkParams{
+		UserID:  usrID,
+		ChirpID: chirpID,
+	})
+
+	if err != nil {
+		respondWithError(w, "Something went wrong", http.StatusInternalServerError)
+		log.Printf("Error creating bookmark: %s\n", err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (apiCfg *apiConfig) deleteBookmarkHandler(w http.ResponseWriter, req *http.Request) {
+	usrID, ok := apiCfg.authenticateUser(w, req)
+
+	if !ok {
+		return
+	}
+
+	chirpID, err := uuid.Parse(req.PathValue("chirpID"))
+
+	if err != nil {
+		respondWithError(w, "Invalid ID", http.StatusBadRequest)
+		log.Printf("Error validating UUID: %s\n", err)
+		return
+	}
+
+	err = apiCfg.dbQueries.DeleteBookmark(req.Context(), database.DeleteBookmarkParams{
+		UserID:  usrID,
+		ChirpID: chirpID,
+	})
+
+	if err != nil {
+		respondWithError(w, "Something went wrong", http.StatusInternalServerError)
+		log.Printf("Error deleting bookmark: %s\n", err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (apiCfg *apiConfig) getBookmarksHandler(w http.ResponseWriter, req *http.Request) {
+	usrID, ok := apiCfg.authenticateUser(w, req)
+
+	if !ok {
+		return
+	}
+
+	limit, offset, err := parsePagination(req)
+
+	if err != nil {
+		respondWithError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	rel, err := apiCfg.relations(req.Context(), uuid.NullUUID{UUID: usrID, Valid: true})
+
+	if err != nil {
+		respondWithError(w, "Error getting bookmarks", http.StatusInternalServerError)
+		log.Printf("Error loading blocks and mutes: %s\n", err)
+		return
+	}
+
+	chirps, err := apiCfg.dbQueries.GetBookmarkedChirps(req.Context(), database.GetBookmarkedChirpsParams{
+		UserID:          usrID,
+		ExcludedUserIds: rel.Excluded(visibility.Direct),
+		Limit:           limit,
+		Offset:          offset,
+	})
+
+	if err != nil {
+		respondWithError(w, "Error getting bookmarks", http.StatusInternalServerError)
+		log.Printf("Error fetching bookmarked chirps: %s\n", err)
+		return
+	}
+
+	chirpsResponse, err := apiCfg.chirpResponses(req.Context(), chirps, uuid.NullUUID{UUID: usrID, Valid: true}, visibility.Direct)
+
+	if err != nil {
+		respondWithError(w, "Error getting bookmarks", http.StatusInternalServerError)
+		log.Printf("Error building chirps response: %s\n", err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, chirpsResponse)
+}
+
+// ownCollection loads the collection in the path and writes a 404 when it
+// doesn't exist or belongs to someone else, so collections of other users
+// can't even be detected.
+func (apiCfg *apiConfig) ownCollection(w http.ResponseWriter, req *http.Request, usrID uuid.UUID) (database.Collection, bool) {
+	collectionID, err := uuid.Parse(req.PathValue("collectionID"))
+
+	if err != nil {
+		respondWithError(w, "Invalid ID", http.StatusBadRequest)
+		log.Printf("Error validating UUID: %s\n", err)
+		return database.Collection{}, false
+	}
+
+	collection, err := apiCfg.dbQueries.GetCollectionById(req.Context(), collectionID)
+
+	if err != nil || collection.UserID != usrID {
+		respondWithError(w, "Collection not found", http.StatusNotFound)
+		log.Printf("Collection %s not found for user: %v\n", collectionID, err)
+		return database.Collection{}, false
+	}
+
+	return collection, true
+}
+
+func (apiCfg *apiConfig) createCollectionHandler(w http.ResponseWriter, req *http.Request) {
+	usrID, ok := apiCfg.authenticateUser(w, req)
+
+	if !ok || !apiCfg.requireEntitlement(w, req, usrID, entitlements.Collections) {
+		return
+	}
+
+	type parameters struct {
+		Name string `json:"name"`
+	}
+
+	var params parameters
+
+	decoder := json.NewDecoder(req.Body)
+	err := decoder.Decode(&params)
+
+	if err != nil {
+		respondWithError(w, "Something went wrong while parsing the request body", http.StatusBadRequest)
+		log.Printf("Error decoding json request: %s\n", err)
+		return
+	}
+
+	name := strings.TrimSpace(params.Name)
+
+	if name == "" || len([]rune(name)) > maxCollectionNameLength {
+		respondWithError(w, "Collection name must have between 1 and 100 characters", http.StatusBadRequest)
+		return
+	}
+
+	collection, err := apiCfg.dbQueries.CreateCollection(req.Context(), database.CreateCollectionParams{
+		UserID: usrID,
+		Name:   name,
+	})
+
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == "23505" {
+		respondWithError(w, "A collection with this name already exists", http.StatusConflict)
+		return
+	} else if err != nil {
+		respondWithError(w, "Something went wrong", http.StatusInternalServerError)
+		log.Printf("Error creating collection: %s\n", err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusCreated, collectionResponse{
+		ID:        collection.ID.String(),
+		CreatedAt: collection.CreatedAt,
+		UpdatedAt: collection.UpdatedAt,
+		Name:      collection.Name,
+	})
+}
+
+func (apiCfg *apiConfig) getCollectionsHandler(w http.ResponseWriter, req *http.Request) {
+	usrID, ok := apiCfg.authenticateUser(w, req)
+
+	if !ok || !apiCfg.requireEntitlement(w, req, usrID, entitlements.Collections) {
+		return
+	}
+
+	collections, err := apiCfg.dbQueries.GetCollectionsByUserId(req.Context(), usrID)
+
+	if err != nil {
+		respondWithError(w, "Something went wrong", http.StatusInternalServerError)
+		log.Printf("Error fetching collections: %s\n", err)
+		return
+	}
+
+	response := []collectionResponse{}
+
+	for _, c := range collections {
+		response = append(response, collectionResponse{
+			ID:        c.ID.String(),
+			CreatedAt: c.CreatedAt,
+			UpdatedAt: c.UpdatedAt,
+			Name:      c.Name,
+		})
+	}
+
+	respondWithJSON(w, http.StatusOK, response)
+}
+
+// Deleting collections and removing chirps from them stay open after a
+// downgrade, so users can still clean up what they can no longer read.
+func (apiCfg *apiConfig) deleteCollectionHandler(w http.ResponseWriter, req *http.Request) {
+	usrID, ok := apiCfg.authenticateUser(w, req)
+
+	if !ok {
+		return
+	}
+
+	collection, ok := apiCfg.ownCollection(w, req, usrID)
+
+	if !ok {
+		return
+	}
+
+	err := apiCfg.dbQueries.DeleteCollection(req.Context(), collection.ID)
+
+	if err != nil {
+		respondWithError(w, "Something went wrong", http.StatusInternalServerError)
+		log.Printf("Error deleting collection: %s\n", err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (apiCfg *apiConfig) addCollectionChirpHandler(w http.ResponseWriter, req *http.Request) {
+	usrID, ok := apiCfg.authenticateUser(w, req)
+
+	if !ok || !apiCfg.requireEntitlement(w, req, usrID, entitlements.Collections) {
+		return
+	}
+
+	collection, ok := apiCfg.ownCollection(w, req, usrID)
+
+	if !ok {
+		return
+	}
+
+	type parameters struct {
+		ChirpID uuid.UUID `json:"chirp_id"`
+	}
+
+	var params parameters
+
+	decoder := json.NewDecoder(req.Body)
+	err := decoder.Decode(&params)
+
+	if err != nil {
+		respondWithError(w, "Something went wrong while parsing the request body", http.StatusBadRequest)
+		log.Printf("Error decoding json request: %s\n", err)
+		return
+	}
+
+	chirp, err := apiCfg.dbQueries.GetChirpById(req.Context(), params.ChirpID)
+
+	if errors.Is(err, sql.ErrNoRows) {
+		respondWithError(w, "Chirp not found", http.StatusNotFound)
+		return
+	} else if err != nil {
+		respondWithError(w, "Something went wrong", http.StatusInternalServerError)
+		log.Printf("Error looking up chirp: %s\n", err)
+		return
+	}
+
+	visible, err := apiCfg.canSeeChirp(req.Context(), chirp, uuid.NullUUID{UUID: usrID, Valid: true}, visibility.Direct)
+
+	if err != nil {
+		respondWithError(w, "Something went wrong", http.StatusInternalServerError)
+		log.Printf("Error loading blocks and mutes: %s\n", err)
+		return
+	}
+
+	if !visible {
+		respondWithError(w, "Chirp not found", http.StatusNotFound)
+		return
+	}
+
+	err = apiCfg.dbQueries.AddChirpToCollection(req.Context(), database.AddChirpToCollectionParams{
+		CollectionID: collection.ID,
+		ChirpID:      params.ChirpID,
+	})
+
+	if err != nil {
+		respondWithError(w, "Something went wrong", http.StatusInternalServerError)
+		log.Printf("Error adding chirp to collection: %s\n", err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (apiCfg *apiConfig) removeCollectionChirpHandler(w http.ResponseWriter, req *http.Request) {
+	usrID, ok := apiCfg.authenticateUser(w, req)
+
+	if !ok {
+		return
+	}
+
+	collection, ok := apiCfg.ownCollection(w, req, usrID)
+
+	if !ok {
+		return
+	}
+
+	chirpID, err := uuid.Parse(req.PathValue("chirpID"))
+
+	if err != nil {
+		respondWithError(w, "Invalid ID", http.StatusBadRequest)
+		log.Printf("Error validating UUID: %s\n", err)
+		return
+	}
+
+	err = apiCfg.dbQueries.RemoveChirpFromCollection(req.Context(), database.RemoveChirpFromCollectionParams{
+		CollectionID: collection.ID,
+		ChirpID:      chirpID,
+	})
+
+	if err != nil {
+		respondWithError(w, "Something went wrong", http.StatusInternalServerError)
+		log.Printf("Error removing chirp from collection: %s\n", err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (apiCfg *apiConfig) getCollectionChirpsHandler(w http.ResponseWriter, req *http.Request) {
+	usrID, ok := apiCfg.authenticateUser(w, req)
+
+	if !ok || !apiCfg.requireEntitlement(w, req, usrID, entitlements.Collections) {
+		return
+	}
+
+	collection, ok := apiCfg.ownCollection(w, req, usrID)
+
+	if !ok {
+		return
+	}
+
+	limit, offset, err := parsePagination(req)
+
+	if err != nil {
+		respondWithError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	rel, err := apiCfg.relations(req.Context(), uuid.NullUUID{UUID: usrID, Valid: true})
+
+	if err != nil {
+		respondWithError(w, "Something went wrong", http.StatusInternalServerError)
+		log.Printf("Error loading blocks and mutes: %s\n", err)
+		return
+	}
+
+	chirps, err := apiCfg.dbQueries.GetCollectionChirps(req.Context(), database.GetCollectionChirpsParams{
+		CollectionID:    collection.ID,
+		ExcludedUserIds: rel.Excluded(visibility.Direct),
+		Limit:           limit,
+		Offset:          offset,
+	})
+
+	if err != nil {
+		respondWithError(w, "Something went wrong", http.StatusInternalServerError)
+		log.Printf("Error fetching collection chirps: %s\n", err)
+		return
+	}
+
+	chirpsResponse, err := apiCfg.chirpResponses(req.Context(), chirps, uuid.NullUUID{UUID: usrID, Valid: true}, visibility.Direct)
+
+	if err != nil {
+		respondWithError(w, "Something went wrong", http.StatusInternalServerError)
+		log.Printf("Error building chirps response: %s\n", err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, chirpsResponse)
+}
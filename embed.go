@@ -0,0 +1,186 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/firerockets/chirpy/internal/widget"
+	"github.com/google/uuid"
+)
+
+// embedCacheAge is how long, in seconds, embeds may be cached. Deleting a
+// chirp takes up to that long to reach the pages embedding it.
+const embedCacheAge = 300
+
+// embeddedChirp loads the chirp shown in embeds. Embeds are seen by anyone
+// visiting the page they're on, so the chirp is looked up as an anonymous
+// viewer would see it, and nil is returned for every chirp they can't see.
+func (apiCfg *apiConfig) embeddedChirp(req *http.Request, chirpID uuid.UUID) (*widget.Chirp, error) {
+	response, _, err := apiCfg.viewChirp(req.Context(), chirpID, uuid.NullUUID{})
+
+	if errors.Is(err, errChirpNotFound) || errors.Is(err, errChirpDeleted) || errors.Is(err, errChirpRemoved) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	name := response.Author.DisplayName
+	if name == "" {
+		name = "@" + response.Author.Handle
+	}
+
+	chirp := &widget.Chirp{
+		URL:        chirpPageURL(apiCfg.siteURL(req), response.ID),
+		AuthorName: name,
+		Handle:     response.Author.Handle,
+		AvatarURL:  response.Author.AvatarURL,
+		Body:       response.Body,
+	}
+
+	if response.PublishedAt != nil {
+		chirp.PublishedAt = *response.PublishedAt
+	}
+
+	return chirp, nil
+}
+
+// embedChirpHandler serves the page oEmbed iframes point at.
+func (apiCfg *apiConfig) embedChirpHandler(w http.ResponseWriter, req *http.Request) {
+	var chirp *widget.Chirp
+	code := http.StatusOK
+
+	chirpID, err := uuid.Parse(req.PathValue("chirpID"))
+
+	if err == nil {
+		chirp, err = apiCfg.embeddedChirp(req, chirpID)
+	}
+
+	if err != nil {
+		log.Printf("Error loading embedded chirp: %s\n", err)
+		chirp = nil
+	}
+
+	if chirp == nil {
+		code = http.StatusNotFound
+	}
+
+	var page bytes.Buffer
+
+	err = widget.Page(&page, chirp)
+
+	if err != nil {
+		respondWithError(w, "Something went wrong", http.StatusInternalServerError)
+		log.Printf("Error rendering embed: %s\n", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Content-Security-Policy", widget.CSP)
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.Header().Set("Referrer-Policy", "no-referrer")
+	w.Header().Set("Cache-Control", "public, max-age="+strconv.Itoa(embedCacheAge))
+	w.WriteHeader(code)
+	w.Write(page.Bytes())
+}
+
+// oembedHandler implements the oEmbed endpoint for chirp URLs on this site:
+// /c/{id}, /api/chirps/{id} or /embed/chirps/{id}. Chirps that can't be
+// shown still get an embed, holding the placeholder.
+func (apiCfg *apiConfig) oembedHandler(w http.ResponseWriter, req *http.Request) {
+	query := req.URL.Query()
+
+	if format := query.Get("format"); format != "" && format != "json" {
+		respondWithError(w, "Only the json format is supported", http.StatusNotImplemented)
+		return
+	}
+
+	maxWidth, err := parseDimension(query.Get("maxwidth"))
+
+	if err != nil {
+		respondWithError(w, "Invalid maxwidth", http.StatusBadRequest)
+		return
+	}
+
+	maxHeight, err := parseDimension(query.Get("maxheight"))
+
+	if err != nil {
+		respondWithError(w, "Invalid maxheight", http.StatusBadRequest)
+		return
+	}
+
+	site := apiCfg.siteURL(req)
+	chirpID, ok := parseChirpURL(query.Get("url"), site)
+
+	if !ok {
+		respondWithError(w, "Not a chirp URL", http.StatusNotFound)
+		return
+	}
+
+	chirp, err := apiCfg.embeddedChirp(req, chirpID)
+
+	if err != nil {
+		respondWithError(w, "Something went wrong", http.StatusInternalServerError)
+		log.Printf("Error loading embedded chirp: %s\n", err)
+		return
+	}
+
+	width, height := widget.Size(maxWidth, maxHeight)
+
+	response := widget.OEmbed{
+		Version:      "1.0",
+		Type:         "rich",
+		ProviderName: "Chirpy",
+		ProviderURL:  site,
+		HTML:         widget.IFrame(site+"/embed/chirps/"+chirpID.String(), width, height),
+		Width:        width,
+		Height:       height,
+		CacheAge:     embedCacheAge,
+	}
+
+	if chirp != nil {
+		response.AuthorName = chirp.AuthorName
+		response.AuthorURL = userPageURL(site, chirp.Handle)
+	}
+
+	respondWithJSON(w, http.StatusOK, response)
+}
+
+func parseDimension(value string) (int, error) {
+	if value == "" {
+		return 0, nil
+	}
+
+	n, err := strconv.Atoi(value)
+	if err != nil || n <= 0 {
+		return 0, errors.New("invalid dimension")
+	}
+
+	return n, nil
+}
+
+// parseChirpURL returns the chirp a URL on site points at.
+func parseChirpURL(raw, site string) (uuid.UUID, bool) {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return uuid.UUID{}, false
+	}
+
+	siteURL, err := url.Parse(site)
+	if err != nil || !strings.EqualFold(u.Host, siteURL.Host) {
+		return uuid.UUID{}, false
+	}
+
+	for _, prefix := range []string{"/c/", "/api/chirps/", "/embed/chirps/"} {
+		if rest, ok := strings.CutPrefix(u.Path, prefix); ok {
+			chirpID, err := uuid.Parse(rest)
+			return chirpID, err == nil
+		}
+	}
+
+	return uuid.UUID{}, false
+}
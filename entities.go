@@ -0,0 +1,197 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"sort"
+	"strings"
+
+	"github.com/firerockets/chirpy/internal/database"
+	"github.com/firerockets/chirpy/internal/entities"
+	"github.com/firerockets/chirpy/internal/handles"
+	"github.com/google/uuid"
+)
+
+type chirpEntity struct {
+	Type   string `json:"type"`
+	Text   string `json:"text"`
+	Start  int    `json:"start"`
+	End    int    `json:"end"`
+	Tag    string `json:"tag,omitempty"`
+	UserID string `json:"user_id,omitempty"`
+	URL    string `json:"url,omitempty"`
+}
+
+// saveChirpEntities parses the chirp body and stores its hashtags, links and
+// the mentions that resolve to an existing user, and queues the previews of
+// new links. It's meant to run in the same transaction as the chirp insert.
+func saveChirpEntities(ctx context.Context, q *database.Queries, chirp database.Chirp) error {
+	for _, e := range entities.Parse(chirp.Body) {
+		switch e.Kind {
+		case entities.Hashtag:
+			hashtag, err := q.UpsertHashtag(ctx, e.Text)
+			if err != nil {
+				return err
+			}
+
+			err = q.CreateChirpHashtag(ctx, database.CreateChirpHashtagParams{
+				ChirpID:     chirp.ID,
+				HashtagID:   hashtag.ID,
+				StartOffset: int32(e.Start),
+				EndOffset:   int32(e.End),
+			})
+			if err != nil {
+				return err
+			}
+		case entities.Mention:
+			// The @name@domain form is for users on other servers, which
+			// can't be resolved here.
+			if strings.Contains(e.Text, "@") || handles.Validate(e.Text) != nil {
+				continue
+			}
+
+			usr, err := q.GetUserByHandle(ctx, e.Text)
+			if errors.Is(err, sql.ErrNoRows) {
+				continue
+			} else if err != nil {
+				return err
+			}
+
+			// Blocked users can't mention each other.
+			blocked, err := q.IsBlockedBetween(ctx, database.IsBlockedBetweenParams{
+				BlockerID: chirp.UserID,
+				BlockedID: usr.ID,
+			})
+			if err != nil {
+				return err
+			}
+			if blocked {
+				continue
+			}
+
+			err = q.CreateChirpMention(ctx, database.CreateChirpMentionParams{
+				ChirpID:     chirp.ID,
+				UserID:      usr.ID,
+				StartOffset: int32(e.Start),
+				EndOffset:   int32(e.End),
+			})
+			if err != nil {
+				return err
+			}
+		case entities.URL:
+			err := q.CreateChirpLink(ctx, database.CreateChirpLinkParams{
+				ChirpID:     chirp.ID,
+				Url:         e.Text,
+				StartOffset: int32(e.Start),
+				EndOffset:   int32(e.End),
+			})
+			if err != nil {
+				return err
+			}
+
+			err = q.QueueLinkPreview(ctx, e.Text)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// loadChirpEntities fetches the stored entities of all chirps at once and
+// groups them by chirp, ordered by their position in the body.
+func (apiCfg *apiConfig) loadChirpEntities(ctx context.Context, chirps []database.Chirp) (map[uuid.UUID][]chirpEntity, error) {
+	ids := make([]uuid.UUID, 0, len(chirps))
+	bodies := map[uuid.UUID][]rune{}
+
+	for _, c := range chirps {
+		ids = append(ids, c.ID)
+		bodies[c.ID] = []rune(c.Body)
+	}
+
+	textAt := func(chirpID uuid.UUID, start, end int32) string {
+		body := bodies[chirpID]
+		if start < 0 || int(end) > len(body) || start > end {
+			return ""
+		}
+		return string(body[start:end])
+	}
+
+	found := map[uuid.UUID][]chirpEntity{}
+
+	hashtags, err := apiCfg.dbQueries.GetChirpHashtagsByChirpIds(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, h := range hashtags {
+		found[h.ChirpID] = append(found[h.ChirpID], chirpEntity{
+			Type:  string(entities.Hashtag),
+			Text:  textAt(h.ChirpID, h.StartOffset, h.EndOffset),
+			Start: int(h.StartOffset),
+			End:   int(h.EndOffset),
+			Tag:   h.Tag,
+		})
+	}
+
+	mentions, err := apiCfg.dbQueries.GetChirpMentionsByChirpIds(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, m := range mentions {
+		found[m.ChirpID] = append(found[m.ChirpID], chirpEntity{
+			Type:   string(entities.Mention),
+			Text:   textAt(m.ChirpID, m.StartOffset, m.EndOffset),
+			Start:  int(m.StartOffset),
+			End:    int(m.EndOffset),
+			UserID: m.UserID.String(),
+		})
+	}
+
+	links, err := apiCfg.dbQueries.GetChirpLinksByChirpIds(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, l := range links {
+		found[l.ChirpID] = append(found[l.ChirpID], chirpEntity{
+			Type:  string(entities.URL),
+			Text:  textAt(l.ChirpID, l.StartOffset, l.EndOffset),
+			Start: int(l.StartOffset),
+			End:   int(l.EndOffset),
+			URL:   l.Url,
+		})
+	}
+
+	for _, list := range found {
+		sort.Slice(list, func(i, j int) bool {
+			return list[i].Start < list[j].Start
+		})
+	}
+
+	return found, nil
+}
+
+// replaceChirpEntities drops the stored entities of an edited chirp and
+// saves the ones in its new body.
+func replaceChirpEntities(ctx context.Context, q *database.Queries, chirp database.Chirp) error {
+	err := q.DeleteChirpHashtags(ctx, chirp.ID)
+	if err != nil {
+		return err
+	}
+
+	err = q.DeleteChirpMentions(ctx, chirp.ID)
+	if err != nil {
+		return err
+	}
+
+	err = q.DeleteChirpLinks(ctx, chirp.ID)
+	if err != nil {
+		return err
+	}
+
+	return saveChirpEntities(ctx, q, chirp)
+}
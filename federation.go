@@ -0,0 +1,512 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/firerockets/chirpy/internal/activitypub"
+	"github.com/firerockets/chirpy/internal/cursor"
+	"github.com/firerockets/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+const (
+	outboxPageSize   = 20
+	maxInboxBodySize = 1 << 20
+)
+
+// federating tells whether accounts are exposed to the fediverse, which
+// needs BASE_URL to build the IDs of local objects.
+func (apiCfg *apiConfig) federating() bool {
+	return apiCfg.apURLs.Base != ""
+}
+
+func respondWithActivity(w http.ResponseWriter, code int, payload interface{}) {
+	data, err := json.Marshal(payload)
+
+	if err != nil {
+		respondWithError(w, "Something went wrong", http.StatusInternalServerError)
+		log.Printf("Error marshalling activity: %s\n", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", activitypub.ContentType)
+	w.WriteHeader(code)
+	w.Write(data)
+}
+
+// federate queues an activity of userID for their remote followers. It's
+// meant to run in the transaction making the change it announces.
+func (apiCfg *apiConfig) federate(ctx context.Context, q *database.Queries, userID uuid.UUID, activity activitypub.Activity) error {
+	if !apiCfg.federating() {
+		return nil
+	}
+
+	payload, err := json.Marshal(activity)
+	if err != nil {
+		return err
+	}
+
+	return q.CreateFollowerDeliveries(ctx, database.CreateFollowerDeliveriesParams{
+		UserID:  userID,
+		Payload: string(payload),
+	})
+}
+
+// actorKey returns the key pair of a user, generating it the first time.
+func (apiCfg *apiConfig) actorKey(ctx context.Context, userID uuid.UUID) (database.ActorKey, error) {
+	key, err := apiCfg.dbQueries.GetActorKey(ctx, userID)
+	if !errors.Is(err, sql.ErrNoRows) {
+		return key, err
+	}
+
+	privateKey, err := activitypub.GenerateKey()
+	if err != nil {
+		return database.ActorKey{}, err
+	}
+
+	privatePEM, err := activitypub.EncodePrivateKey(privateKey)
+	if err != nil {
+		return database.ActorKey{}, err
+	}
+
+	publicPEM, err := activitypub.EncodePublicKey(&privateKey.PublicKey)
+	if err != nil {
+		return database.ActorKey{}, err
+	}
+
+	// Two requests may generate a key at once. Only the first is kept, so
+	// read back whichever that was.
+	err = apiCfg.dbQueries.CreateActorKey(ctx, database.CreateActorKeyParams{
+		UserID:        userID,
+		PublicKeyPem:  publicPEM,
+		PrivateKeyPem: privatePEM,
+	})
+	if err != nil {
+		return database.ActorKey{}, err
+	}
+
+	return apiCfg.dbQueries.GetActorKey(ctx, userID)
+}
+
+// federatedUser loads the user in the path and writes a 404 when federation
+// is off or they can't be federated.
+func (apiCfg *apiConfig) federatedUser(w http.ResponseWriter, req *http.Request) (database.User, bool) {
+	if !apiCfg.federating() {
+		respondWithError(w, "Not found", http.StatusNotFound)
+		return database.User{}, false
+	}
+
+	userID, err := uuid.Parse(req.PathValue("userID"))
+
+	if err != nil {
+		respondWithError(w, "User not found", http.StatusNotFound)
+		return database.User{}, false
+	}
+
+	usr, err := apiCfg.dbQueries.GetUserById(req.Context(), userID)
+
+	if errors.Is(err, sql.ErrNoRows) || (err == nil && usr.SuspendedAt.Valid) {
+		respondWithError(w, "User not found", http.StatusNotFound)
+		return database.User{}, false
+	} else if err != nil {
+		respondWithError(w, "Something went wrong", http.StatusInternalServerError)
+		log.Printf("Error looking up user: %s\n", err)
+		return database.User{}, false
+	}
+
+	return usr, true
+}
+
+// webfingerHandler resolves acct:handle@domain to the actor of a user.
+func (apiCfg *apiConfig) webfingerHandler(w http.ResponseWriter, req *http.Request) {
+	if !apiCfg.federating() {
+		respondWithError(w, "Not found", http.StatusNotFound)
+		return
+	}
+
+	resource := req.URL.Query().Get("resource")
+	handle, domain, err := activitypub.ParseAccount(resource)
+
+	if err != nil {
+		respondWithError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	base, err := url.Parse(apiCfg.apURLs.Base)
+
+	if err != nil || domain != strings.ToLower(base.Host) {
+		respondWithError(w, "User not found", http.StatusNotFound)
+		return
+	}
+
+	usr, err := apiCfg.dbQueries.GetUserByHandle(req.Context(), handle)
+
+	if errors.Is(err, sql.ErrNoRows) || (err == nil && usr.SuspendedAt.Valid) {
+		respondWithError(w, "User not found", http.StatusNotFound)
+		return
+	} else if err != nil {
+		respondWithError(w, "Something went wrong", http.StatusInternalServerError)
+		log.Printf("Error looking up user: %s\n", err)
+		return
+	}
+
+	data, err := json.Marshal(activitypub.NewWebFinger(resource, apiCfg.apURLs.Actor(usr.ID)))
+
+	if err != nil {
+		respondWithError(w, "Something went wrong", http.StatusInternalServerError)
+		log.Printf("Error marshalling webfinger: %s\n", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", activitypub.WebFingerContentType)
+	w.WriteHeader(http.StatusOK)
+	w.Write(data)
+}
+
+func (apiCfg *apiConfig) actorHandler(w http.ResponseWriter, req *http.Request) {
+	usr, ok := apiCfg.federatedUser(w, req)
+
+	if !ok {
+		return
+	}
+
+	key, err := apiCfg.actorKey(req.Context(), usr.ID)
+
+	if err != nil {
+		respondWithError(w, "Something went wrong", http.StatusInternalServerError)
+		log.Printf("Error loading actor key: %s\n", err)
+		return
+	}
+
+	respondWithActivity(w, http.StatusOK, activitypub.NewActor(apiCfg.apURLs, usr, key.PublicKeyPem, apiCfg.avatarURL(usr)))
+}
+
+// outboxHandler lists the Create activities of a user's published chirps,
+// newest first. The collection itself only links to its first page.
+func (apiCfg *apiConfig) outboxHandler(w http.ResponseWriter, req *http.Request) {
+	usr, ok := apiCfg.federatedUser(w, req)
+
+	if !ok {
+		return
+	}
+
+	outbox := apiCfg.apURLs.Outbox(usr.ID)
+
+	if req.URL.Query().Get("page") == "" {
+		total, err := apiCfg.dbQueries.CountPublishedChirpsByUserId(req.Context(), usr.ID)
+
+		if err != nil {
+			respondWithError(w, "Something went wrong", http.StatusInternalServerError)
+			log.Printf("Error counting chirps: %s\n", err)
+			return
+		}
+
+		respondWithActivity(w, http.StatusOK, activitypub.OrderedCollection{
+			Context:    activitypub.Context,
+			ID:         outbox,
+			Type:       "OrderedCollection",
+			TotalItems: total,
+			First:      outbox + "?page=true",
+		})
+		return
+	}
+
+	params := database.GetOutboxChirpsParams{
+		UserID: usr.ID,
+		Limit:  outboxPageSize,
+	}
+
+	pageID := outbox + "?page=true"
+
+	if value := req.URL.Query().Get("cursor"); value != "" {
+		before, err := cursor.Parse(value)
+
+		if err != nil {
+			respondWithError(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		params.BeforePublishedAt = sql.NullTime{Time: before.At, Valid: true}
+		params.BeforeID = uuid.NullUUID{UUID: before.ID, Valid: true}
+		pageID += "&cursor=" + value
+	}
+
+	chirps, err := apiCfg.dbQueries.GetOutboxChirps(req.Context(), params)
+
+	if err != nil {
+		respondWithError(w, "Something went wrong", http.StatusInternalServerError)
+		log.Printf("Error fetching chirps: %s\n", err)
+		return
+	}
+
+	page := activitypub.OrderedCollection{
+		Context:      activitypub.Context,
+		ID:           pageID,
+		Type:         "OrderedCollectionPage",
+		PartOf:       outbox,
+		OrderedItems: []activitypub.Activity{},
+	}
+
+	for _, chirp := range chirps {
+		create := activitypub.NewCreate(apiCfg.apURLs, chirp)
+		create.Context = nil
+		page.OrderedItems = append(page.OrderedItems, create)
+	}
+
+	if len(chirps) == outboxPageSize {
+		last := chirps[len(chirps)-1]
+		page.Next = outbox + "?page=true&cursor=" + cursor.Cursor{At: last.PublishedAt.Time, ID: last.ID}.String()
+	}
+
+	respondWithActivity(w, http.StatusOK, page)
+}
+
+// followersHandler only tells how many remote followers a user has. Who
+// they are isn't shared.
+func (apiCfg *apiConfig) followersHandler(w http.ResponseWriter, req *http.Request) {
+	usr, ok := apiCfg.federatedUser(w, req)
+
+	if !ok {
+		return
+	}
+
+	total, err := apiCfg.dbQueries.CountRemoteFollowers(req.Context(), usr.ID)
+
+	if err != nil {
+		respondWithError(w, "Something went wrong", http.StatusInternalServerError)
+		log.Printf("Error counting followers: %s\n", err)
+		return
+	}
+
+	respondWithActivity(w, http.StatusOK, activitypub.OrderedCollection{
+		Context:    activitypub.Context,
+		ID:         apiCfg.apURLs.Followers(usr.ID),
+		Type:       "OrderedCollection",
+		TotalItems: total,
+	})
+}
+
+// noteHandler serves a published chirp as a Note, and a tombstone once it
+// was deleted or removed.
+func (apiCfg *apiConfig) noteHandler(w http.ResponseWriter, req *http.Request) {
+	if !apiCfg.federating() {
+		respondWithError(w, "Not found", http.StatusNotFound)
+		return
+	}
+
+	chirpID, err := uuid.Parse(req.PathValue("chirpID"))
+
+	if err != nil {
+		respondWithError(w, "Chirp not found", http.StatusNotFound)
+		return
+	}
+
+	chirp, err := apiCfg.dbQueries.GetChirpById(req.Context(), chirpID)
+
+	if errors.Is(err, sql.ErrNoRows) || (err == nil && !chirp.PublishedAt.Valid) {
+		respondWithError(w, "Chirp not found", http.StatusNotFound)
+		return
+	} else if err != nil {
+		respondWithError(w, "Something went wrong", http.StatusInternalServerError)
+		log.Printf("Error fetching chirp: %s\n", err)
+		return
+	}
+
+	if chirp.DeletedAt.Valid || chirp.RemovedAt.Valid {
+		respondWithActivity(w, http.StatusGone, activitypub.Note{
+			Context: activitypub.Context,
+			ID:      apiCfg.apURLs.Note(chirp.ID),
+			Type:    "Tombstone",
+		})
+		return
+	}
+
+	author, err := apiCfg.dbQueries.GetUserById(req.Context(), chirp.UserID)
+
+	if err != nil || author.SuspendedAt.Valid {
+		respondWithError(w, "Chirp not found", http.StatusNotFound)
+		return
+	}
+
+	note := activitypub.NewNote(apiCfg.apURLs, chirp)
+	note.Context = activitypub.Context
+
+	respondWithActivity(w, http.StatusOK, note)
+}
+
+// inboxHandler accepts activities from remote servers. Requests must carry
+// an HTTP Signature by the key of the activity's actor. Follow, Undo, Like
+// and Announce are handled, anything else is accepted and ignored.
+func (apiCfg *apiConfig) inboxHandler(w http.ResponseWriter, req *http.Request) {
+	usr, ok := apiCfg.federatedUser(w, req)
+
+	if !ok {
+		return
+	}
+
+	body, err := io.ReadAll(http.MaxBytesReader(w, req.Body, maxInboxBodySize))
+
+	if err != nil {
+		respondWithError(w, "Activity is too large", http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	var activity activitypub.Activity
+
+	err = json.Unmarshal(body, &activity)
+
+	if err != nil {
+		respondWithError(w, "Invalid activity", http.StatusBadRequest)
+		log.Printf("Error decoding activity: %s\n", err)
+		return
+	}
+
+	keyID, err := activitypub.SignatureKeyID(req)
+
+	if err != nil {
+		respondWithError(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	// Keys are only fetched from the actor's own server, so a forged
+	// header can't make us request arbitrary URLs.
+	if !activitypub.SameHost(keyID, activity.Actor) {
+		respondWithError(w, "Activity isn't signed by its actor", http.StatusUnauthorized)
+		return
+	}
+
+	actor, key, err := apiCfg.apClient.FetchKey(req.Context(), keyID)
+
+	if err != nil {
+		respondWithError(w, "Unauthorized", http.StatusUnauthorized)
+		log.Printf("Error fetching signing key %s: %s\n", keyID, err)
+		return
+	}
+
+	if actor.ID != activity.Actor {
+		respondWithError(w, "Activity isn't signed by its actor", http.StatusUnauthorized)
+		return
+	}
+
+	err = activitypub.Verify(req, body, key)
+
+	if err != nil {
+		respondWithError(w, "Unauthorized", http.StatusUnauthorized)
+		log.Printf("Error verifying signature of %s: %s\n", actor.ID, err)
+		return
+	}
+
+	switch activity.Type {
+	case "Follow":
+		err = apiCfg.acceptFollow(req.Context(), usr, actor, activity)
+	case "Undo":
+		if activitypub.ObjectType(activity.Object) == "Follow" {
+			err = apiCfg.dbQueries.DeleteRemoteFollower(req.Context(), database.DeleteRemoteFollowerParams{
+				UserID:  usr.ID,
+				ActorID: actor.ID,
+			})
+		} else {
+			err = apiCfg.dbQueries.DeleteRemoteInteraction(req.Context(), database.DeleteRemoteInteractionParams{
+				ActorID:    actor.ID,
+				ActivityID: activitypub.Reference(activity.Object),
+			})
+		}
+	case "Like", "Announce":
+		err = apiCfg.recordInteraction(req.Context(), usr, actor, activity)
+	}
+
+	if errors.Is(err, errNotForThisInbox) {
+		respondWithError(w, err.Error(), http.StatusBadRequest)
+		return
+	} else if err != nil {
+		respondWithError(w, "Something went wrong", http.StatusInternalServerError)
+		log.Printf("Error handling %s from %s: %s\n", activity.Type, actor.ID, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusAccepted)
+}
+
+var errNotForThisInbox = errors.New("activity isn't about this user")
+
+// acceptFollow records a remote follower and queues the Accept they wait
+// for.
+func (apiCfg *apiConfig) acceptFollow(ctx context.Context, usr database.User, actor activitypub.Actor, follow activitypub.Activity) error {
+	if activitypub.Reference(follow.Object) != apiCfg.apURLs.Actor(usr.ID) {
+		return errNotForThisInbox
+	}
+
+	// Deliveries are signed with this key, so it must exist by then.
+	_, err := apiCfg.actorKey(ctx, usr.ID)
+	if err != nil {
+		return err
+	}
+
+	payload, err := json.Marshal(activitypub.NewAccept(apiCfg.apURLs, usr.ID, follow))
+	if err != nil {
+		return err
+	}
+
+	tx, err := apiCfg.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	qtx := apiCfg.dbQueries.WithTx(tx)
+
+	err = qtx.UpsertRemoteFollower(ctx, database.UpsertRemoteFollowerParams{
+		UserID:   usr.ID,
+		ActorID:  actor.ID,
+		Inbox:    actor.Inbox,
+		FollowID: follow.ID,
+	})
+	if err != nil {
+		return err
+	}
+
+	err = qtx.CreateDelivery(ctx, database.CreateDeliveryParams{
+		UserID:  usr.ID,
+		Inbox:   actor.Inbox,
+		Payload: string(payload),
+	})
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// recordInteraction records a Like or Announce of one of usr's chirps.
+func (apiCfg *apiConfig) recordInteraction(ctx context.Context, usr database.User, actor activitypub.Actor, activity activitypub.Activity) error {
+	chirpID, ok := apiCfg.apURLs.ChirpID(activitypub.Reference(activity.Object))
+	if !ok {
+		return errNotForThisInbox
+	}
+
+	chirp, err := apiCfg.dbQueries.GetChirpById(ctx, chirpID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return errNotForThisInbox
+	} else if err != nil {
+		return err
+	}
+
+	if chirp.UserID != usr.ID || !chirp.PublishedAt.Valid || chirp.RemovedAt.Valid || chirp.DeletedAt.Valid {
+		return errNotForThisInbox
+	}
+
+	return apiCfg.dbQueries.UpsertRemoteInteraction(ctx, database.UpsertRemoteInteractionParams{
+		ChirpID:    chirp.ID,
+		ActorID:    actor.ID,
+		Kind:       strings.ToLower(activity.Type),
+		ActivityID: activity.ID,
+	})
+}
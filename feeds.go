@@ -0,0 +1,202 @@
+package main
+
+import (
+	"bytes"
+	"database/sql"
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/firerockets/chirpy/internal/database"
+	"github.com/firerockets/chirpy/internal/entities"
+	"github.com/firerockets/chirpy/internal/feeds"
+	"github.com/firerockets/chirpy/internal/visibility"
+	"github.com/google/uuid"
+)
+
+// feedSize is how many of the newest chirps a feed holds. Readers poll
+// often enough that older ones were already seen.
+const feedSize = 50
+
+// siteURL is the base of the absolute links feeds need. BASE_URL wins when
+// set, since the Host header can't be trusted behind every proxy.
+func (apiCfg *apiConfig) siteURL(req *http.Request) string {
+	if apiCfg.baseURL != "" {
+		return apiCfg.baseURL
+	}
+
+	if req.TLS != nil {
+		return "https://" + req.Host
+	}
+	return "http://" + req.Host
+}
+
+// userFeedHandler serves /users/{handle}/feed.atom and feed.rss. Feed
+// readers don't authenticate, so the feed is what anonymous viewers see.
+func (apiCfg *apiConfig) userFeedHandler(w http.ResponseWriter, req *http.Request) {
+	usr, err := apiCfg.lookupUser(req.Context(), req.PathValue("handle"))
+
+	if errors.Is(err, sql.ErrNoRows) {
+		respondWithError(w, "User not found", http.StatusNotFound)
+		return
+	} else if err != nil {
+		respondWithError(w, "Something went wrong", http.StatusInternalServerError)
+		log.Printf("Error looking up user: %s\n", err)
+		return
+	}
+
+	rel, err := apiCfg.relations(req.Context(), uuid.NullUUID{})
+
+	if err != nil {
+		respondWithError(w, "Something went wrong", http.StatusInternalServerError)
+		log.Printf("Error loading blocks and mutes: %s\n", err)
+		return
+	}
+
+	if usr.SuspendedAt.Valid {
+		respondWithTombstone(w, usr.ID, usr.SuspendedAt.Time, "This account is suspended")
+		return
+	}
+
+	if !rel.CanSeeUser(usr.ID, visibility.Direct) {
+		respondWithError(w, "User not found", http.StatusNotFound)
+		return
+	}
+
+	chirps, err := apiCfg.dbQueries.GetFeedChirps(req.Context(), database.GetFeedChirpsParams{
+		AuthorID:        uuid.NullUUID{UUID: usr.ID, Valid: true},
+		ExcludedUserIds: rel.Excluded(visibility.Feed),
+		Limit:           feedSize,
+	})
+
+	if err != nil {
+		respondWithError(w, "Error getting chirps", http.StatusInternalServerError)
+		log.Printf("Error fetching chirps for feed of %s: %s\n", usr.ID, err)
+		return
+	}
+
+	title := usr.DisplayName
+	if title == "" {
+		title = "@" + usr.Handle
+	}
+
+	site := apiCfg.siteURL(req)
+
+	apiCfg.respondWithFeed(w, req, feeds.Feed{
+		Title:   title + " on Chirpy",
+		Link:    userPageURL(site, usr.Handle),
+		Updated: usr.CreatedAt,
+	}, chirps)
+}
+
+// hashtagFeedHandler serves /hashtags/{tag}/feed.atom and feed.rss.
+func (apiCfg *apiConfig) hashtagFeedHandler(w http.ResponseWriter, req *http.Request) {
+	tag := entities.NormalizeTag(req.PathValue("tag"))
+
+	if tag == "" {
+		respondWithError(w, "Invalid hashtag", http.StatusBadRequest)
+		return
+	}
+
+	rel, err := apiCfg.relations(req.Context(), uuid.NullUUID{})
+
+	if err != nil {
+		respondWithError(w, "Something went wrong", http.StatusInternalServerError)
+		log.Printf("Error loading blocks and mutes: %s\n", err)
+		return
+	}
+
+	chirps, err := apiCfg.dbQueries.GetFeedChirps(req.Context(), database.GetFeedChirpsParams{
+		Hashtag:         sql.NullString{String: tag, Valid: true},
+		ExcludedUserIds: rel.Excluded(visibility.Feed),
+		Limit:           feedSize,
+	})
+
+	if err != nil {
+		respondWithError(w, "Error getting chirps", http.StatusInternalServerError)
+		log.Printf("Error fetching chirps for feed of hashtag %s: %s\n", tag, err)
+		return
+	}
+
+	site := apiCfg.siteURL(req)
+
+	apiCfg.respondWithFeed(w, req, feeds.Feed{
+		Title: "#" + tag + " on Chirpy",
+		Link:  site + "/api/hashtags/" + tag + "/chirps",
+	}, chirps)
+}
+
+// respondWithFeed renders chirps in the format named by the last path
+// segment and answers conditional requests. The ETag is derived from the
+// rendered feed, so it also changes when a chirp drops out of it, which
+// Last-Modified alone can't tell.
+func (apiCfg *apiConfig) respondWithFeed(w http.ResponseWriter, req *http.Request, feed feeds.Feed, chirps []database.Chirp) {
+	render, contentType := feeds.Atom, feeds.AtomContentType
+
+	switch req.PathValue("format") {
+	case "feed.atom":
+	case "feed.rss":
+		render, contentType = feeds.RSS, feeds.RSSContentType
+	default:
+		respondWithError(w, "Not found", http.StatusNotFound)
+		return
+	}
+
+	authors, err := apiCfg.loadChirpAuthors(req.Context(), chirps)
+
+	if err != nil {
+		respondWithError(w, "Error getting chirps", http.StatusInternalServerError)
+		log.Printf("Error loading chirp authors: %s\n", err)
+		return
+	}
+
+	site := apiCfg.siteURL(req)
+	feed.Self = site + req.URL.Path
+	feed.ID = feed.Self
+
+	for _, c := range chirps {
+		author := authors[c.UserID]
+
+		name := author.DisplayName
+		if name == "" {
+			name = "@" + author.Handle
+		}
+
+		// Scheduled chirps keep the updated_at of their last edit, which
+		// can predate their publication.
+		updated := c.UpdatedAt
+		if c.PublishedAt.Time.After(updated) {
+			updated = c.PublishedAt.Time
+		}
+
+		feed.Entries = append(feed.Entries, feeds.Entry{
+			ID:        "urn:uuid:" + c.ID.String(),
+			Link:      chirpPageURL(site, c.ID.String()),
+			Author:    name,
+			Content:   c.Body,
+			Published: c.PublishedAt.Time,
+			Updated:   updated,
+		})
+
+		if updated.After(feed.Updated) {
+			feed.Updated = updated
+		}
+	}
+
+	body, err := render(feed)
+
+	if err != nil {
+		respondWithError(w, "Something went wrong", http.StatusInternalServerError)
+		log.Printf("Error rendering feed: %s\n", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("ETag", feeds.ETag(body))
+	w.Header().Set("Cache-Control", "public, max-age=60")
+
+	// ServeContent checks If-None-Match before If-Modified-Since, and
+	// answers HEAD requests.
+	http.ServeContent(w, req, "", feed.Updated.Truncate(time.Second), bytes.NewReader(body))
+}
@@ -0,0 +1,247 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/firerockets/chirpy/internal/database"
+	"github.com/firerockets/chirpy/internal/filter"
+	"github.com/lib/pq"
+)
+
+// reloadContentFilter rebuilds the content filter from the rules file, if one
+// is configured, and the rules in the database. Database rules win.
+func (apiCfg *apiConfig) reloadContentFilter(ctx context.Context) error {
+	rules := []filter.Rule{}
+
+	if apiCfg.contentFilterFile != "" {
+		fileRules, err := filter.LoadFile(apiCfg.contentFilterFile)
+		if err != nil {
+			return err
+		}
+		rules = append(rules, fileRules...)
+	}
+
+	dbRules, err := apiCfg.dbQueries.GetFilterRules(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, r := range dbRules {
+		policy, err := filter.ParsePolicy(r.Policy)
+		if err != nil {
+			return err
+		}
+		rules = append(rules, filter.Rule{Word: r.Word, Policy: policy})
+	}
+
+	apiCfg.contentFilter.Replace(rules)
+
+	return nil
+}
+
+// contentFilterChanged reloads the content filter after an admin edits the
+// rules and tells the other instances to reload theirs.
+func (apiCfg *apiConfig) contentFilterChanged(ctx context.Context) error {
+	err := apiCfg.reloadContentFilter(ctx)
+	if err != nil {
+		return err
+	}
+
+	return apiCfg.dbQueries.AnnounceFilterChange(ctx)
+}
+
+// watchContentFilter reloads the content filter whenever another instance
+// announces a rule change. The nil sent after the listener reconnects also
+// triggers a reload, as changes may have been missed while it was down.
+func (apiCfg *apiConfig) watchContentFilter(ctx context.Context, notifications <-chan *pq.Notification) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case _, ok := <-notifications:
+			if !ok {
+				return
+			}
+
+			err := apiCfg.reloadContentFilter(ctx)
+			if err != nil {
+				log.Printf("Error reloading content filter: %s\n", err)
+			}
+		}
+	}
+}
+
+// flagReason describes the flagged words of a filter result for reviewers.
+func flagReason(result filter.Result) string {
+	words := []string{}
+
+	for _, m := range result.Matches {
+		if m.Policy == filter.Flag {
+			words = append(words, m.Word)
+		}
+	}
+
+	return "content filter: " + strings.Join(words, ", ")
+}
+
+type filterRuleResponse struct {
+	Word   string `json:"word"`
+	Policy string `json:"policy"`
+	// Source is "file" for rules from the rules file, which can't be
+	// changed through the API, and "database" for the others.
+	Source string `json:"source"`
+}
+
+const (
+	filterRuleSourceFile     = "file"
+	filterRuleSourceDatabase = "database"
+)
+
+func (apiCfg *apiConfig) getFilterRulesHandler(w http.ResponseWriter, req *http.Request) {
+	if _, ok := apiCfg.authenticateAdmin(w, req); !ok {
+		return
+	}
+
+	response := []filterRuleResponse{}
+
+	for _, r := range apiCfg.contentFilter.Rules() {
+		source := filterRuleSourceDatabase
+		if r.FromFile {
+			source = filterRuleSourceFile
+		}
+
+		response = append(response, filterRuleResponse{
+			Word:   r.Word,
+			Policy: string(r.Policy),
+			Source: source,
+		})
+	}
+
+	respondWithJSON(w, http.StatusOK, response)
+}
+
+func (apiCfg *apiConfig) upsertFilterRuleHandler(w http.ResponseWriter, req *http.Request) {
+	if _, ok := apiCfg.authenticateAdmin(w, req); !ok {
+		return
+	}
+
+	var params filterRuleResponse
+
+	decoder := json.NewDecoder(req.Body)
+	err := decoder.Decode(&params)
+
+	if err != nil {
+		respondWithError(w, "Something went wrong while parsing the request body", http.StatusBadRequest)
+		log.Printf("Error decoding json request: %s\n", err)
+		return
+	}
+
+	policy, err := filter.ParsePolicy(params.Policy)
+	word := strings.ToLower(strings.TrimSpace(params.Word))
+
+	if err != nil || len(strings.Fields(word)) != 1 {
+		respondWithError(w, "A rule needs a single word and a policy of mask, reject or flag", http.StatusBadRequest)
+		return
+	}
+
+	rule, err := apiCfg.dbQueries.UpsertFilterRule(req.Context(), database.UpsertFilterRuleParams{
+		Word:   word,
+		Policy: string(policy),
+	})
+
+	if err != nil {
+		respondWithError(w, "Something went wrong", http.StatusInternalServerError)
+		log.Printf("Error saving filter rule: %s\n", err)
+		return
+	}
+
+	err = apiCfg.contentFilterChanged(req.Context())
+
+	if err != nil {
+		respondWithError(w, "Something went wrong", http.StatusInternalServerError)
+		log.Printf("Error reloading content filter: %s\n", err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, filterRuleResponse{
+		Word:   rule.Word,
+		Policy: rule.Policy,
+		Source: filterRuleSourceDatabase,
+	})
+}
+
+func (apiCfg *apiConfig) deleteFilterRuleHandler(w http.ResponseWriter, req *http.Request) {
+	if _, ok := apiCfg.authenticateAdmin(w, req); !ok {
+		return
+	}
+
+	word := strings.ToLower(req.PathValue("word"))
+	deleted, err := apiCfg.dbQueries.DeleteFilterRule(req.Context(), word)
+
+	if err != nil {
+		respondWithError(w, "Something went wrong", http.StatusInternalServerError)
+		log.Printf("Error deleting filter rule: %s\n", err)
+		return
+	}
+
+	if deleted == 0 {
+		for _, r := range apiCfg.contentFilter.Rules() {
+			if r.Word == word && r.FromFile {
+				respondWithError(w, "This rule comes from the rules file and can only be removed there", http.StatusConflict)
+				return
+			}
+		}
+
+		respondWithError(w, "Filter rule not found", http.StatusNotFound)
+		return
+	}
+
+	err = apiCfg.contentFilterChanged(req.Context())
+
+	if err != nil {
+		respondWithError(w, "Something went wrong", http.StatusInternalServerError)
+		log.Printf("Error reloading content filter: %s\n", err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (apiCfg *apiConfig) getChirpFlagsHandler(w http.ResponseWriter, req *http.Request) {
+	if _, ok := apiCfg.authenticateAdmin(w, req); !ok {
+		return
+	}
+
+	flags, err := apiCfg.dbQueries.GetChirpFlags(req.Context())
+
+	if err != nil {
+		respondWithError(w, "Something went wrong", http.StatusInternalServerError)
+		log.Printf("Error fetching chirp flags: %s\n", err)
+		return
+	}
+
+	type flagResponse struct {
+		ID        string    `json:"id"`
+		CreatedAt time.Time `json:"created_at"`
+		ChirpID   string    `json:"chirp_id"`
+		Reason    string    `json:"reason"`
+	}
+
+	response := []flagResponse{}
+
+	for _, f := range flags {
+		response = append(response, flagResponse{
+			ID:        f.ID.String(),
+			CreatedAt: f.CreatedAt,
+			ChirpID:   f.ChirpID.String(),
+			Reason:    f.Reason,
+		})
+	}
+
+	respondWithJSON(w, http.StatusOK, response)
+}
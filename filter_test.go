@@ -0,0 +1,80 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/firerockets/chirpy/internal/auth"
+	"github.com/firerockets/chirpy/internal/entitlements"
+	"github.com/firerockets/chirpy/internal/filter"
+	"github.com/google/uuid"
+)
+
+func TestDeleteFilterRuleHandler(t *testing.T) {
+	adminID := uuid.New()
+	now := time.Now()
+
+	apiCfg := newTestConfig(t, map[string]fakeResult{
+		"GetUserById": {{
+			adminID.String(), now, now, "admin@example.com", "unused",
+			true, "admin", "Admin", "", nil, nil,
+		}},
+		"DeleteFilterRule": {},
+	})
+	apiCfg.contentFilter = filter.New([]filter.Rule{
+		{Word: "kerfuffle", Policy: filter.Mask, FromFile: true},
+	})
+
+	token, err := auth.MakeJWT(adminID, "free", nil, apiCfg.secret, time.Hour)
+	if err != nil {
+		t.Fatalf("Error making token: %s", err)
+	}
+
+	cases := map[string]int{
+		"kerfuffle": http.StatusConflict,
+		"fornax":    http.StatusNotFound,
+	}
+
+	for word, expected := range cases {
+		req := httptest.NewRequest(http.MethodDelete, "/admin/filter/rules/"+word, nil)
+		req.SetPathValue("word", word)
+		req.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+
+		apiCfg.deleteFilterRuleHandler(w, req)
+
+		if w.Code != expected {
+			t.Errorf("Deleting %q should answer %d, got %d: %s", word, expected, w.Code, w.Body)
+		}
+	}
+}
+
+func TestCheckChirpBodyCountsMaskedLength(t *testing.T) {
+	contentFilter := filter.New([]filter.Rule{{Word: "ass", Policy: filter.Mask}})
+	limit := chirpLengthLimit(entitlements.Free())
+
+	// Masking "ass" as "****" takes the body one character past the limit.
+	body := strings.Repeat("x", limit-4) + " ass"
+
+	w := httptest.NewRecorder()
+	if _, ok := checkChirpBody(w, contentFilter, entitlements.Free(), body); ok {
+		t.Errorf("Expected the masked body to be too long, got it accepted")
+	}
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected %d, got %d: %s", http.StatusBadRequest, w.Code, w.Body)
+	}
+
+	w = httptest.NewRecorder()
+	filtered, ok := checkChirpBody(w, contentFilter, entitlements.Free(), body[1:])
+	if !ok {
+		t.Fatalf("Expected a body at the limit once masked to be accepted: %s", w.Body)
+	}
+
+	if !strings.HasSuffix(filtered.Body, " ****") {
+		t.Errorf("Expected the body to be masked, got %q", filtered.Body)
+	}
+}
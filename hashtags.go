@@ -0,0 +1,36 @@
+package main
+
+import (
+	"log"
+	"net/http"
+
+	"github.com/firerockets/chirpy/internal/entities"
+	"github.com/firerockets/chirpy/internal/visibility"
+)
+
+func (apiCfg *apiConfig) getHashtagChirpsHandler(w http.ResponseWriter, req *http.Request) {
+	tag := entities.NormalizeTag(req.PathValue("tag"))
+
+	if tag == "" {
+		respondWithError(w, "Invalid hashtag", http.StatusBadRequest)
+		return
+	}
+
+	chirps, err := apiCfg.dbQueries.GetChirpsByHashtag(req.Context(), tag)
+
+	if err != nil {
+		respondWithError(w, "Error getting chirps", http.StatusInternalServerError)
+		log.Printf("Error fetching chirps for hashtag %s: %s\n", tag, err)
+		return
+	}
+
+	chirpsResponse, err := apiCfg.chirpResponses(req.Context(), chirps, apiCfg.viewerID(req), visibility.Feed)
+
+	if err != nil {
+		respondWithError(w, "Error getting chirps", http.StatusInternalServerError)
+		log.Printf("Error building chirps response: %s\n", err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, chirpsResponse)
+}
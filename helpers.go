@@ -2,8 +2,13 @@ package main
 
 import (
 	"encoding/json"
+	"fmt"
 	"log"
 	"net/http"
+	"strconv"
+
+	"github.com/firerockets/chirpy/internal/auth"
+	"github.com/google/uuid"
 )
 
 func respondWithError(w http.ResponseWriter, msg string, code int) {
@@ -32,3 +37,84 @@ func respondWithJSON(w http.ResponseWriter, code int, payload interface{}) {
 
 	w.Write(data)
 }
+
+// authenticateUser writes the error response itself and returns false when
+// the request has no valid bearer token.
+func (apiCfg *apiConfig) authenticateUser(w http.ResponseWriter, req *http.Request) (uuid.UUID, bool) {
+	token, err := auth.GetBearerToken(req.Header)
+
+	if err != nil {
+		respondWithError(w, "Unauthorized", http.StatusUnauthorized)
+		log.Printf("Error loading token from header: %s\n", err)
+		return uuid.UUID{}, false
+	}
+
+	usrID, err := auth.ValidadeJWT(token, apiCfg.secret)
+
+	if err != nil {
+		respondWithError(w, "Unauthorized", http.StatusUnauthorized)
+		log.Printf("Error validating token: %s\n", err)
+		return uuid.UUID{}, false
+	}
+
+	return usrID, true
+}
+
+// viewerID returns the user making the request on endpoints that also work
+// anonymously. It's null when there is no valid bearer token.
+func (apiCfg *apiConfig) viewerID(req *http.Request) uuid.NullUUID {
+	token, err := auth.GetBearerToken(req.Header)
+
+	if err != nil {
+		return uuid.NullUUID{}
+	}
+
+	usrID, err := auth.ValidadeJWT(token, apiCfg.secret)
+
+	if err != nil {
+		return uuid.NullUUID{}
+	}
+
+	return uuid.NullUUID{UUID: usrID, Valid: true}
+}
+
+const (
+	defaultPageSize = 20
+	maxPageSize     = 100
+)
+
+// parseLimit reads the limit query parameter, for endpoints that page with
+// a cursor instead of an offset.
+func parseLimit(req *http.Request) (int32, error) {
+	limit := defaultPageSize
+
+	if value := req.URL.Query().Get("limit"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 1 || parsed > maxPageSize {
+			return 0, fmt.Errorf("limit must be between 1 and %d", maxPageSize)
+		}
+		limit = parsed
+	}
+
+	return int32(limit), nil
+}
+
+// parsePagination reads the limit and offset query parameters.
+func parsePagination(req *http.Request) (int32, int32, error) {
+	limit, err := parseLimit(req)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	offset := 0
+
+	if value := req.URL.Query().Get("offset"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 0 {
+			return 0, 0, fmt.Errorf("offset must be a positive number")
+		}
+		offset = parsed
+	}
+
+	return limit, int32(offset), nil
+}
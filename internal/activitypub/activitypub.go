@@ -0,0 +1,232 @@
+package activitypub
+
+import (
+	"html"
+	"strings"
+	"time"
+
+	"github.com/firerockets/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+const (
+	ContentType = "application/activity+json"
+	// Public is the audience of everything Chirpy federates.
+	Public = "https://www.w3.org/ns/activitystreams#Public"
+)
+
+var Context = []string{
+	"https://www.w3.org/ns/activitystreams",
+	"https://w3id.org/security/v1",
+}
+
+// URLs builds the IDs of the local objects. Users are addressed by ID
+// rather than handle, since handles can change.
+type URLs struct {
+	Base string
+}
+
+func (u URLs) Actor(userID uuid.UUID) string {
+	return u.Base + "/ap/users/" + userID.String()
+}
+
+func (u URLs) KeyID(userID uuid.UUID) string {
+	return u.Actor(userID) + "#main-key"
+}
+
+func (u URLs) Inbox(userID uuid.UUID) string {
+	return u.Actor(userID) + "/inbox"
+}
+
+func (u URLs) Outbox(userID uuid.UUID) string {
+	return u.Actor(userID) + "/outbox"
+}
+
+func (u URLs) Followers(userID uuid.UUID) string {
+	return u.Actor(userID) + "/followers"
+}
+
+func (u URLs) Note(chirpID uuid.UUID) string {
+	return u.Base + "/ap/chirps/" + chirpID.String()
+}
+
+// ChirpID returns the chirp a local note ID points at.
+func (u URLs) ChirpID(id string) (uuid.UUID, bool) {
+	rest, ok := strings.CutPrefix(id, u.Base+"/ap/chirps/")
+	if !ok {
+		return uuid.UUID{}, false
+	}
+
+	chirpID, err := uuid.Parse(rest)
+	return chirpID, err == nil
+}
+
+type PublicKey struct {
+	ID           string `json:"id"`
+	Owner        string `json:"owner"`
+	PublicKeyPem string `json:"publicKeyPem"`
+}
+
+type Image struct {
+	Type string `json:"type"`
+	URL  string `json:"url"`
+}
+
+type Actor struct {
+	Context           any       `json:"@context,omitempty"`
+	ID                string    `json:"id"`
+	Type              string    `json:"type"`
+	PreferredUsername string    `json:"preferredUsername"`
+	Name              string    `json:"name,omitempty"`
+	Summary           string    `json:"summary,omitempty"`
+	Inbox             string    `json:"inbox"`
+	Outbox            string    `json:"outbox"`
+	Followers         string    `json:"followers,omitempty"`
+	Icon              *Image    `json:"icon,omitempty"`
+	PublicKey         PublicKey `json:"publicKey"`
+}
+
+func NewActor(urls URLs, usr database.User, publicKeyPEM, avatarURL string) Actor {
+	actor := Actor{
+		Context:           Context,
+		ID:                urls.Actor(usr.ID),
+		Type:              "Person",
+		PreferredUsername: usr.Handle,
+		Name:              usr.DisplayName,
+		Summary:           Content(usr.Bio),
+		Inbox:             urls.Inbox(usr.ID),
+		Outbox:            urls.Outbox(usr.ID),
+		Followers:         urls.Followers(usr.ID),
+		PublicKey: PublicKey{
+			ID:           urls.KeyID(usr.ID),
+			Owner:        urls.Actor(usr.ID),
+			PublicKeyPem: publicKeyPEM,
+		},
+	}
+
+	if avatarURL != "" {
+		actor.Icon = &Image{Type: "Image", URL: avatarURL}
+	}
+
+	return actor
+}
+
+type Note struct {
+	Context      any        `json:"@context,omitempty"`
+	ID           string     `json:"id"`
+	Type         string     `json:"type"`
+	AttributedTo string     `json:"attributedTo,omitempty"`
+	Content      string     `json:"content,omitempty"`
+	Published    *time.Time `json:"published,omitempty"`
+	To           []string   `json:"to,omitempty"`
+	Cc           []string   `json:"cc,omitempty"`
+}
+
+// NewNote only makes sense for published chirps.
+func NewNote(urls URLs, chirp database.Chirp) Note {
+	return Note{
+		ID:           urls.Note(chirp.ID),
+		Type:         "Note",
+		AttributedTo: urls.Actor(chirp.UserID),
+		Content:      Content(chirp.Body),
+		Published:    &chirp.PublishedAt.Time,
+		To:           []string{Public},
+		Cc:           []string{urls.Followers(chirp.UserID)},
+	}
+}
+
+// Content turns plain text into the HTML ActivityPub expects.
+func Content(text string) string {
+	if text == "" {
+		return ""
+	}
+	return "<p>" + strings.ReplaceAll(html.EscapeString(text), "\n", "<br>") + "</p>"
+}
+
+// Activity is both built here and parsed from remote servers. Object is
+// either an ID or an embedded object, see Reference and ObjectType.
+type Activity struct {
+	Context any      `json:"@context,omitempty"`
+	ID      string   `json:"id"`
+	Type    string   `json:"type"`
+	Actor   string   `json:"actor"`
+	Object  any      `json:"object"`
+	To      []string `json:"to,omitempty"`
+	Cc      []string `json:"cc,omitempty"`
+}
+
+func NewCreate(urls URLs, chirp database.Chirp) Activity {
+	note := NewNote(urls, chirp)
+
+	return Activity{
+		Context: Context,
+		ID:      note.ID + "/activity",
+		Type:    "Create",
+		Actor:   note.AttributedTo,
+		Object:  note,
+		To:      note.To,
+		Cc:      note.Cc,
+	}
+}
+
+// NewDelete replaces the chirp with a tombstone on the receiving servers.
+func NewDelete(urls URLs, chirp database.Chirp) Activity {
+	return Activity{
+		Context: Context,
+		ID:      urls.Note(chirp.ID) + "/delete",
+		Type:    "Delete",
+		Actor:   urls.Actor(chirp.UserID),
+		Object:  Note{ID: urls.Note(chirp.ID), Type: "Tombstone"},
+		To:      []string{Public},
+		Cc:      []string{urls.Followers(chirp.UserID)},
+	}
+}
+
+// NewAccept accepts a Follow of userID, echoing it back as received.
+func NewAccept(urls URLs, userID uuid.UUID, follow Activity) Activity {
+	follow.Context = nil
+
+	return Activity{
+		Context: Context,
+		ID:      urls.Actor(userID) + "#accepts/" + uuid.NewString(),
+		Type:    "Accept",
+		Actor:   urls.Actor(userID),
+		Object:  follow,
+		To:      []string{follow.Actor},
+	}
+}
+
+// Reference returns the ID of an object, whether it was embedded or given
+// by its ID alone.
+func Reference(object any) string {
+	switch o := object.(type) {
+	case string:
+		return o
+	case map[string]any:
+		id, _ := o["id"].(string)
+		return id
+	default:
+		return ""
+	}
+}
+
+// ObjectType returns the type of an embedded object, and "" when only its
+// ID was given.
+func ObjectType(object any) string {
+	if o, ok := object.(map[string]any); ok {
+		t, _ := o["type"].(string)
+		return t
+	}
+	return ""
+}
+
+type OrderedCollection struct {
+	Context      any        `json:"@context,omitempty"`
+	ID           string     `json:"id"`
+	Type         string     `json:"type"`
+	TotalItems   int64      `json:"totalItems"`
+	First        string     `json:"first,omitempty"`
+	PartOf       string     `json:"partOf,omitempty"`
+	Next         string     `json:"next,omitempty"`
+	OrderedItems []Activity `json:"orderedItems,omitempty"`
+}
@@ -0,0 +1,80 @@
+package activitypub
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestChirpID(t *testing.T) {
+	urls := URLs{Base: "https://chirpy.example"}
+	chirpID := uuid.New()
+
+	got, ok := urls.ChirpID(urls.Note(chirpID))
+	if !ok || got != chirpID {
+		t.Errorf("Expected %s from its own note ID, got %s", chirpID, got)
+	}
+
+	for _, id := range []string{
+		"https://other.example/ap/chirps/" + chirpID.String(),
+		urls.Base + "/ap/chirps/not-an-id",
+		urls.Actor(chirpID),
+	} {
+		if _, ok := urls.ChirpID(id); ok {
+			t.Errorf("%s should not be a local chirp", id)
+		}
+	}
+}
+
+func TestParseAccount(t *testing.T) {
+	handle, domain, err := ParseAccount("acct:alice@Chirpy.Example")
+
+	if err != nil {
+		t.Errorf("Error parsing account: %s", err)
+	}
+
+	if handle != "alice" || domain != "chirpy.example" {
+		t.Errorf("Expected alice at chirpy.example, got %s at %s", handle, domain)
+	}
+
+	for _, resource := range []string{"alice@chirpy.example", "acct:alice", "acct:@chirpy.example", "https://chirpy.example"} {
+		if _, _, err := ParseAccount(resource); err != ErrInvalidResource {
+			t.Errorf("Expected %q to be invalid, got %v", resource, err)
+		}
+	}
+}
+
+func TestReference(t *testing.T) {
+	var undo Activity
+	payload := `{"type":"Undo","actor":"https://remote.example/users/bob","object":{"id":"https://remote.example/follows/1","type":"Follow"}}`
+
+	if err := json.Unmarshal([]byte(payload), &undo); err != nil {
+		t.Fatalf("Error parsing activity: %s", err)
+	}
+
+	if got := Reference(undo.Object); got != "https://remote.example/follows/1" {
+		t.Errorf("Expected the embedded object ID, got %q", got)
+	}
+
+	if got := ObjectType(undo.Object); got != "Follow" {
+		t.Errorf("Expected the embedded object type, got %q", got)
+	}
+
+	if got := Reference("https://remote.example/notes/1"); got != "https://remote.example/notes/1" {
+		t.Errorf("Expected the object ID as is, got %q", got)
+	}
+
+	if got := ObjectType("https://remote.example/notes/1"); got != "" {
+		t.Errorf("Expected no type for an object given by ID, got %q", got)
+	}
+}
+
+func TestContent(t *testing.T) {
+	got := Content("<b>hi</b> & bye\nsee you")
+	expected := "<p>&lt;b&gt;hi&lt;/b&gt; &amp; bye<br>see you</p>"
+
+	if got != expected {
+		t.Errorf("Expected %q, got %q", expected, got)
+	}
+}
@@ -0,0 +1,229 @@
+package activitypub
+
+import (
+	"bytes"
+	"context"
+	"crypto/rsa"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/firerockets/chirpy/internal/safehttp"
+)
+
+const (
+	// maxDocumentSize caps what is read from remote servers.
+	maxDocumentSize = 1 << 20
+	maxRedirects    = 3
+	// keyCacheTTL is how long a fetched key is trusted before it's fetched
+	// again, which is also how long a rotated key takes to be picked up.
+	keyCacheTTL   = time.Hour
+	maxCachedKeys = 10000
+)
+
+var (
+	ErrInsecureURL      = errors.New("remote URLs must use https")
+	ErrTooManyRedirects = errors.New("too many redirects")
+)
+
+// Client talks to remote servers. Only https URLs are fetched or posted
+// to, so tests run against an httptest TLS server with its client.
+type Client struct {
+	HTTP *http.Client
+
+	mu   sync.Mutex
+	keys map[string]cachedKey
+}
+
+type cachedKey struct {
+	actor     Actor
+	key       *rsa.PublicKey
+	expiresAt time.Time
+}
+
+// NewClient returns a client that only reaches public addresses on port
+// 443. Inboxes and keys come from remote documents and request headers,
+// so they must not be able to point it at internal services.
+func NewClient() *Client {
+	return newClient(safehttp.PublicPorts(443))
+}
+
+func newClient(allowed func(netip.AddrPort) bool) *Client {
+	transport := safehttp.NewTransport(allowed, 5*time.Second)
+
+	return &Client{HTTP: &http.Client{
+		Transport: transport,
+		Timeout:   10 * time.Second,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) >= maxRedirects {
+				return ErrTooManyRedirects
+			}
+			return checkURL(req.URL.String())
+		},
+	}}
+}
+
+func checkURL(raw string) error {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return err
+	}
+	if u.Scheme != "https" || u.Host == "" || u.User != nil {
+		return ErrInsecureURL
+	}
+	return nil
+}
+
+// SameHost tells whether two URLs are on the same host, such as a keyId
+// and the actor an activity claims to be from.
+func SameHost(a, b string) bool {
+	ua, err := url.Parse(a)
+	if err != nil || ua.Host == "" {
+		return false
+	}
+	ub, err := url.Parse(b)
+	if err != nil {
+		return false
+	}
+	return strings.EqualFold(ua.Host, ub.Host)
+}
+
+// FetchActor loads an actor document and makes sure it is the one asked
+// for, so a server can't pass off someone else's actor.
+func (c *Client) FetchActor(ctx context.Context, id string) (Actor, error) {
+	if err := checkURL(id); err != nil {
+		return Actor{}, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, id, nil)
+	if err != nil {
+		return Actor{}, err
+	}
+
+	req.Header.Set("Accept", ContentType)
+
+	resp, err := c.HTTP.Do(req)
+	if err != nil {
+		return Actor{}, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return Actor{}, fmt.Errorf("fetching actor %s: %s", id, resp.Status)
+	}
+
+	var actor Actor
+	if err := json.NewDecoder(io.LimitReader(resp.Body, maxDocumentSize)).Decode(&actor); err != nil {
+		return Actor{}, err
+	}
+
+	if actor.ID != id {
+		return Actor{}, fmt.Errorf("fetching actor %s: got %s instead", id, actor.ID)
+	}
+
+	return actor, nil
+}
+
+// FetchKey loads the public key a signed request claims to use, along with
+// the actor owning it. Keys are cached for keyCacheTTL.
+func (c *Client) FetchKey(ctx context.Context, keyID string) (Actor, *rsa.PublicKey, error) {
+	if cached, ok := c.cachedKey(keyID); ok {
+		return cached.actor, cached.key, nil
+	}
+
+	actorID, _, _ := strings.Cut(keyID, "#")
+
+	actor, err := c.FetchActor(ctx, actorID)
+	if err != nil {
+		return Actor{}, nil, err
+	}
+
+	if actor.PublicKey.ID != keyID {
+		return Actor{}, nil, fmt.Errorf("actor %s has no key %s", actorID, keyID)
+	}
+
+	key, err := ParsePublicKey(actor.PublicKey.PublicKeyPem)
+	if err != nil {
+		return Actor{}, nil, err
+	}
+
+	c.cacheKey(keyID, cachedKey{actor: actor, key: key, expiresAt: time.Now().Add(keyCacheTTL)})
+
+	return actor, key, nil
+}
+
+func (c *Client) cachedKey(keyID string) (cachedKey, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	cached, ok := c.keys[keyID]
+	if !ok || time.Now().After(cached.expiresAt) {
+		return cachedKey{}, false
+	}
+	return cached, true
+}
+
+func (c *Client) cacheKey(keyID string, cached cachedKey) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.keys == nil {
+		c.keys = map[string]cachedKey{}
+	}
+
+	// Make room by dropping expired keys first, and any key at all when
+	// there are none.
+	if len(c.keys) >= maxCachedKeys {
+		now := time.Now()
+		for id, k := range c.keys {
+			if now.After(k.expiresAt) {
+				delete(c.keys, id)
+			}
+		}
+		for id := range c.keys {
+			if len(c.keys) < maxCachedKeys {
+				break
+			}
+			delete(c.keys, id)
+		}
+	}
+
+	c.keys[keyID] = cached
+}
+
+// Post delivers an activity to an inbox, signed with the sender's key.
+func (c *Client) Post(ctx context.Context, inbox string, payload []byte, keyID string, key *rsa.PrivateKey) error {
+	if err := checkURL(inbox); err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, inbox, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Content-Type", ContentType)
+
+	if err := Sign(req, payload, keyID, key); err != nil {
+		return err
+	}
+
+	resp, err := c.HTTP.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("delivering to %s: %s", inbox, resp.Status)
+	}
+
+	return nil
+}
@@ -0,0 +1,237 @@
+package activitypub
+
+import (
+	"context"
+	"crypto/rsa"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/firerockets/chirpy/internal/safehttp"
+)
+
+// mockRemote is a remote server with a single actor, bob. Its inbox only
+// accepts requests signed by the sender key it's given.
+type mockRemote struct {
+	*httptest.Server
+	senderKey *rsa.PublicKey
+	fail      bool
+	fetches   atomic.Int32
+
+	mu       sync.Mutex
+	received []Activity
+}
+
+func newMockRemote(t *testing.T, bobKey, senderKey *rsa.PrivateKey) *mockRemote {
+	remote := &mockRemote{senderKey: &senderKey.PublicKey}
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("GET /users/bob", func(w http.ResponseWriter, req *http.Request) {
+		remote.fetches.Add(1)
+
+		pem, err := EncodePublicKey(&bobKey.PublicKey)
+		if err != nil {
+			t.Errorf("Error encoding key: %s", err)
+		}
+
+		actor := remote.URL + "/users/bob"
+		w.Header().Set("Content-Type", ContentType)
+		json.NewEncoder(w).Encode(Actor{
+			ID:                actor,
+			Type:              "Person",
+			PreferredUsername: "bob",
+			Inbox:             actor + "/inbox",
+			PublicKey:         PublicKey{ID: actor + "#main-key", Owner: actor, PublicKeyPem: pem},
+		})
+	})
+
+	mux.HandleFunc("GET /users/mallory", func(w http.ResponseWriter, req *http.Request) {
+		json.NewEncoder(w).Encode(Actor{ID: remote.URL + "/users/bob", Type: "Person"})
+	})
+
+	mux.HandleFunc("GET /users/plain", func(w http.ResponseWriter, req *http.Request) {
+		http.Redirect(w, req, "http://"+req.Host+"/users/bob", http.StatusFound)
+	})
+
+	mux.HandleFunc("GET /users/loop", func(w http.ResponseWriter, req *http.Request) {
+		http.Redirect(w, req, "/users/loop", http.StatusFound)
+	})
+
+	mux.HandleFunc("POST /users/bob/inbox", func(w http.ResponseWriter, req *http.Request) {
+		body, _ := io.ReadAll(req.Body)
+
+		if remote.fail {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+
+		if err := Verify(req, body, remote.senderKey); err != nil {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		var activity Activity
+		json.Unmarshal(body, &activity)
+
+		remote.mu.Lock()
+		remote.received = append(remote.received, activity)
+		remote.mu.Unlock()
+
+		w.WriteHeader(http.StatusAccepted)
+	})
+
+	remote.Server = httptest.NewTLSServer(mux)
+	t.Cleanup(remote.Close)
+	return remote
+}
+
+func (r *mockRemote) Received() []Activity {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.received
+}
+
+func TestFetchKey(t *testing.T) {
+	bobKey := newTestKey(t)
+	remote := newMockRemote(t, bobKey, bobKey)
+	client := &Client{HTTP: remote.Client()}
+
+	actor, key, err := client.FetchKey(context.Background(), remote.URL+"/users/bob#main-key")
+
+	if err != nil {
+		t.Fatalf("Error fetching key: %s", err)
+	}
+
+	if actor.Inbox != remote.URL+"/users/bob/inbox" {
+		t.Errorf("Expected bob's inbox, got %q", actor.Inbox)
+	}
+
+	if !key.Equal(&bobKey.PublicKey) {
+		t.Error("Expected bob's public key")
+	}
+
+	if _, _, err := client.FetchKey(context.Background(), remote.URL+"/users/bob#other-key"); err == nil {
+		t.Error("A key the actor doesn't list should be rejected")
+	}
+
+	if _, err := client.FetchActor(context.Background(), remote.URL+"/users/mallory"); err == nil {
+		t.Error("An actor document for someone else should be rejected")
+	}
+}
+
+func TestFetchKeyIsCached(t *testing.T) {
+	bobKey := newTestKey(t)
+	remote := newMockRemote(t, bobKey, bobKey)
+	client := &Client{HTTP: remote.Client()}
+
+	for i := 0; i < 3; i++ {
+		if _, _, err := client.FetchKey(context.Background(), remote.URL+"/users/bob#main-key"); err != nil {
+			t.Fatalf("Error fetching key: %s", err)
+		}
+	}
+
+	if n := remote.fetches.Load(); n != 1 {
+		t.Errorf("Expected the key to be fetched once, got %d fetches", n)
+	}
+
+	client.keys[remote.URL+"/users/bob#main-key"] = cachedKey{expiresAt: time.Now().Add(-time.Second)}
+
+	if _, key, err := client.FetchKey(context.Background(), remote.URL+"/users/bob#main-key"); err != nil || key == nil {
+		t.Errorf("Expired key should be fetched again, got %v", err)
+	}
+
+	if n := remote.fetches.Load(); n != 2 {
+		t.Errorf("Expected a second fetch after expiry, got %d fetches", n)
+	}
+}
+
+func TestClientBlocksPrivateAddresses(t *testing.T) {
+	key := newTestKey(t)
+	remote := newMockRemote(t, key, key)
+	client := NewClient()
+
+	if _, err := client.FetchActor(context.Background(), remote.URL+"/users/bob"); !errors.Is(err, safehttp.ErrBlocked) {
+		t.Errorf("Fetching a loopback actor: got %v, want ErrBlocked", err)
+	}
+
+	for _, inbox := range []string{remote.URL + "/users/bob/inbox", "https://169.254.169.254/inbox", "https://10.0.0.1:8443/inbox"} {
+		if err := client.Post(context.Background(), inbox, nil, "", key); !errors.Is(err, safehttp.ErrBlocked) {
+			t.Errorf("Posting to %s: got %v, want ErrBlocked", inbox, err)
+		}
+	}
+}
+
+func TestClientRedirects(t *testing.T) {
+	key := newTestKey(t)
+	remote := newMockRemote(t, key, key)
+
+	client := newClient(func(addr netip.AddrPort) bool { return addr.Addr().IsLoopback() })
+	client.HTTP.Transport.(*http.Transport).TLSClientConfig = remote.Client().Transport.(*http.Transport).TLSClientConfig.Clone()
+
+	cases := map[string]error{
+		"/users/plain": ErrInsecureURL,
+		"/users/loop":  ErrTooManyRedirects,
+	}
+
+	for path, want := range cases {
+		if _, err := client.FetchActor(context.Background(), remote.URL+path); !errors.Is(err, want) {
+			t.Errorf("FetchActor(%s) = %v, want %v", path, err, want)
+		}
+	}
+}
+
+func TestSameHost(t *testing.T) {
+	cases := []struct {
+		a, b string
+		want bool
+	}{
+		{"https://remote.example/users/bob#main-key", "https://remote.example/users/bob", true},
+		{"https://REMOTE.example/key", "https://remote.example/users/bob", true},
+		{"https://evil.example/key", "https://remote.example/users/bob", false},
+		{"https://remote.example:8443/key", "https://remote.example/users/bob", false},
+		{"/key", "", false},
+	}
+
+	for _, c := range cases {
+		if got := SameHost(c.a, c.b); got != c.want {
+			t.Errorf("SameHost(%q, %q) = %v, want %v", c.a, c.b, got, c.want)
+		}
+	}
+}
+
+func TestInsecureURLsAreRejected(t *testing.T) {
+	client := NewClient()
+
+	if _, err := client.FetchActor(context.Background(), "http://remote.example/users/bob"); err != ErrInsecureURL {
+		t.Errorf("Expected %v, got %v", ErrInsecureURL, err)
+	}
+
+	if err := client.Post(context.Background(), "http://remote.example/inbox", nil, "", newTestKey(t)); err != ErrInsecureURL {
+		t.Errorf("Expected %v, got %v", ErrInsecureURL, err)
+	}
+}
+
+func TestPostIsSigned(t *testing.T) {
+	key := newTestKey(t)
+	remote := newMockRemote(t, key, key)
+	client := &Client{HTTP: remote.Client()}
+
+	payload, _ := json.Marshal(Activity{ID: "https://chirpy.example/activities/1", Type: "Create"})
+
+	err := client.Post(context.Background(), remote.URL+"/users/bob/inbox", payload, "https://chirpy.example/ap/users/1#main-key", key)
+
+	if err != nil {
+		t.Fatalf("Error posting activity: %s", err)
+	}
+
+	if received := remote.Received(); len(received) != 1 || received[0].Type != "Create" {
+		t.Errorf("Expected the remote to receive the Create, got %+v", received)
+	}
+}
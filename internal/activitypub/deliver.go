@@ -0,0 +1,112 @@
+package activitypub
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/firerockets/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+const (
+	deliveryBatchSize = 50
+	// maxDeliveryAttempts spreads retries over about a day and a half
+	// before giving up on an inbox.
+	maxDeliveryAttempts = 10
+	maxDeliveryBackoff  = 12 * time.Hour
+)
+
+type DeliveryStore interface {
+	ClaimDueDeliveries(ctx context.Context, limit int32) ([]database.ClaimDueDeliveriesRow, error)
+	DeleteDelivery(ctx context.Context, id uuid.UUID) error
+	RetryDelivery(ctx context.Context, arg database.RetryDeliveryParams) error
+}
+
+// Deliverer posts queued activities to remote inboxes. Failed deliveries
+// are retried with exponential backoff.
+type Deliverer struct {
+	store    DeliveryStore
+	client   *Client
+	urls     URLs
+	interval time.Duration
+}
+
+func NewDeliverer(store DeliveryStore, client *Client, urls URLs, interval time.Duration) *Deliverer {
+	return &Deliverer{
+		store:    store,
+		client:   client,
+		urls:     urls,
+		interval: interval,
+	}
+}
+
+// Run delivers once right away and then on every tick until ctx is done.
+func (d *Deliverer) Run(ctx context.Context) {
+	ticker := time.NewTicker(d.interval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := d.Deliver(ctx); err != nil {
+			log.Printf("Error delivering activities: %s\n", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Deliver attempts the deliveries that are due and returns how many went
+// through.
+func (d *Deliverer) Deliver(ctx context.Context) (int, error) {
+	due, err := d.store.ClaimDueDeliveries(ctx, deliveryBatchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	delivered := 0
+
+	for _, delivery := range due {
+		err := d.post(ctx, delivery)
+		if err == nil {
+			delivered++
+			err = d.store.DeleteDelivery(ctx, delivery.ID)
+		} else if delivery.Attempts >= maxDeliveryAttempts {
+			log.Printf("Giving up delivering to %s: %s\n", delivery.Inbox, err)
+			err = d.store.DeleteDelivery(ctx, delivery.ID)
+		} else {
+			err = d.store.RetryDelivery(ctx, database.RetryDeliveryParams{
+				ID:            delivery.ID,
+				NextAttemptAt: time.Now().Add(backoff(delivery.Attempts)),
+			})
+		}
+
+		if err != nil {
+			return delivered, err
+		}
+	}
+
+	return delivered, nil
+}
+
+func (d *Deliverer) post(ctx context.Context, delivery database.ClaimDueDeliveriesRow) error {
+	key, err := ParsePrivateKey(delivery.PrivateKeyPem)
+	if err != nil {
+		return err
+	}
+
+	return d.client.Post(ctx, delivery.Inbox, []byte(delivery.Payload), d.urls.KeyID(delivery.UserID), key)
+}
+
+// backoff waits five minutes after the first attempt and doubles from
+// there.
+func backoff(attempts int32) time.Duration {
+	wait := 5 * time.Minute << (attempts - 1)
+	if wait <= 0 || wait > maxDeliveryBackoff {
+		return maxDeliveryBackoff
+	}
+	return wait
+}
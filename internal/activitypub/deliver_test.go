@@ -0,0 +1,102 @@
+package activitypub
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/firerockets/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+type fakeDeliveryStore struct {
+	due     []database.ClaimDueDeliveriesRow
+	deleted []uuid.UUID
+	retried map[uuid.UUID]time.Time
+}
+
+func (s *fakeDeliveryStore) ClaimDueDeliveries(ctx context.Context, limit int32) ([]database.ClaimDueDeliveriesRow, error) {
+	due := s.due
+	s.due = nil
+	return due, nil
+}
+
+func (s *fakeDeliveryStore) DeleteDelivery(ctx context.Context, id uuid.UUID) error {
+	s.deleted = append(s.deleted, id)
+	return nil
+}
+
+func (s *fakeDeliveryStore) RetryDelivery(ctx context.Context, arg database.RetryDeliveryParams) error {
+	s.retried[arg.ID] = arg.NextAttemptAt
+	return nil
+}
+
+func TestDeliver(t *testing.T) {
+	key := newTestKey(t)
+	pem, err := EncodePrivateKey(key)
+	if err != nil {
+		t.Fatalf("Error encoding key: %s", err)
+	}
+
+	remote := newMockRemote(t, key, key)
+	inbox := remote.URL + "/users/bob/inbox"
+
+	delivery := func(attempts int32) database.ClaimDueDeliveriesRow {
+		return database.ClaimDueDeliveriesRow{
+			ID:            uuid.New(),
+			UserID:        uuid.New(),
+			Inbox:         inbox,
+			Payload:       `{"type":"Create"}`,
+			Attempts:      attempts,
+			PrivateKeyPem: pem,
+		}
+	}
+
+	store := &fakeDeliveryStore{retried: map[uuid.UUID]time.Time{}}
+	deliverer := NewDeliverer(store, &Client{HTTP: remote.Client()}, URLs{Base: "https://chirpy.example"}, time.Minute)
+
+	ok := delivery(1)
+	store.due = []database.ClaimDueDeliveriesRow{ok}
+
+	delivered, err := deliverer.Deliver(context.Background())
+
+	if err != nil || delivered != 1 {
+		t.Errorf("Expected 1 delivery, got %d (%v)", delivered, err)
+	}
+
+	if len(store.deleted) != 1 || store.deleted[0] != ok.ID {
+		t.Errorf("Delivered activities should be dequeued, got %v", store.deleted)
+	}
+
+	remote.fail = true
+	store.deleted = nil
+
+	retry := delivery(2)
+	exhausted := delivery(maxDeliveryAttempts)
+	store.due = []database.ClaimDueDeliveriesRow{retry, exhausted}
+
+	before := time.Now()
+	delivered, err = deliverer.Deliver(context.Background())
+
+	if err != nil || delivered != 0 {
+		t.Errorf("Expected no delivery, got %d (%v)", delivered, err)
+	}
+
+	if at, ok := store.retried[retry.ID]; !ok || at.Before(before.Add(backoff(2))) {
+		t.Errorf("Expected a retry in %s, got %v", backoff(2), at)
+	}
+
+	if len(store.deleted) != 1 || store.deleted[0] != exhausted.ID {
+		t.Errorf("Deliveries out of attempts should be dropped, got %v", store.deleted)
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	if backoff(1) != 5*time.Minute || backoff(2) != 10*time.Minute {
+		t.Errorf("Expected backoff to start at 5 minutes and double, got %s and %s", backoff(1), backoff(2))
+	}
+
+	if backoff(maxDeliveryAttempts) != maxDeliveryBackoff || backoff(64) != maxDeliveryBackoff {
+		t.Errorf("Expected backoff to be capped at %s", maxDeliveryBackoff)
+	}
+}
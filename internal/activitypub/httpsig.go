@@ -0,0 +1,171 @@
+package activitypub
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+)
+
+// maxClockSkew is how far the Date of a signed request can be from now.
+const maxClockSkew = time.Hour
+
+var (
+	ErrMissingSignature = errors.New("request isn't signed")
+	ErrInvalidSignature = errors.New("invalid signature")
+)
+
+// Sign adds an HTTP Signature (draft-cavage-http-signatures) covering the
+// request target, host and date, and the body digest when there is a body.
+func Sign(req *http.Request, body []byte, keyID string, key *rsa.PrivateKey) error {
+	req.Header.Set("Date", time.Now().UTC().Format(http.TimeFormat))
+
+	headers := []string{"(request-target)", "host", "date"}
+
+	if body != nil {
+		req.Header.Set("Digest", digest(body))
+		headers = append(headers, "digest")
+	}
+
+	hashed := sha256.Sum256([]byte(signingString(req, headers)))
+
+	signature, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, hashed[:])
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Signature", fmt.Sprintf(`keyId="%s",algorithm="rsa-sha256",headers="%s",signature="%s"`,
+		keyID,
+		strings.Join(headers, " "),
+		base64.StdEncoding.EncodeToString(signature),
+	))
+
+	return nil
+}
+
+type signature struct {
+	keyID     string
+	headers   []string
+	signature []byte
+}
+
+func parseSignature(req *http.Request) (signature, error) {
+	header := req.Header.Get("Signature")
+	if header == "" {
+		return signature{}, ErrMissingSignature
+	}
+
+	sig := signature{headers: []string{"date"}}
+
+	for _, param := range strings.Split(header, ",") {
+		name, value, ok := strings.Cut(strings.TrimSpace(param), "=")
+		if !ok {
+			return signature{}, ErrInvalidSignature
+		}
+		value = strings.Trim(value, `"`)
+
+		switch name {
+		case "keyId":
+			sig.keyID = value
+		case "headers":
+			sig.headers = strings.Fields(strings.ToLower(value))
+		case "signature":
+			decoded, err := base64.StdEncoding.DecodeString(value)
+			if err != nil {
+				return signature{}, ErrInvalidSignature
+			}
+			sig.signature = decoded
+		}
+	}
+
+	if sig.keyID == "" || sig.signature == nil {
+		return signature{}, ErrInvalidSignature
+	}
+
+	return sig, nil
+}
+
+// SignatureKeyID returns the key a request claims to be signed with, so it
+// can be fetched before calling Verify.
+func SignatureKeyID(req *http.Request) (string, error) {
+	sig, err := parseSignature(req)
+	return sig.keyID, err
+}
+
+// Verify checks the signature of a request with the public key of its
+// keyId. The signature must cover the request target, host and date, and
+// the digest of the body when there is one.
+func Verify(req *http.Request, body []byte, key *rsa.PublicKey) error {
+	sig, err := parseSignature(req)
+	if err != nil {
+		return err
+	}
+
+	required := []string{"(request-target)", "host", "date"}
+	if len(body) > 0 {
+		required = append(required, "digest")
+	}
+
+	for _, h := range required {
+		if !slices.Contains(sig.headers, h) {
+			return fmt.Errorf("%w: %s isn't signed", ErrInvalidSignature, h)
+		}
+	}
+
+	date, err := http.ParseTime(req.Header.Get("Date"))
+	if err != nil {
+		return fmt.Errorf("%w: bad date", ErrInvalidSignature)
+	}
+
+	if skew := time.Since(date); skew > maxClockSkew || skew < -maxClockSkew {
+		return fmt.Errorf("%w: date is too far from now", ErrInvalidSignature)
+	}
+
+	if len(body) > 0 && req.Header.Get("Digest") != digest(body) {
+		return fmt.Errorf("%w: digest doesn't match the body", ErrInvalidSignature)
+	}
+
+	hashed := sha256.Sum256([]byte(signingString(req, sig.headers)))
+
+	if err := rsa.VerifyPKCS1v15(key, crypto.SHA256, hashed[:], sig.signature); err != nil {
+		return ErrInvalidSignature
+	}
+
+	return nil
+}
+
+func signingString(req *http.Request, headers []string) string {
+	lines := make([]string, 0, len(headers))
+
+	for _, h := range headers {
+		var value string
+
+		switch h {
+		case "(request-target)":
+			value = strings.ToLower(req.Method) + " " + req.URL.RequestURI()
+		case "host":
+			value = req.Host
+			if value == "" {
+				value = req.URL.Host
+			}
+		default:
+			value = strings.Join(req.Header.Values(h), ", ")
+		}
+
+		lines = append(lines, h+": "+value)
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+func digest(body []byte) string {
+	sum := sha256.Sum256(body)
+	return "SHA-256=" + base64.StdEncoding.EncodeToString(sum[:])
+}
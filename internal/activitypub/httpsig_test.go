@@ -0,0 +1,101 @@
+package activitypub
+
+import (
+	"bytes"
+	"crypto/rsa"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+var (
+	testKeyOnce sync.Once
+	testKey     *rsa.PrivateKey
+)
+
+// newTestKey shares one key between tests, since generating them is slow.
+func newTestKey(t *testing.T) *rsa.PrivateKey {
+	testKeyOnce.Do(func() {
+		key, err := GenerateKey()
+		if err != nil {
+			t.Fatalf("Error generating key: %s", err)
+		}
+		testKey = key
+	})
+	return testKey
+}
+
+// signedRequest signs a request as a client would and returns it as the
+// server receives it.
+func signedRequest(t *testing.T, body []byte, key *rsa.PrivateKey) *http.Request {
+	out, err := http.NewRequest(http.MethodPost, "https://chirpy.example/ap/users/1/inbox", bytes.NewReader(body))
+	if err != nil {
+		t.Fatalf("Error creating request: %s", err)
+	}
+
+	if err := Sign(out, body, "https://remote.example/users/bob#main-key", key); err != nil {
+		t.Fatalf("Error signing request: %s", err)
+	}
+
+	in := httptest.NewRequest(http.MethodPost, "/ap/users/1/inbox", bytes.NewReader(body))
+	in.Host = "chirpy.example"
+	in.Header = out.Header
+	return in
+}
+
+func TestSignAndVerify(t *testing.T) {
+	key := newTestKey(t)
+	body := []byte(`{"type":"Follow"}`)
+	req := signedRequest(t, body, key)
+
+	keyID, err := SignatureKeyID(req)
+	if err != nil || keyID != "https://remote.example/users/bob#main-key" {
+		t.Errorf("Expected the signing key ID, got %q (%v)", keyID, err)
+	}
+
+	if err := Verify(req, body, &key.PublicKey); err != nil {
+		t.Errorf("Error verifying signature: %s", err)
+	}
+}
+
+func TestVerifyRejectsTampering(t *testing.T) {
+	key := newTestKey(t)
+	body := []byte(`{"type":"Follow"}`)
+
+	other, err := GenerateKey()
+	if err != nil {
+		t.Fatalf("Error generating key: %s", err)
+	}
+
+	cases := map[string]func(req *http.Request) ([]byte, *rsa.PublicKey){
+		"body": func(req *http.Request) ([]byte, *rsa.PublicKey) {
+			return []byte(`{"type":"Undo"}`), &key.PublicKey
+		},
+		"host": func(req *http.Request) ([]byte, *rsa.PublicKey) {
+			req.Host = "other.example"
+			return body, &key.PublicKey
+		},
+		"date": func(req *http.Request) ([]byte, *rsa.PublicKey) {
+			req.Header.Set("Date", time.Now().Add(-2*maxClockSkew).UTC().Format(http.TimeFormat))
+			return body, &key.PublicKey
+		},
+		"key": func(req *http.Request) ([]byte, *rsa.PublicKey) {
+			return body, &other.PublicKey
+		},
+		"signature": func(req *http.Request) ([]byte, *rsa.PublicKey) {
+			req.Header.Del("Signature")
+			return body, &key.PublicKey
+		},
+	}
+
+	for name, tamper := range cases {
+		req := signedRequest(t, body, key)
+		tamperedBody, publicKey := tamper(req)
+
+		if err := Verify(req, tamperedBody, publicKey); err == nil {
+			t.Errorf("Signature should not verify with a tampered %s", name)
+		}
+	}
+}
@@ -0,0 +1,47 @@
+package activitypub
+
+import (
+	"errors"
+	"strings"
+)
+
+const WebFingerContentType = "application/jrd+json"
+
+var ErrInvalidResource = errors.New("resource must look like acct:handle@domain")
+
+type Link struct {
+	Rel  string `json:"rel"`
+	Type string `json:"type,omitempty"`
+	Href string `json:"href"`
+}
+
+type WebFinger struct {
+	Subject string   `json:"subject"`
+	Aliases []string `json:"aliases,omitempty"`
+	Links   []Link   `json:"links"`
+}
+
+func NewWebFinger(resource, actorID string) WebFinger {
+	return WebFinger{
+		Subject: resource,
+		Aliases: []string{actorID},
+		Links: []Link{
+			{Rel: "self", Type: ContentType, Href: actorID},
+		},
+	}
+}
+
+// ParseAccount splits an acct: resource into its handle and domain.
+func ParseAccount(resource string) (string, string, error) {
+	account, ok := strings.CutPrefix(resource, "acct:")
+	if !ok {
+		return "", "", ErrInvalidResource
+	}
+
+	handle, domain, ok := strings.Cut(strings.TrimPrefix(account, "@"), "@")
+	if !ok || handle == "" || domain == "" {
+		return "", "", ErrInvalidResource
+	}
+
+	return handle, strings.ToLower(domain), nil
+}
@@ -0,0 +1,118 @@
+package analytics
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/firerockets/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+type Store interface {
+	AddChirpImpressions(ctx context.Context, arg database.AddChirpImpressionsParams) error
+}
+
+type impressionKey struct {
+	chirpID uuid.UUID
+	day     time.Time
+}
+
+// Impressions counts chirp views in memory and writes them out in batches,
+// so reading a timeline never waits on a write per chirp.
+type Impressions struct {
+	store    Store
+	interval time.Duration
+
+	mu     sync.Mutex
+	counts map[impressionKey]int64
+	now    func() time.Time
+}
+
+func NewImpressions(store Store, interval time.Duration) *Impressions {
+	return &Impressions{
+		store:    store,
+		interval: interval,
+		counts:   map[impressionKey]int64{},
+		now:      time.Now,
+	}
+}
+
+// Add counts one impression of each chirp for the current UTC day.
+func (im *Impressions) Add(chirpIDs ...uuid.UUID) {
+	if len(chirpIDs) == 0 {
+		return
+	}
+
+	day := Day(im.now())
+
+	im.mu.Lock()
+	defer im.mu.Unlock()
+
+	for _, id := range chirpIDs {
+		im.counts[impressionKey{chirpID: id, day: day}]++
+	}
+}
+
+// Run flushes on every tick until ctx is done. The caller flushes one last
+// time once nothing adds impressions anymore.
+func (im *Impressions) Run(ctx context.Context) {
+	ticker := time.NewTicker(im.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		if err := im.Flush(ctx); err != nil {
+			log.Printf("Error flushing chirp impressions: %s\n", err)
+		}
+	}
+}
+
+// Flush writes the buffered impressions in a single query. When the write
+// fails they're put back, to go out with the next flush.
+func (im *Impressions) Flush(ctx context.Context) error {
+	im.mu.Lock()
+	counts := im.counts
+	im.counts = map[impressionKey]int64{}
+	im.mu.Unlock()
+
+	if len(counts) == 0 {
+		return nil
+	}
+
+	arg := database.AddChirpImpressionsParams{
+		ChirpIds: make([]uuid.UUID, 0, len(counts)),
+		Days:     make([]time.Time, 0, len(counts)),
+		Counts:   make([]int64, 0, len(counts)),
+	}
+	for key, count := range counts {
+		arg.ChirpIds = append(arg.ChirpIds, key.chirpID)
+		arg.Days = append(arg.Days, key.day)
+		arg.Counts = append(arg.Counts, count)
+	}
+
+	err := im.store.AddChirpImpressions(ctx, arg)
+	if err != nil {
+		im.mu.Lock()
+		for key, count := range counts {
+			im.counts[key] += count
+		}
+		im.mu.Unlock()
+		return err
+	}
+
+	return nil
+}
+
+// Day truncates t to the start of its UTC day, the unit every rollup is
+// kept in.
+func Day(t time.Time) time.Time {
+	t = t.UTC()
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
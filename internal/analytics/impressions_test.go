@@ -0,0 +1,99 @@
+package analytics
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/firerockets/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+type fakeStore struct {
+	fail    bool
+	flushes int
+	counts  map[impressionKey]int64
+}
+
+func (s *fakeStore) AddChirpImpressions(ctx context.Context, arg database.AddChirpImpressionsParams) error {
+	if s.fail {
+		return errors.New("database is down")
+	}
+	s.flushes++
+	for i, id := range arg.ChirpIds {
+		s.counts[impressionKey{chirpID: id, day: arg.Days[i]}] += arg.Counts[i]
+	}
+	return nil
+}
+
+func TestFlush(t *testing.T) {
+	store := &fakeStore{counts: map[impressionKey]int64{}}
+	impressions := NewImpressions(store, time.Minute)
+
+	now := time.Date(2024, 5, 1, 23, 30, 0, 0, time.UTC)
+	impressions.now = func() time.Time { return now }
+
+	a, b := uuid.New(), uuid.New()
+	impressions.Add(a, b)
+	impressions.Add(a)
+	now = now.Add(time.Hour)
+	impressions.Add(a)
+
+	if err := impressions.Flush(context.Background()); err != nil {
+		t.Fatalf("Flush returned an error: %s", err)
+	}
+
+	if store.flushes != 1 {
+		t.Errorf("Expected a single write, got %d", store.flushes)
+	}
+
+	mayFirst := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	maySecond := mayFirst.AddDate(0, 0, 1)
+	want := map[impressionKey]int64{
+		{chirpID: a, day: mayFirst}:  2,
+		{chirpID: b, day: mayFirst}:  1,
+		{chirpID: a, day: maySecond}: 1,
+	}
+	for key, count := range want {
+		if store.counts[key] != count {
+			t.Errorf("%s on %s: got %d impressions, want %d", key.chirpID, key.day.Format(time.DateOnly), store.counts[key], count)
+		}
+	}
+
+	if err := impressions.Flush(context.Background()); err != nil || store.flushes != 1 {
+		t.Errorf("Flushing nothing should skip the write, got %d writes (%v)", store.flushes, err)
+	}
+}
+
+func TestFlushKeepsCountsOnError(t *testing.T) {
+	store := &fakeStore{fail: true, counts: map[impressionKey]int64{}}
+	impressions := NewImpressions(store, time.Minute)
+
+	id := uuid.New()
+	impressions.Add(id)
+
+	if err := impressions.Flush(context.Background()); err == nil {
+		t.Fatalf("Expected the failed write to be reported")
+	}
+
+	impressions.Add(id)
+	store.fail = false
+
+	if err := impressions.Flush(context.Background()); err != nil {
+		t.Fatalf("Flush returned an error: %s", err)
+	}
+
+	if got := store.counts[impressionKey{chirpID: id, day: Day(time.Now())}]; got != 2 {
+		t.Errorf("Expected 2 impressions after the retry, got %d", got)
+	}
+}
+
+func TestDay(t *testing.T) {
+	local := time.FixedZone("UTC-5", -5*60*60)
+	got := Day(time.Date(2024, 5, 1, 22, 0, 0, 0, local))
+
+	if want := time.Date(2024, 5, 2, 0, 0, 0, 0, time.UTC); !got.Equal(want) {
+		t.Errorf("Day = %s, want %s", got, want)
+	}
+}
@@ -0,0 +1,75 @@
+package analytics
+
+import (
+	"context"
+	"database/sql"
+	"log"
+	"time"
+
+	"github.com/firerockets/chirpy/internal/database"
+)
+
+// Rollup keeps the daily engagement and follower tables up to date. Every
+// run recounts yesterday and today from scratch, which picks up late
+// commits and undone bookmarks without tracking what changed.
+type Rollup struct {
+	db       *sql.DB
+	queries  *database.Queries
+	interval time.Duration
+}
+
+func NewRollup(db *sql.DB, queries *database.Queries, interval time.Duration) *Rollup {
+	return &Rollup{
+		db:       db,
+		queries:  queries,
+		interval: interval,
+	}
+}
+
+// Run rolls up once right away and then on every tick until ctx is done.
+func (r *Rollup) Run(ctx context.Context) {
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+
+	for {
+		if err := r.Roll(ctx, time.Now()); err != nil {
+			log.Printf("Error rolling up analytics: %s\n", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (r *Rollup) Roll(ctx context.Context, now time.Time) error {
+	today := Day(now)
+	since := today.AddDate(0, 0, -1)
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	qtx := r.queries.WithTx(tx)
+
+	err = qtx.DeleteChirpEngagementsSince(ctx, since)
+	if err != nil {
+		return err
+	}
+
+	err = qtx.RollupChirpEngagements(ctx, since)
+	if err != nil {
+		return err
+	}
+
+	err = qtx.RollupFollowerCounts(ctx, today)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
@@ -10,13 +10,26 @@ import (
 	"github.com/google/uuid"
 )
 
-func MakeJWT(userID uuid.UUID, tokenSecret string, expiresIn time.Duration) (string, error) {
+// claims adds the user's plan and entitlements as they were when the token
+// was issued. They let clients show the right features without another
+// request; the server always checks the database instead.
+type claims struct {
+	jwt.RegisteredClaims
+	Plan         string   `json:"plan,omitempty"`
+	Entitlements []string `json:"entitlements,omitempty"`
+}
+
+func MakeJWT(userID uuid.UUID, plan string, entitlements []string, tokenSecret string, expiresIn time.Duration) (string, error) {
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.RegisteredClaims{
-		Issuer:    "chirpy",
-		IssuedAt:  jwt.NewNumericDate(time.Now()),
-		ExpiresAt: jwt.NewNumericDate(time.Now().Add(expiresIn)),
-		Subject:   userID.String(),
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    "chirpy",
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(expiresIn)),
+			Subject:   userID.String(),
+		},
+		Plan:         plan,
+		Entitlements: entitlements,
 	})
 
 	return token.SignedString([]byte(tokenSecret))
@@ -40,6 +53,23 @@ func ValidadeJWT(tokenString, tokenSecret string) (uuid.UUID, error) {
 	}
 }
 
+// JWTExpiresAt returns when a valid token stops being accepted, so long
+// lived connections know when to check for a new one.
+func JWTExpiresAt(tokenString, tokenSecret string) (time.Time, error) {
+	claims := &jwt.RegisteredClaims{}
+	_, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
+		return []byte(tokenSecret), nil
+	})
+
+	if err != nil {
+		return time.Time{}, err
+	} else if claims.ExpiresAt == nil {
+		return time.Time{}, fmt.Errorf("token has no expiration time")
+	}
+
+	return claims.ExpiresAt.Time, nil
+}
+
 func GetBearerToken(headers http.Header) (string, error) {
 	bearer := headers.Get("Authorization")
 
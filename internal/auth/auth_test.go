@@ -1,9 +1,11 @@
 package auth
 
 import (
+	"slices"
 	"testing"
 	"time"
 
+	"github.com/golang-jwt/jwt/v5"
 	"github.com/google/uuid"
 )
 
@@ -12,7 +14,7 @@ func TestMakeJWT(t *testing.T) {
 	secret := "test secret"
 	expiresIn := time.Duration(2) * time.Second
 
-	token, err := MakeJWT(userID, secret, expiresIn)
+	token, err := MakeJWT(userID, "free", nil, secret, expiresIn)
 
 	if err != nil {
 		t.Errorf("Error creating token: %s", err)
@@ -29,12 +31,36 @@ func TestMakeJWT(t *testing.T) {
 	}
 }
 
+func TestMakeJWTIncludesEntitlements(t *testing.T) {
+	secret := "test secret"
+	entitlements := []string{"long_chirps", "analytics"}
+
+	token, err := MakeJWT(uuid.New(), "chirpy_red", entitlements, secret, time.Hour)
+
+	if err != nil {
+		t.Errorf("Error creating token: %s", err)
+	}
+
+	parsed := &claims{}
+	_, err = jwt.ParseWithClaims(token, parsed, func(token *jwt.Token) (interface{}, error) {
+		return []byte(secret), nil
+	})
+
+	if err != nil {
+		t.Errorf("Error parsing token: %s", err)
+	}
+
+	if parsed.Plan != "chirpy_red" || !slices.Equal(parsed.Entitlements, entitlements) {
+		t.Errorf("Expected chirpy_red with %v, got %s with %v", entitlements, parsed.Plan, parsed.Entitlements)
+	}
+}
+
 func TestMakeJWTWhenTokenExpired(t *testing.T) {
 	userID := uuid.New()
 	secret := "test secret"
 	expiresIn := time.Duration(-1_000_000)
 
-	token, err := MakeJWT(userID, secret, expiresIn)
+	token, err := MakeJWT(userID, "free", nil, secret, expiresIn)
 
 	if err != nil {
 		t.Errorf("Error creating token: %s", err)
@@ -50,3 +76,30 @@ func TestMakeJWTWhenTokenExpired(t *testing.T) {
 		t.Error("User ID should not match since token is expired")
 	}
 }
+
+func TestJWTExpiresAt(t *testing.T) {
+	secret := "test secret"
+	expiresIn := time.Hour
+
+	token, err := MakeJWT(uuid.New(), "free", nil, secret, expiresIn)
+
+	if err != nil {
+		t.Errorf("Error creating token: %s", err)
+	}
+
+	expiresAt, err := JWTExpiresAt(token, secret)
+
+	if err != nil {
+		t.Errorf("Error reading token expiration: %s", err)
+	}
+
+	if d := time.Until(expiresAt); d <= 0 || d > expiresIn {
+		t.Errorf("Expected the token to expire within %s, got %s", expiresIn, expiresAt)
+	}
+
+	_, err = JWTExpiresAt(token, "wrong secret")
+
+	if err == nil {
+		t.Error("Token signed with another secret should be invalid")
+	}
+}
@@ -0,0 +1,33 @@
+package chirptext
+
+import (
+	"github.com/firerockets/chirpy/internal/entities"
+	"github.com/rivo/uniseg"
+	"golang.org/x/text/unicode/norm"
+)
+
+// URLWeight is how many characters a URL counts for, whatever its length.
+const URLWeight = 23
+
+// Normalize puts body in NFC form, the form chirps are stored and counted in.
+func Normalize(body string) string {
+	return norm.NFC.String(body)
+}
+
+// Length counts body in grapheme clusters, so an emoji or a letter with a
+// combining accent counts as one character. Every URL counts as URLWeight.
+func Length(body string) int {
+	runes := []rune(body)
+	length := 0
+	last := 0
+
+	for _, e := range entities.Parse(body) {
+		if e.Kind != entities.URL {
+			continue
+		}
+		length += uniseg.GraphemeClusterCount(string(runes[last:e.Start])) + URLWeight
+		last = e.End
+	}
+
+	return length + uniseg.GraphemeClusterCount(string(runes[last:]))
+}
@@ -0,0 +1,29 @@
+package chirptext
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestLength(t *testing.T) {
+	cases := map[string]int{
+		"hello":   5,
+		"こんにちは":   5,
+		"👍🏽 ok":   4,
+		"👨‍👩‍👧‍👦": 1,
+		"e\u0301": 1,
+		"see https://example.com/" + strings.Repeat("a", 100): 4 + URLWeight,
+	}
+
+	for body, expected := range cases {
+		if length := Length(body); length != expected {
+			t.Errorf("Length(%q) should be %d, got %d", body, expected, length)
+		}
+	}
+}
+
+func TestNormalize(t *testing.T) {
+	if Normalize("e\u0301") != "\u00e9" {
+		t.Error("Body should be normalized to NFC")
+	}
+}
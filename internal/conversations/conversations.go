@@ -0,0 +1,48 @@
+package conversations
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/firerockets/chirpy/internal/chirptext"
+	"github.com/google/uuid"
+)
+
+const (
+	// MaxMembers is how many users a conversation can have, including the
+	// one who started it.
+	MaxMembers = 8
+	// MaxMessageLength counts grapheme clusters, like chirps do.
+	MaxMessageLength = 1000
+)
+
+var (
+	ErrEmptyMessage   = errors.New("message can't be empty")
+	ErrMessageTooLong = fmt.Errorf("messages can be up to %d characters long", MaxMessageLength)
+)
+
+// DirectKey identifies the 1:1 conversation between two users, whichever
+// of them starts it.
+func DirectKey(a, b uuid.UUID) string {
+	x, y := a.String(), b.String()
+	if y < x {
+		x, y = y, x
+	}
+	return x + ":" + y
+}
+
+// NormalizeMessage returns the body a message is stored with.
+func NormalizeMessage(body string) (string, error) {
+	body = chirptext.Normalize(strings.TrimSpace(body))
+
+	if body == "" {
+		return "", ErrEmptyMessage
+	}
+
+	if chirptext.Length(body) > MaxMessageLength {
+		return "", ErrMessageTooLong
+	}
+
+	return body, nil
+}
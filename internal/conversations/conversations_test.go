@@ -0,0 +1,44 @@
+package conversations
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestDirectKey(t *testing.T) {
+	a, b := uuid.New(), uuid.New()
+
+	if DirectKey(a, b) != DirectKey(b, a) {
+		t.Error("Direct key should not depend on who starts the conversation")
+	}
+
+	if DirectKey(a, b) == DirectKey(a, uuid.New()) {
+		t.Error("Different pairs should have different keys")
+	}
+}
+
+func TestNormalizeMessage(t *testing.T) {
+	body, err := NormalizeMessage("  é  ")
+
+	if err != nil {
+		t.Errorf("Error normalizing message: %s", err)
+	}
+
+	if body != "é" {
+		t.Errorf("Expected the message to be trimmed and in NFC form, got %q", body)
+	}
+
+	cases := map[string]error{
+		"   ":                                   ErrEmptyMessage,
+		strings.Repeat("a", MaxMessageLength):   nil,
+		strings.Repeat("a", MaxMessageLength+1): ErrMessageTooLong,
+	}
+
+	for body, expected := range cases {
+		if _, err := NormalizeMessage(body); err != expected {
+			t.Errorf("Expected %v for a %d byte message, got %v", expected, len(body), err)
+		}
+	}
+}
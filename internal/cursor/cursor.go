@@ -0,0 +1,49 @@
+package cursor
+
+import (
+	"encoding/base64"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var ErrInvalid = errors.New("invalid cursor")
+
+// Cursor marks a position in a list ordered by a timestamp, with the ID
+// breaking ties between rows that share one. Clients get it as an opaque
+// string.
+type Cursor struct {
+	At time.Time
+	ID uuid.UUID
+}
+
+func (c Cursor) String() string {
+	raw := c.At.UTC().Format(time.RFC3339Nano) + "|" + c.ID.String()
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+func Parse(s string) (Cursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return Cursor{}, ErrInvalid
+	}
+
+	at, id, ok := strings.Cut(string(raw), "|")
+	if !ok {
+		return Cursor{}, ErrInvalid
+	}
+
+	t, err := time.Parse(time.RFC3339Nano, at)
+	if err != nil {
+		return Cursor{}, ErrInvalid
+	}
+
+	parsedID, err := uuid.Parse(id)
+	if err != nil {
+		return Cursor{}, ErrInvalid
+	}
+
+	return Cursor{At: t, ID: parsedID}, nil
+}
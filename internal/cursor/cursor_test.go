@@ -0,0 +1,33 @@
+package cursor
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestRoundTrip(t *testing.T) {
+	c := Cursor{
+		At: time.Date(2026, 3, 14, 15, 9, 26, 535897000, time.UTC),
+		ID: uuid.New(),
+	}
+
+	parsed, err := Parse(c.String())
+
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+
+	if !parsed.At.Equal(c.At) || parsed.ID != c.ID {
+		t.Errorf("Expected %v, got %v", c, parsed)
+	}
+}
+
+func TestParseRejectsGarbage(t *testing.T) {
+	for _, s := range []string{"", "not base64!", "bm8tc2VwYXJhdG9y", Cursor{ID: uuid.New()}.String()[:10]} {
+		if _, err := Parse(s); err == nil {
+			t.Errorf("Expected %q to be rejected", s)
+		}
+	}
+}
@@ -7,23 +7,58 @@ package database
 
 import (
 	"context"
+	"database/sql"
+	"time"
 
 	"github.com/google/uuid"
+	"github.com/lib/pq"
 )
 
+const announceChirp = `-- name: AnnounceChirp :exec
+SELECT pg_notify('chirp_published', $1::text)
+`
+
+func (q *Queries) AnnounceChirp(ctx context.Context, chirpID string) error {
+	_, err := q.db.ExecContext(ctx, announceChirp, chirpID)
+	return err
+}
+
+const countPublishedChirpsByUserId = `-- name: CountPublishedChirpsByUserId :one
+SELECT COUNT(*) FROM chirps
+WHERE user_id = $1 AND published_at IS NOT NULL AND removed_at IS NULL AND deleted_at IS NULL
+`
+
+func (q *Queries) CountPublishedChirpsByUserId(ctx context.Context, userID uuid.UUID) (int64, error) {
+	row := q.db.QueryRowContext(ctx, countPublishedChirpsByUserId, userID)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
 const createChirp = `-- name: CreateChirp :one
-INSERT INTO chirps (id, created_at, updated_at, body, user_id)
-VALUES (gen_random_uuid(), NOW(), NOW(), $1, $2)
-RETURNING id, created_at, updated_at, body, user_id
+INSERT INTO chirps (id, created_at, updated_at, body, user_id, published_at, publish_at, is_draft)
+VALUES (
+    gen_random_uuid(), NOW(), NOW(), $1, $2,
+    CASE WHEN NOT $3::boolean AND $4::timestamptz IS NULL THEN NOW() END,
+    $4, $3
+)
+RETURNING id, created_at, updated_at, body, user_id, published_at, publish_at, is_draft, removed_at, deleted_at
 `
 
 type CreateChirpParams struct {
-	Body   string
-	UserID uuid.UUID
+	Body      string
+	UserID    uuid.UUID
+	IsDraft   bool
+	PublishAt sql.NullTime
 }
 
 func (q *Queries) CreateChirp(ctx context.Context, arg CreateChirpParams) (Chirp, error) {
-	row := q.db.QueryRowContext(ctx, createChirp, arg.Body, arg.UserID)
+	row := q.db.QueryRowContext(ctx, createChirp,
+		arg.Body,
+		arg.UserID,
+		arg.IsDraft,
+		arg.PublishAt,
+	)
 	var i Chirp
 	err := row.Scan(
 		&i.ID,
@@ -31,13 +66,19 @@ func (q *Queries) CreateChirp(ctx context.Context, arg CreateChirpParams) (Chirp
 		&i.UpdatedAt,
 		&i.Body,
 		&i.UserID,
+		&i.PublishedAt,
+		&i.PublishAt,
+		&i.IsDraft,
+		&i.RemovedAt,
+		&i.DeletedAt,
 	)
 	return i, err
 }
 
 const deleteChirpById = `-- name: DeleteChirpById :exec
-DELETE FROM chirps
-WHERE id = $1
+UPDATE chirps
+SET updated_at = NOW(), deleted_at = NOW()
+WHERE id = $1 AND deleted_at IS NULL
 `
 
 func (q *Queries) DeleteChirpById(ctx context.Context, id uuid.UUID) error {
@@ -45,8 +86,18 @@ func (q *Queries) DeleteChirpById(ctx context.Context, id uuid.UUID) error {
 	return err
 }
 
+const deleteUnpublishedChirp = `-- name: DeleteUnpublishedChirp :exec
+DELETE FROM chirps
+WHERE id = $1 AND published_at IS NULL
+`
+
+func (q *Queries) DeleteUnpublishedChirp(ctx context.Context, id uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, deleteUnpublishedChirp, id)
+	return err
+}
+
 const getChirpById = `-- name: GetChirpById :one
-SELECT id, created_at, updated_at, body, user_id FROM chirps
+SELECT id, created_at, updated_at, body, user_id, published_at, publish_at, is_draft, removed_at, deleted_at FROM chirps
 WHERE id = $1
 LIMIT 1
 `
@@ -60,13 +111,20 @@ func (q *Queries) GetChirpById(ctx context.Context, id uuid.UUID) (Chirp, error)
 		&i.UpdatedAt,
 		&i.Body,
 		&i.UserID,
+		&i.PublishedAt,
+		&i.PublishAt,
+		&i.IsDraft,
+		&i.RemovedAt,
+		&i.DeletedAt,
 	)
 	return i, err
 }
 
 const getChirps = `-- name: GetChirps :many
-SELECT id, created_at, updated_at, body, user_id FROM chirps
-ORDER BY created_at
+SELECT chirps.id, chirps.created_at, chirps.updated_at, chirps.body, chirps.user_id, chirps.published_at, chirps.publish_at, chirps.is_draft, chirps.removed_at, chirps.deleted_at FROM chirps
+JOIN users ON users.id = chirps.user_id AND users.suspended_at IS NULL
+WHERE chirps.published_at IS NOT NULL AND chirps.removed_at IS NULL AND chirps.deleted_at IS NULL
+ORDER BY chirps.published_at
 `
 
 func (q *Queries) GetChirps(ctx context.Context) ([]Chirp, error) {
@@ -84,6 +142,11 @@ func (q *Queries) GetChirps(ctx context.Context) ([]Chirp, error) {
 			&i.UpdatedAt,
 			&i.Body,
 			&i.UserID,
+			&i.PublishedAt,
+			&i.PublishAt,
+			&i.IsDraft,
+			&i.RemovedAt,
+			&i.DeletedAt,
 		); err != nil {
 			return nil, err
 		}
@@ -99,9 +162,10 @@ func (q *Queries) GetChirps(ctx context.Context) ([]Chirp, error) {
 }
 
 const getChirpsByUserId = `-- name: GetChirpsByUserId :many
-SELECT id, created_at, updated_at, body, user_id FROM chirps
-WHERE user_id = $1
-ORDER BY created_at
+SELECT chirps.id, chirps.created_at, chirps.updated_at, chirps.body, chirps.user_id, chirps.published_at, chirps.publish_at, chirps.is_draft, chirps.removed_at, chirps.deleted_at FROM chirps
+JOIN users ON users.id = chirps.user_id AND users.suspended_at IS NULL
+WHERE chirps.user_id = $1 AND chirps.published_at IS NOT NULL AND chirps.removed_at IS NULL AND chirps.deleted_at IS NULL
+ORDER BY chirps.published_at
 `
 
 func (q *Queries) GetChirpsByUserId(ctx context.Context, userID uuid.UUID) ([]Chirp, error) {
@@ -119,6 +183,225 @@ func (q *Queries) GetChirpsByUserId(ctx context.Context, userID uuid.UUID) ([]Ch
 			&i.UpdatedAt,
 			&i.Body,
 			&i.UserID,
+			&i.PublishedAt,
+			&i.PublishAt,
+			&i.IsDraft,
+			&i.RemovedAt,
+			&i.DeletedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getChirpsPublishedAfter = `-- name: GetChirpsPublishedAfter :many
+SELECT chirps.id, chirps.created_at, chirps.updated_at, chirps.body, chirps.user_id, chirps.published_at, chirps.publish_at, chirps.is_draft, chirps.removed_at, chirps.deleted_at FROM chirps
+JOIN users ON users.id = chirps.user_id AND users.suspended_at IS NULL
+WHERE chirps.published_at IS NOT NULL AND chirps.removed_at IS NULL AND chirps.deleted_at IS NULL
+AND (chirps.published_at, chirps.id) > ($1::timestamp, $2::uuid)
+AND ($3::uuid IS NULL OR chirps.user_id = $3::uuid)
+AND ($4::text IS NULL OR EXISTS (
+    SELECT 1 FROM chirp_hashtags
+    JOIN hashtags ON hashtags.id = chirp_hashtags.hashtag_id
+    WHERE chirp_hashtags.chirp_id = chirps.id AND hashtags.tag = $4::text
+))
+AND NOT (chirps.user_id = ANY($5::uuid[]))
+ORDER BY chirps.published_at, chirps.id
+LIMIT $6
+`
+
+type GetChirpsPublishedAfterParams struct {
+	AfterPublishedAt time.Time
+	AfterID          uuid.UUID
+	AuthorID         uuid.NullUUID
+	Hashtag          sql.NullString
+	ExcludedUserIds  []uuid.UUID
+	Limit            int32
+}
+
+func (q *Queries) GetChirpsPublishedAfter(ctx context.Context, arg GetChirpsPublishedAfterParams) ([]Chirp, error) {
+	rows, err := q.db.QueryContext(ctx, getChirpsPublishedAfter,
+		arg.AfterPublishedAt,
+		arg.AfterID,
+		arg.AuthorID,
+		arg.Hashtag,
+		pq.Array(arg.ExcludedUserIds),
+		arg.Limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Chirp
+	for rows.Next() {
+		var i Chirp
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Body,
+			&i.UserID,
+			&i.PublishedAt,
+			&i.PublishAt,
+			&i.IsDraft,
+			&i.RemovedAt,
+			&i.DeletedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getDueChirpIds = `-- name: GetDueChirpIds :many
+SELECT id FROM chirps
+WHERE published_at IS NULL AND NOT is_draft AND publish_at <= NOW() AND deleted_at IS NULL
+ORDER BY publish_at
+LIMIT $1
+`
+
+func (q *Queries) GetDueChirpIds(ctx context.Context, limit int32) ([]uuid.UUID, error) {
+	rows, err := q.db.QueryContext(ctx, getDueChirpIds, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []uuid.UUID
+	for rows.Next() {
+		var id uuid.UUID
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		items = append(items, id)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getFeedChirps = `-- name: GetFeedChirps :many
+SELECT chirps.id, chirps.created_at, chirps.updated_at, chirps.body, chirps.user_id, chirps.published_at, chirps.publish_at, chirps.is_draft, chirps.removed_at, chirps.deleted_at FROM chirps
+JOIN users ON users.id = chirps.user_id AND users.suspended_at IS NULL
+WHERE chirps.published_at IS NOT NULL AND chirps.removed_at IS NULL AND chirps.deleted_at IS NULL
+AND ($1::uuid IS NULL OR chirps.user_id = $1::uuid)
+AND ($2::text IS NULL OR EXISTS (
+    SELECT 1 FROM chirp_hashtags
+    JOIN hashtags ON hashtags.id = chirp_hashtags.hashtag_id
+    WHERE chirp_hashtags.chirp_id = chirps.id AND hashtags.tag = $2::text
+))
+AND NOT (chirps.user_id = ANY($3::uuid[]))
+ORDER BY chirps.published_at DESC, chirps.id DESC
+LIMIT $4
+`
+
+type GetFeedChirpsParams struct {
+	AuthorID        uuid.NullUUID
+	Hashtag         sql.NullString
+	ExcludedUserIds []uuid.UUID
+	Limit           int32
+}
+
+func (q *Queries) GetFeedChirps(ctx context.Context, arg GetFeedChirpsParams) ([]Chirp, error) {
+	rows, err := q.db.QueryContext(ctx, getFeedChirps,
+		arg.AuthorID,
+		arg.Hashtag,
+		pq.Array(arg.ExcludedUserIds),
+		arg.Limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Chirp
+	for rows.Next() {
+		var i Chirp
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Body,
+			&i.UserID,
+			&i.PublishedAt,
+			&i.PublishAt,
+			&i.IsDraft,
+			&i.RemovedAt,
+			&i.DeletedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getOutboxChirps = `-- name: GetOutboxChirps :many
+SELECT id, created_at, updated_at, body, user_id, published_at, publish_at, is_draft, removed_at, deleted_at FROM chirps
+WHERE user_id = $1 AND published_at IS NOT NULL AND removed_at IS NULL AND deleted_at IS NULL
+AND (
+    $2::timestamp IS NULL
+    OR (published_at, id) < ($2::timestamp, $3::uuid)
+)
+ORDER BY published_at DESC, id DESC
+LIMIT $4
+`
+
+type GetOutboxChirpsParams struct {
+	UserID            uuid.UUID
+	BeforePublishedAt sql.NullTime
+	BeforeID          uuid.NullUUID
+	Limit             int32
+}
+
+func (q *Queries) GetOutboxChirps(ctx context.Context, arg GetOutboxChirpsParams) ([]Chirp, error) {
+	rows, err := q.db.QueryContext(ctx, getOutboxChirps,
+		arg.UserID,
+		arg.BeforePublishedAt,
+		arg.BeforeID,
+		arg.Limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Chirp
+	for rows.Next() {
+		var i Chirp
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Body,
+			&i.UserID,
+			&i.PublishedAt,
+			&i.PublishAt,
+			&i.IsDraft,
+			&i.RemovedAt,
+			&i.DeletedAt,
 		); err != nil {
 			return nil, err
 		}
@@ -132,3 +415,165 @@ func (q *Queries) GetChirpsByUserId(ctx context.Context, userID uuid.UUID) ([]Ch
 	}
 	return items, nil
 }
+
+const getUnpublishedChirpsByUserId = `-- name: GetUnpublishedChirpsByUserId :many
+SELECT id, created_at, updated_at, body, user_id, published_at, publish_at, is_draft, removed_at, deleted_at FROM chirps
+WHERE user_id = $1 AND published_at IS NULL AND deleted_at IS NULL
+ORDER BY is_draft, publish_at, created_at
+`
+
+func (q *Queries) GetUnpublishedChirpsByUserId(ctx context.Context, userID uuid.UUID) ([]Chirp, error) {
+	rows, err := q.db.QueryContext(ctx, getUnpublishedChirpsByUserId, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Chirp
+	for rows.Next() {
+		var i Chirp
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Body,
+			&i.UserID,
+			&i.PublishedAt,
+			&i.PublishAt,
+			&i.IsDraft,
+			&i.RemovedAt,
+			&i.DeletedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const publishDueChirp = `-- name: PublishDueChirp :one
+UPDATE chirps
+SET updated_at = NOW(), published_at = NOW()
+WHERE id = $1 AND published_at IS NULL AND NOT is_draft AND publish_at <= NOW() AND deleted_at IS NULL
+RETURNING id, created_at, updated_at, body, user_id, published_at, publish_at, is_draft, removed_at, deleted_at
+`
+
+// Returns no rows when the chirp was published, unscheduled or deleted
+// since it came due.
+func (q *Queries) PublishDueChirp(ctx context.Context, id uuid.UUID) (Chirp, error) {
+	row := q.db.QueryRowContext(ctx, publishDueChirp, id)
+	var i Chirp
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Body,
+		&i.UserID,
+		&i.PublishedAt,
+		&i.PublishAt,
+		&i.IsDraft,
+		&i.RemovedAt,
+		&i.DeletedAt,
+	)
+	return i, err
+}
+
+const purgeDeletedChirps = `-- name: PurgeDeletedChirps :execrows
+DELETE FROM chirps
+WHERE deleted_at < NOW() - make_interval(days => $1::int)
+`
+
+func (q *Queries) PurgeDeletedChirps(ctx context.Context, retentionDays int32) (int64, error) {
+	result, err := q.db.ExecContext(ctx, purgeDeletedChirps, retentionDays)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const removeChirp = `-- name: RemoveChirp :exec
+UPDATE chirps
+SET updated_at = NOW(), removed_at = NOW()
+WHERE id = $1 AND removed_at IS NULL
+`
+
+func (q *Queries) RemoveChirp(ctx context.Context, id uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, removeChirp, id)
+	return err
+}
+
+const restoreChirp = `-- name: RestoreChirp :one
+UPDATE chirps
+SET updated_at = NOW(), deleted_at = NULL
+WHERE id = $1 AND deleted_at >= NOW() - make_interval(days => $2::int)
+RETURNING id, created_at, updated_at, body, user_id, published_at, publish_at, is_draft, removed_at, deleted_at
+`
+
+type RestoreChirpParams struct {
+	ID            uuid.UUID
+	RetentionDays int32
+}
+
+func (q *Queries) RestoreChirp(ctx context.Context, arg RestoreChirpParams) (Chirp, error) {
+	row := q.db.QueryRowContext(ctx, restoreChirp, arg.ID, arg.RetentionDays)
+	var i Chirp
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Body,
+		&i.UserID,
+		&i.PublishedAt,
+		&i.PublishAt,
+		&i.IsDraft,
+		&i.RemovedAt,
+		&i.DeletedAt,
+	)
+	return i, err
+}
+
+const updateUnpublishedChirp = `-- name: UpdateUnpublishedChirp :one
+UPDATE chirps
+SET updated_at = NOW(),
+    body = $1,
+    published_at = CASE WHEN NOT $2::boolean AND $3::timestamptz IS NULL THEN NOW() END,
+    publish_at = $3,
+    is_draft = $2
+WHERE id = $4 AND published_at IS NULL AND deleted_at IS NULL
+RETURNING id, created_at, updated_at, body, user_id, published_at, publish_at, is_draft, removed_at, deleted_at
+`
+
+type UpdateUnpublishedChirpParams struct {
+	Body      string
+	IsDraft   bool
+	PublishAt sql.NullTime
+	ID        uuid.UUID
+}
+
+func (q *Queries) UpdateUnpublishedChirp(ctx context.Context, arg UpdateUnpublishedChirpParams) (Chirp, error) {
+	row := q.db.QueryRowContext(ctx, updateUnpublishedChirp,
+		arg.Body,
+		arg.IsDraft,
+		arg.PublishAt,
+		arg.ID,
+	)
+	var i Chirp
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Body,
+		&i.UserID,
+		&i.PublishedAt,
+		&i.PublishAt,
+		&i.IsDraft,
+		&i.RemovedAt,
+		&i.DeletedAt,
+	)
+	return i, err
+}
@@ -0,0 +1,49 @@
+package database
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestFeedChirpsHideSuspendedAuthors(t *testing.T) {
+	q := openTestDB(t)
+	ctx := context.Background()
+	suffix := uuid.NewString()[:8]
+
+	author := createTestUser(t, q, "feedauthor_"+suffix)
+	suspended := createTestUser(t, q, "feedsuspended_"+suffix)
+
+	for _, usr := range []User{author, suspended} {
+		_, err := q.CreateChirp(ctx, CreateChirpParams{Body: "hello", UserID: usr.ID})
+		if err != nil {
+			t.Fatalf("Error creating chirp: %s", err)
+		}
+	}
+
+	err := q.SuspendUser(ctx, suspended.ID)
+	if err != nil {
+		t.Fatalf("Error suspending user: %s", err)
+	}
+
+	for _, usr := range []User{author, suspended} {
+		chirps, err := q.GetFeedChirps(ctx, GetFeedChirpsParams{
+			AuthorID:        uuid.NullUUID{UUID: usr.ID, Valid: true},
+			ExcludedUserIds: []uuid.UUID{},
+			Limit:           10,
+		})
+		if err != nil {
+			t.Fatalf("Error getting feed chirps: %s", err)
+		}
+
+		want := 1
+		if usr.ID == suspended.ID {
+			want = 0
+		}
+
+		if len(chirps) != want {
+			t.Errorf("Expected %d chirps by %s, got %d", want, usr.Handle, len(chirps))
+		}
+	}
+}
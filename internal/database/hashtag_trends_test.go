@@ -0,0 +1,133 @@
+package database
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// createTaggedChirps creates a hashtag and count chirps by author using it.
+func createTaggedChirps(t *testing.T, q *Queries, author User, tag string, count int) (Hashtag, []Chirp) {
+	t.Helper()
+	ctx := context.Background()
+
+	hashtag, err := q.UpsertHashtag(ctx, tag)
+	if err != nil {
+		t.Fatalf("Error creating hashtag: %s", err)
+	}
+	t.Cleanup(func() {
+		q.db.ExecContext(context.Background(), "DELETE FROM hashtags WHERE id = $1", hashtag.ID)
+	})
+
+	chirps := []Chirp{}
+
+	for i := 0; i < count; i++ {
+		chirp, err := q.CreateChirp(ctx, CreateChirpParams{Body: "#" + tag, UserID: author.ID})
+		if err != nil {
+			t.Fatalf("Error creating chirp: %s", err)
+		}
+
+		err = q.CreateChirpHashtag(ctx, CreateChirpHashtagParams{ChirpID: chirp.ID, HashtagID: hashtag.ID})
+		if err != nil {
+			t.Fatalf("Error tagging chirp: %s", err)
+		}
+
+		chirps = append(chirps, chirp)
+	}
+
+	return hashtag, chirps
+}
+
+// recountUses recounts the buckets changed since the given time and returns
+// how many times the hashtag was used in the hour before it.
+func recountUses(t *testing.T, q *Queries, hashtagID uuid.UUID, since time.Time) int64 {
+	t.Helper()
+	ctx := context.Background()
+
+	if err := q.DeleteStaleHashtagBuckets(ctx, since); err != nil {
+		t.Fatalf("Error clearing buckets: %s", err)
+	}
+	if err := q.UpsertHashtagBuckets(ctx, since); err != nil {
+		t.Fatalf("Error counting buckets: %s", err)
+	}
+
+	rows, err := q.GetHashtagWindowCounts(ctx, GetHashtagWindowCountsParams{
+		CurrentStart:  since.Add(-time.Hour),
+		PreviousStart: since.Add(-2 * time.Hour),
+	})
+	if err != nil {
+		t.Fatalf("Error getting window counts: %s", err)
+	}
+
+	for _, r := range rows {
+		if r.HashtagID == hashtagID {
+			return r.Uses
+		}
+	}
+	return 0
+}
+
+func TestHashtagBucketsDropDeletedChirps(t *testing.T) {
+	q := openTestDB(t)
+	ctx := context.Background()
+	suffix := uuid.NewString()[:8]
+
+	author := createTestUser(t, q, "trendauthor_"+suffix)
+	hashtag, chirps := createTaggedChirps(t, q, author, "trend"+suffix, 2)
+
+	firstRun := chirps[0].CreatedAt.Add(-time.Minute)
+
+	if got := recountUses(t, q, hashtag.ID, firstRun); got != 2 {
+		t.Fatalf("Expected 2 uses, got %d", got)
+	}
+
+	// Read the clock from the database, which stamps the chirps.
+	secondRun, err := q.GetDatabaseTime(ctx)
+	if err != nil {
+		t.Fatalf("Error reading the time: %s", err)
+	}
+
+	err = q.DeleteChirpById(ctx, chirps[0].ID)
+	if err != nil {
+		t.Fatalf("Error deleting chirp: %s", err)
+	}
+
+	// The deleted chirp was published before the second run, but its bucket
+	// is recounted all the same.
+	if got := recountUses(t, q, hashtag.ID, secondRun); got != 1 {
+		t.Errorf("Expected 1 use after the deletion, got %d", got)
+	}
+}
+
+func TestHashtagBucketsDropSuspendedAuthors(t *testing.T) {
+	q := openTestDB(t)
+	ctx := context.Background()
+	suffix := uuid.NewString()[:8]
+
+	author := createTestUser(t, q, "trendsuspended_"+suffix)
+	hashtag, chirps := createTaggedChirps(t, q, author, "suspended"+suffix, 2)
+
+	firstRun := chirps[0].CreatedAt.Add(-time.Minute)
+
+	if got := recountUses(t, q, hashtag.ID, firstRun); got != 2 {
+		t.Fatalf("Expected 2 uses, got %d", got)
+	}
+
+	secondRun, err := q.GetDatabaseTime(ctx)
+	if err != nil {
+		t.Fatalf("Error reading the time: %s", err)
+	}
+
+	err = q.SuspendUser(ctx, author.ID)
+	if err != nil {
+		t.Fatalf("Error suspending user: %s", err)
+	}
+
+	// None of the chirps changed, but the suspension has their buckets
+	// recounted without them.
+	if got := recountUses(t, q, hashtag.ID, secondRun); got != 0 {
+		t.Errorf("Expected no uses after the suspension, got %d", got)
+	}
+}
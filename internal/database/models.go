@@ -11,12 +11,251 @@ import (
 	"github.com/google/uuid"
 )
 
+type ActorKey struct {
+	UserID        uuid.UUID
+	CreatedAt     time.Time
+	PublicKeyPem  string
+	PrivateKeyPem string
+}
+
+type Block struct {
+	BlockerID uuid.UUID
+	BlockedID uuid.UUID
+	CreatedAt time.Time
+}
+
+type Bookmark struct {
+	UserID    uuid.UUID
+	ChirpID   uuid.UUID
+	CreatedAt time.Time
+}
+
 type Chirp struct {
+	ID          uuid.UUID
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+	Body        string
+	UserID      uuid.UUID
+	PublishedAt sql.NullTime
+	PublishAt   sql.NullTime
+	IsDraft     bool
+	RemovedAt   sql.NullTime
+	DeletedAt   sql.NullTime
+}
+
+type ChirpEngagementsDaily struct {
+	ChirpID     uuid.UUID
+	Day         time.Time
+	Engagements int64
+}
+
+type ChirpFlag struct {
+	ID        uuid.UUID
+	CreatedAt time.Time
+	ChirpID   uuid.UUID
+	Reason    string
+}
+
+type ChirpHashtag struct {
+	ChirpID     uuid.UUID
+	HashtagID   uuid.UUID
+	StartOffset int32
+	EndOffset   int32
+}
+
+type ChirpImpressionsDaily struct {
+	ChirpID     uuid.UUID
+	Day         time.Time
+	Impressions int64
+}
+
+type ChirpLink struct {
+	ChirpID     uuid.UUID
+	Url         string
+	StartOffset int32
+	EndOffset   int32
+}
+
+type ChirpMedium struct {
+	ChirpID  uuid.UUID
+	MediaID  uuid.UUID
+	Position int32
+}
+
+type ChirpMention struct {
+	ChirpID     uuid.UUID
+	UserID      uuid.UUID
+	StartOffset int32
+	EndOffset   int32
+}
+
+type Collection struct {
+	ID        uuid.UUID
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	UserID    uuid.UUID
+	Name      string
+}
+
+type CollectionChirp struct {
+	CollectionID uuid.UUID
+	ChirpID      uuid.UUID
+	CreatedAt    time.Time
+}
+
+type Conversation struct {
+	ID        uuid.UUID
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	DirectKey sql.NullString
+}
+
+type ConversationMember struct {
+	ConversationID uuid.UUID
+	UserID         uuid.UUID
+	JoinedAt       time.Time
+	LastReadAt     sql.NullTime
+}
+
+type Delivery struct {
+	ID            uuid.UUID
+	CreatedAt     time.Time
+	UserID        uuid.UUID
+	Inbox         string
+	Payload       string
+	Attempts      int32
+	NextAttemptAt time.Time
+}
+
+type FilterRule struct {
+	Word      string
+	Policy    string
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+type FollowerCountsDaily struct {
+	UserID    uuid.UUID
+	Day       time.Time
+	Followers int64
+}
+
+type Hashtag struct {
+	ID        uuid.UUID
+	CreatedAt time.Time
+	Tag       string
+}
+
+type HashtagBucket struct {
+	HashtagID   uuid.UUID
+	BucketStart time.Time
+	Uses        int32
+}
+
+type HashtagDenylist struct {
+	Tag       string
+	CreatedAt time.Time
+	Reason    string
+}
+
+type LinkPreview struct {
+	Url           string
+	CreatedAt     time.Time
+	FetchedAt     sql.NullTime
+	Attempts      int32
+	NextAttemptAt time.Time
+	Title         string
+	Description   string
+	ImageUrl      string
+	SiteName      string
+}
+
+type Medium struct {
+	ID           uuid.UUID
+	CreatedAt    time.Time
+	UserID       uuid.UUID
+	StorageKey   string
+	ThumbnailKey string
+	ContentType  string
+	Width        int32
+	Height       int32
+	AltText      string
+}
+
+type Message struct {
+	ID             uuid.UUID
+	CreatedAt      time.Time
+	ConversationID uuid.UUID
+	SenderID       uuid.UUID
+	Body           string
+}
+
+type ModerationAction struct {
+	ID          uuid.UUID
+	CreatedAt   time.Time
+	CaseID      uuid.UUID
+	ModeratorID uuid.UUID
+	Action      string
+	Note        string
+}
+
+type ModerationCase struct {
+	ID         uuid.UUID
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
+	ChirpID    uuid.NullUUID
+	UserID     uuid.UUID
+	Status     string
+	ResolvedAt sql.NullTime
+}
+
+type Mute struct {
+	MuterID   uuid.UUID
+	MutedID   uuid.UUID
+	CreatedAt time.Time
+}
+
+type Notification struct {
 	ID        uuid.UUID
 	CreatedAt time.Time
 	UpdatedAt time.Time
-	Body      string
 	UserID    uuid.UUID
+	Kind      string
+	ChirpID   uuid.UUID
+	ReadAt    sql.NullTime
+}
+
+type NotificationActor struct {
+	NotificationID uuid.UUID
+	ActorID        uuid.UUID
+	CreatedAt      time.Time
+}
+
+type PinnedChirp struct {
+	UserID   uuid.UUID
+	ChirpID  uuid.UUID
+	PinnedAt time.Time
+}
+
+type Poll struct {
+	ID        uuid.UUID
+	CreatedAt time.Time
+	ChirpID   uuid.UUID
+	ClosesAt  time.Time
+}
+
+type PollOption struct {
+	ID       uuid.UUID
+	PollID   uuid.UUID
+	Position int32
+	Text     string
+}
+
+type PollVote struct {
+	PollID    uuid.UUID
+	OptionID  uuid.UUID
+	UserID    uuid.UUID
+	CreatedAt time.Time
 }
 
 type RefreshToken struct {
@@ -28,11 +267,63 @@ type RefreshToken struct {
 	RevokedAt sql.NullTime
 }
 
+type RemoteFollower struct {
+	UserID    uuid.UUID
+	ActorID   string
+	Inbox     string
+	FollowID  string
+	CreatedAt time.Time
+}
+
+type RemoteInteraction struct {
+	ChirpID    uuid.UUID
+	ActorID    string
+	Kind       string
+	ActivityID string
+	CreatedAt  time.Time
+}
+
+type Report struct {
+	ID         uuid.UUID
+	CreatedAt  time.Time
+	CaseID     uuid.UUID
+	ReporterID uuid.UUID
+	Category   string
+	Comment    string
+}
+
+type Subscription struct {
+	ID                 uuid.UUID
+	UserID             uuid.UUID
+	Plan               string
+	Status             string
+	CurrentPeriodStart time.Time
+	CurrentPeriodEnd   time.Time
+	Source             string
+	CreatedAt          time.Time
+	UpdatedAt          time.Time
+}
+
+type TrendingHashtag struct {
+	TimeWindow   string
+	HashtagID    uuid.UUID
+	Rank         int32
+	Uses         int64
+	PreviousUses int64
+	Score        float64
+	ComputedAt   time.Time
+}
+
 type User struct {
 	ID             uuid.UUID
 	CreatedAt      time.Time
 	UpdatedAt      time.Time
 	Email          string
 	HashedPassword string
-	IsChirpyRed    bool
+	IsAdmin        bool
+	Handle         string
+	DisplayName    string
+	Bio            string
+	AvatarKey      sql.NullString
+	SuspendedAt    sql.NullTime
 }
@@ -0,0 +1,88 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"testing"
+
+	"github.com/google/uuid"
+	_ "github.com/lib/pq"
+)
+
+// openTestDB connects to the migrated database in TEST_DB_URL. Query tests
+// are skipped without one.
+func openTestDB(t *testing.T) *Queries {
+	dbURL := os.Getenv("TEST_DB_URL")
+	if dbURL == "" {
+		t.Skip("TEST_DB_URL isn't set")
+	}
+
+	db, err := sql.Open("postgres", dbURL)
+	if err != nil {
+		t.Fatalf("Error opening database: %s", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	return New(db)
+}
+
+func TestSearchUsersRanksByFollowers(t *testing.T) {
+	q := openTestDB(t)
+	ctx := context.Background()
+	suffix := uuid.NewString()[:8]
+
+	// Neither handle matches the query exactly, and the less followed
+	// one is the closer match.
+	closer := createTestUser(t, q, "searchrank_"+suffix+"a")
+	popular := createTestUser(t, q, "searchrank_"+suffix+"_popular")
+
+	for i := 0; i < 3; i++ {
+		actor := fmt.Sprintf("https://remote.example/users/%s-%d", suffix, i)
+		err := q.UpsertRemoteFollower(ctx, UpsertRemoteFollowerParams{
+			UserID:   popular.ID,
+			ActorID:  actor,
+			Inbox:    actor + "/inbox",
+			FollowID: actor + "/follow",
+		})
+		if err != nil {
+			t.Fatalf("Error adding follower: %s", err)
+		}
+	}
+
+	users, err := q.SearchUsers(ctx, SearchUsersParams{
+		Prefix:          "searchrank_" + suffix + "%",
+		Query:           "searchrank_" + suffix,
+		ExcludedUserIds: []uuid.UUID{},
+		Limit:           10,
+	})
+	if err != nil {
+		t.Fatalf("Error searching users: %s", err)
+	}
+
+	if len(users) != 2 {
+		t.Fatalf("Expected 2 matches, got %d", len(users))
+	}
+
+	if users[0].ID != popular.ID || users[1].ID != closer.ID {
+		t.Errorf("Expected %s before %s, got %s then %s", popular.Handle, closer.Handle, users[0].Handle, users[1].Handle)
+	}
+}
+
+func createTestUser(t *testing.T, q *Queries, handle string) User {
+	usr, err := q.CreateUser(context.Background(), CreateUserParams{
+		Email:          handle + "@example.com",
+		HashedPassword: "unused",
+		Handle:         handle,
+	})
+	if err != nil {
+		t.Fatalf("Error creating user %s: %s", handle, err)
+	}
+
+	t.Cleanup(func() {
+		q.db.ExecContext(context.Background(), "DELETE FROM users WHERE id = $1", usr.ID)
+	})
+
+	return usr
+}
@@ -0,0 +1,129 @@
+package entities
+
+import (
+	"strings"
+	"unicode"
+)
+
+type Kind string
+
+const (
+	Hashtag Kind = "hashtag"
+	Mention Kind = "mention"
+	URL     Kind = "url"
+)
+
+const maxHashtagLength = 100
+
+// Entity is a hashtag, mention or URL found in a chirp body. Start and End
+// are rune offsets into the body, End being exclusive.
+type Entity struct {
+	Kind  Kind
+	Text  string
+	Start int
+	End   int
+}
+
+// Parse extracts entities from body in the order they appear. Hashtags are
+// returned lowercased without the '#', mentions without the leading '@'.
+func Parse(body string) []Entity {
+	runes := []rune(body)
+	found := []Entity{}
+
+	for i := 0; i < len(runes); {
+		if i > 0 && isWordRune(runes[i-1]) {
+			i++
+			continue
+		}
+
+		var end int
+		var entity Entity
+
+		switch {
+		case hasURLPrefix(runes[i:]):
+			end = scanURL(runes, i)
+			entity = Entity{Kind: URL, Text: string(runes[i:end])}
+		case runes[i] == '#':
+			end = scanWhile(runes, i+1, isWordRune)
+			tag := string(runes[i+1 : end])
+			if !strings.ContainsFunc(tag, unicode.IsLetter) || len([]rune(tag)) > maxHashtagLength {
+				i++
+				continue
+			}
+			entity = Entity{Kind: Hashtag, Text: NormalizeTag(tag)}
+		case runes[i] == '@':
+			end = scanMention(runes, i+1)
+			if end == i+1 {
+				i++
+				continue
+			}
+			entity = Entity{Kind: Mention, Text: strings.ToLower(string(runes[i+1 : end]))}
+		default:
+			i++
+			continue
+		}
+
+		entity.Start = i
+		entity.End = end
+		found = append(found, entity)
+		i = end
+	}
+
+	return found
+}
+
+// NormalizeTag returns the canonical form of a hashtag as it is stored.
+func NormalizeTag(tag string) string {
+	return strings.ToLower(strings.TrimPrefix(tag, "#"))
+}
+
+func isWordRune(r rune) bool {
+	return r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r) || unicode.Is(unicode.Mn, r)
+}
+
+func isHandleRune(r rune) bool {
+	return r == '_' || r == '.' || r == '-' || r == '+' || (r < unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r)))
+}
+
+func hasURLPrefix(runes []rune) bool {
+	s := strings.ToLower(string(runes[:min(len(runes), 8)]))
+	return strings.HasPrefix(s, "http://") || strings.HasPrefix(s, "https://")
+}
+
+func scanWhile(runes []rune, start int, accept func(rune) bool) int {
+	end := start
+	for end < len(runes) && accept(runes[end]) {
+		end++
+	}
+	return end
+}
+
+func scanURL(runes []rune, start int) int {
+	end := scanWhile(runes, start, func(r rune) bool {
+		return !unicode.IsSpace(r)
+	})
+
+	for end > start && strings.ContainsRune(".,;:!?)]}'\"", runes[end-1]) {
+		end--
+	}
+
+	return end
+}
+
+// scanMention accepts both "@name" and "@name@domain" forms. Trailing dots
+// and dashes are punctuation rather than part of the mention.
+func scanMention(runes []rune, start int) int {
+	end := scanWhile(runes, start, isHandleRune)
+
+	if end > start && end < len(runes) && runes[end] == '@' {
+		if domainEnd := scanWhile(runes, end+1, isHandleRune); domainEnd > end+1 {
+			end = domainEnd
+		}
+	}
+
+	for end > start && strings.ContainsRune(".-", runes[end-1]) {
+		end--
+	}
+
+	return end
+}
@@ -0,0 +1,36 @@
+package entities
+
+import (
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	body := "Hi @alice@example.com, see https://chirpy.dev/x?y=1. #GoLang #42 #café"
+
+	expected := []Entity{
+		{Kind: Mention, Text: "alice@example.com", Start: 3, End: 21},
+		{Kind: URL, Text: "https://chirpy.dev/x?y=1", Start: 27, End: 51},
+		{Kind: Hashtag, Text: "golang", Start: 53, End: 60},
+		{Kind: Hashtag, Text: "café", Start: 65, End: 70},
+	}
+
+	found := Parse(body)
+
+	if len(found) != len(expected) {
+		t.Fatalf("Expected %d entities, got %d: %v", len(expected), len(found), found)
+	}
+
+	for i, e := range expected {
+		if found[i] != e {
+			t.Errorf("Entity %d should be %v, got %v", i, e, found[i])
+		}
+	}
+}
+
+func TestParseIgnoresEmbeddedMarkers(t *testing.T) {
+	found := Parse("mail me at bob@example.com or visit page#section")
+
+	if len(found) != 0 {
+		t.Errorf("No entities expected, got %v", found)
+	}
+}
@@ -0,0 +1,94 @@
+package entitlements
+
+import (
+	"slices"
+	"time"
+
+	"github.com/firerockets/chirpy/internal/database"
+)
+
+type Entitlement string
+
+const (
+	// LongChirps raises the chirp length limit.
+	LongChirps Entitlement = "long_chirps"
+	// ExtraPins raises how many chirps can be pinned to a profile.
+	ExtraPins Entitlement = "extra_pins"
+	// Collections unlocks sorting bookmarks into collections.
+	Collections Entitlement = "collections"
+	// Analytics unlocks impressions, engagement and follower growth.
+	Analytics Entitlement = "analytics"
+)
+
+const (
+	PlanFree      = "free"
+	PlanChirpyRed = "chirpy_red"
+)
+
+const (
+	StatusActive  = "active"
+	StatusExpired = "expired"
+)
+
+const (
+	SourcePolka = "polka"
+	// SourceLegacy marks the subscriptions carried over from the old
+	// is_chirpy_red flag. Nothing renews them, so they don't expire until
+	// Polka sends an event that replaces them.
+	SourceLegacy = "legacy"
+)
+
+var plans = map[string][]Entitlement{
+	PlanFree:      {},
+	PlanChirpyRed: {LongChirps, ExtraPins, Collections, Analytics},
+}
+
+// Set is what a user is entitled to at a point in time.
+type Set struct {
+	Plan string
+	// ExpiresAt is when the current period ends, zero on the free plan and
+	// for legacy subscriptions.
+	ExpiresAt    time.Time
+	entitlements []Entitlement
+}
+
+func Free() Set {
+	return Set{Plan: PlanFree, entitlements: plans[PlanFree]}
+}
+
+// For returns what sub grants at now. Subscriptions stop granting anything
+// as soon as their period ends, even before the Expirer marks them expired,
+// except legacy ones which have no end.
+func For(sub database.Subscription, now time.Time) Set {
+	granted, ok := plans[sub.Plan]
+	if !ok || sub.Status != StatusActive {
+		return Free()
+	}
+
+	if sub.Source == SourceLegacy {
+		return Set{Plan: sub.Plan, entitlements: granted}
+	}
+
+	if !now.Before(sub.CurrentPeriodEnd) {
+		return Free()
+	}
+
+	return Set{
+		Plan:         sub.Plan,
+		ExpiresAt:    sub.CurrentPeriodEnd,
+		entitlements: granted,
+	}
+}
+
+func (s Set) Has(e Entitlement) bool {
+	return slices.Contains(s.entitlements, e)
+}
+
+// Names lists the entitlements as they appear in responses and tokens.
+func (s Set) Names() []string {
+	names := make([]string, 0, len(s.entitlements))
+	for _, e := range s.entitlements {
+		names = append(names, string(e))
+	}
+	return names
+}
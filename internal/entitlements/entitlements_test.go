@@ -0,0 +1,88 @@
+package entitlements
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/firerockets/chirpy/internal/database"
+)
+
+func TestFor(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	active := database.Subscription{
+		Plan:             PlanChirpyRed,
+		Status:           StatusActive,
+		CurrentPeriodEnd: now.Add(time.Hour),
+	}
+
+	got := For(active, now)
+
+	if got.Plan != PlanChirpyRed || !got.ExpiresAt.Equal(active.CurrentPeriodEnd) {
+		t.Errorf("Active subscription: got plan %s until %s", got.Plan, got.ExpiresAt)
+	}
+
+	for _, e := range []Entitlement{LongChirps, ExtraPins, Collections, Analytics} {
+		if !got.Has(e) {
+			t.Errorf("Chirpy Red should grant %s", e)
+		}
+	}
+
+	lapsed := active
+	lapsed.CurrentPeriodEnd = now
+
+	expired := active
+	expired.Status = StatusExpired
+
+	unknown := active
+	unknown.Plan = "platinum"
+
+	for name, sub := range map[string]database.Subscription{
+		"lapsed":  lapsed,
+		"expired": expired,
+		"unknown": unknown,
+	} {
+		got := For(sub, now)
+		if got.Plan != PlanFree || got.Has(LongChirps) || !got.ExpiresAt.IsZero() {
+			t.Errorf("%s subscription: got %+v, want the free plan", name, got)
+		}
+	}
+}
+
+func TestForLegacy(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	legacy := database.Subscription{
+		Plan:             PlanChirpyRed,
+		Status:           StatusActive,
+		Source:           SourceLegacy,
+		CurrentPeriodEnd: now.Add(-time.Hour),
+	}
+
+	got := For(legacy, now)
+
+	if got.Plan != PlanChirpyRed || !got.Has(Collections) || !got.ExpiresAt.IsZero() {
+		t.Errorf("Legacy subscription past its period: got %+v, want open-ended Chirpy Red", got)
+	}
+
+	legacy.Status = StatusExpired
+
+	if got := For(legacy, now); got.Plan != PlanFree {
+		t.Errorf("Expired legacy subscription: got plan %s, want the free plan", got.Plan)
+	}
+}
+
+func TestNames(t *testing.T) {
+	if names := Free().Names(); names == nil || len(names) != 0 {
+		t.Errorf("Free plan names = %#v, want an empty list", names)
+	}
+
+	names := For(database.Subscription{
+		Plan:             PlanChirpyRed,
+		Status:           StatusActive,
+		CurrentPeriodEnd: time.Now().Add(time.Hour),
+	}, time.Now()).Names()
+
+	if !slices.Contains(names, "analytics") {
+		t.Errorf("Chirpy Red names = %v, want analytics among them", names)
+	}
+}
@@ -0,0 +1,57 @@
+package entitlements
+
+import (
+	"context"
+	"log"
+	"time"
+)
+
+type ExpireStore interface {
+	ExpireSubscriptions(ctx context.Context, now time.Time) (int64, error)
+}
+
+// Expirer marks subscriptions whose period ended as expired, which frees
+// the user for a fresh subscription and keeps the history accurate. Legacy
+// subscriptions have no period and are left alone.
+type Expirer struct {
+	store    ExpireStore
+	interval time.Duration
+}
+
+func NewExpirer(store ExpireStore, interval time.Duration) *Expirer {
+	return &Expirer{
+		store:    store,
+		interval: interval,
+	}
+}
+
+// Run expires once right away and then on every tick until ctx is done.
+func (e *Expirer) Run(ctx context.Context) {
+	ticker := time.NewTicker(e.interval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := e.Expire(ctx); err != nil {
+			log.Printf("Error expiring subscriptions: %s\n", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (e *Expirer) Expire(ctx context.Context) (int64, error) {
+	expired, err := e.store.ExpireSubscriptions(ctx, time.Now())
+	if err != nil {
+		return 0, err
+	}
+
+	if expired > 0 {
+		log.Printf("Expired %d subscriptions\n", expired)
+	}
+
+	return expired, nil
+}
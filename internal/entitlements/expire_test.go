@@ -0,0 +1,36 @@
+package entitlements
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+type fakeExpireStore struct {
+	now time.Time
+}
+
+func (s *fakeExpireStore) ExpireSubscriptions(ctx context.Context, now time.Time) (int64, error) {
+	s.now = now
+	return 2, nil
+}
+
+func TestExpire(t *testing.T) {
+	store := &fakeExpireStore{}
+
+	before := time.Now()
+	expired, err := NewExpirer(store, time.Minute).Expire(context.Background())
+	after := time.Now()
+
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+
+	if expired != 2 {
+		t.Errorf("Expected 2 expired subscriptions, got %d", expired)
+	}
+
+	if store.now.Before(before) || store.now.After(after) {
+		t.Errorf("Expected subscriptions ending by now to expire, got %s", store.now)
+	}
+}
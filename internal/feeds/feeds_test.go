@@ -0,0 +1,131 @@
+package feeds
+
+import (
+	"encoding/xml"
+	"strings"
+	"testing"
+	"time"
+)
+
+func testFeed() Feed {
+	published := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	return Feed{
+		ID:      "https://chirpy.example/users/alice/feed.atom",
+		Title:   "Alice on Chirpy",
+		Link:    "https://chirpy.example/api/users/alice",
+		Self:    "https://chirpy.example/users/alice/feed.atom",
+		Updated: published,
+		Entries: []Entry{
+			{
+				ID:        "urn:uuid:6f1c2a4e-8a53-4d57-9d3c-0a4b1f2e3d4c",
+				Link:      "https://chirpy.example/api/chirps/6f1c2a4e-8a53-4d57-9d3c-0a4b1f2e3d4c",
+				Author:    "Alice",
+				Content:   "Fish & <chips>\nfor lunch",
+				Published: published,
+				Updated:   published,
+			},
+		},
+	}
+}
+
+func TestTitle(t *testing.T) {
+	cases := []struct {
+		content string
+		want    string
+	}{
+		{"hello", "hello"},
+		{"  first line\nsecond line", "first line"},
+		{strings.Repeat("a", maxTitleLength), strings.Repeat("a", maxTitleLength)},
+		{strings.Repeat("é", maxTitleLength+1), strings.Repeat("é", maxTitleLength-1) + "…"},
+		{"", ""},
+	}
+
+	for _, c := range cases {
+		if got := Title(c.content); got != c.want {
+			t.Errorf("Title(%q) = %q, want %q", c.content, got, c.want)
+		}
+	}
+}
+
+func TestAtom(t *testing.T) {
+	body, err := Atom(testFeed())
+	if err != nil {
+		t.Fatalf("Atom returned an error: %s", err)
+	}
+
+	var parsed atomFeed
+	if err := xml.Unmarshal(body, &parsed); err != nil {
+		t.Fatalf("Atom isn't valid XML: %s", err)
+	}
+
+	if len(parsed.Entries) != 1 {
+		t.Fatalf("got %d entries, want 1", len(parsed.Entries))
+	}
+
+	entry := parsed.Entries[0]
+	if entry.Content.Body != "Fish & <chips>\nfor lunch" {
+		t.Errorf("content = %q, want the chirp unchanged", entry.Content.Body)
+	}
+	if entry.Title != "Fish & <chips>" {
+		t.Errorf("title = %q, want the first line", entry.Title)
+	}
+	if entry.Updated != "2026-03-01T12:00:00Z" {
+		t.Errorf("updated = %q, want an RFC 3339 date", entry.Updated)
+	}
+	if !strings.Contains(string(body), `rel="self"`) {
+		t.Errorf("feed has no self link")
+	}
+}
+
+func TestRSS(t *testing.T) {
+	body, err := RSS(testFeed())
+	if err != nil {
+		t.Fatalf("RSS returned an error: %s", err)
+	}
+
+	var parsed struct {
+		Channel struct {
+			Items []struct {
+				GUID        string `xml:"guid"`
+				Description string `xml:"description"`
+				PubDate     string `xml:"pubDate"`
+			} `xml:"item"`
+		} `xml:"channel"`
+	}
+	if err := xml.Unmarshal(body, &parsed); err != nil {
+		t.Fatalf("RSS isn't valid XML: %s", err)
+	}
+
+	if len(parsed.Channel.Items) != 1 {
+		t.Fatalf("got %d items, want 1", len(parsed.Channel.Items))
+	}
+
+	item := parsed.Channel.Items[0]
+	if item.GUID != "urn:uuid:6f1c2a4e-8a53-4d57-9d3c-0a4b1f2e3d4c" {
+		t.Errorf("guid = %q", item.GUID)
+	}
+	if item.Description != "Fish & <chips>\nfor lunch" {
+		t.Errorf("description = %q, want the chirp unchanged", item.Description)
+	}
+	if item.PubDate != "Sun, 01 Mar 2026 12:00:00 +0000" {
+		t.Errorf("pubDate = %q, want an RFC 1123 date", item.PubDate)
+	}
+	if !strings.Contains(string(body), `isPermaLink="false"`) {
+		t.Errorf("guid isn't marked as not a permalink")
+	}
+}
+
+func TestETag(t *testing.T) {
+	a := ETag([]byte("one"))
+
+	if a != ETag([]byte("one")) {
+		t.Errorf("ETag isn't stable")
+	}
+	if a == ETag([]byte("two")) {
+		t.Errorf("different bodies share an ETag")
+	}
+	if !strings.HasPrefix(a, `"`) || !strings.HasSuffix(a, `"`) {
+		t.Errorf("ETag %s isn't quoted", a)
+	}
+}
@@ -0,0 +1,403 @@
+package filter
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// Channel is the PostgreSQL channel rule changes are announced on, so every
+// instance reloads its filter when an admin edits the rules on any of them.
+const Channel = "content_filter"
+
+type Policy string
+
+const (
+	Mask   Policy = "mask"
+	Reject Policy = "reject"
+	Flag   Policy = "flag"
+)
+
+const maskText = "****"
+
+type Rule struct {
+	Word   string
+	Policy Policy
+	// FromFile marks the rules loaded by LoadFile, which can only be changed
+	// by editing the file.
+	FromFile bool
+}
+
+// Match is a filtered word found in a body. Start and End are rune offsets
+// into the original body, End being exclusive.
+type Match struct {
+	Word   string
+	Policy Policy
+	Start  int
+	End    int
+}
+
+type Result struct {
+	Body    string
+	Matches []Match
+}
+
+func (r Result) Rejected() bool {
+	return r.has(Reject)
+}
+
+func (r Result) Flagged() bool {
+	return r.has(Flag)
+}
+
+func (r Result) has(policy Policy) bool {
+	for _, m := range r.Matches {
+		if m.Policy == policy {
+			return true
+		}
+	}
+	return false
+}
+
+// Filter matches chirp bodies against a set of rules. It's safe to use from
+// several goroutines and its rules can be replaced while in use.
+type Filter struct {
+	mu    sync.RWMutex
+	rules map[string]Rule
+	// stretched maps the squeezed form of every rule word to the rule words
+	// sharing it, longest first, for inputs with repeated letters.
+	stretched map[string][]string
+}
+
+func New(rules []Rule) *Filter {
+	f := &Filter{}
+	f.Replace(rules)
+	return f
+}
+
+func ParsePolicy(s string) (Policy, error) {
+	switch Policy(strings.ToLower(s)) {
+	case Mask:
+		return Mask, nil
+	case Reject:
+		return Reject, nil
+	case Flag:
+		return Flag, nil
+	}
+	return "", fmt.Errorf("unknown filter policy %q", s)
+}
+
+// Replace swaps all the rules at once. Later rules win over earlier ones for
+// the same word.
+func (f *Filter) Replace(rules []Rule) {
+	byWord := map[string]Rule{}
+
+	for _, r := range rules {
+		word := normalize(r.Word)
+		if word == "" {
+			continue
+		}
+		byWord[word] = Rule{Word: strings.ToLower(r.Word), Policy: r.Policy, FromFile: r.FromFile}
+	}
+
+	stretched := map[string][]string{}
+
+	for word := range byWord {
+		key := squeeze(word)
+		stretched[key] = append(stretched[key], word)
+	}
+
+	for _, words := range stretched {
+		sort.Slice(words, func(i, j int) bool {
+			if len(words[i]) != len(words[j]) {
+				return len(words[i]) > len(words[j])
+			}
+			return words[i] < words[j]
+		})
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.rules = byWord
+	f.stretched = stretched
+}
+
+func (f *Filter) Rules() []Rule {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	rules := make([]Rule, 0, len(f.rules))
+	for _, r := range f.rules {
+		rules = append(rules, r)
+	}
+
+	sort.Slice(rules, func(i, j int) bool {
+		return rules[i].Word < rules[j].Word
+	})
+
+	return rules
+}
+
+// Apply looks for filtered words in body and returns it with the words under
+// the mask policy masked. Words are compared after Unicode compatibility
+// folding, case folding, removing diacritics and punctuation and undoing
+// common character substitutions, so "K3rfuffl3!" and "k.e.r.f.u.f.f.l.e"
+// both match "kerfuffle".
+func (f *Filter) Apply(body string) Result {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	runes := []rune(body)
+	tokens := tokenize(runes)
+	matches := []Match{}
+
+	for _, t := range tokens {
+		if m, ok := f.match(t); ok {
+			matches = append(matches, m)
+		}
+	}
+
+	matches = append(matches, f.matchSpelledOut(tokens)...)
+
+	sort.Slice(matches, func(i, j int) bool {
+		return matches[i].Start < matches[j].Start
+	})
+
+	masked := []rune{}
+	last := 0
+
+	for _, m := range matches {
+		if m.Policy != Mask || m.Start < last {
+			continue
+		}
+		masked = append(masked, runes[last:m.Start]...)
+		masked = append(masked, []rune(maskText)...)
+		last = m.End
+	}
+
+	masked = append(masked, runes[last:]...)
+
+	return Result{
+		Body:    string(masked),
+		Matches: matches,
+	}
+}
+
+func (f *Filter) match(t token) (Match, bool) {
+	for _, v := range t.variants() {
+		if rule, ok := f.lookup(normalize(v.text)); ok {
+			return Match{Word: rule.Word, Policy: rule.Policy, Start: v.start, End: v.end}, true
+		}
+	}
+	return Match{}, false
+}
+
+// matchSpelledOut joins runs of single character tokens to catch words
+// spelled out with spaces, trying the longest run from each position first.
+func (f *Filter) matchSpelledOut(tokens []token) []Match {
+	matches := []Match{}
+
+	for i := 0; i < len(tokens); i++ {
+		j := i
+		for j < len(tokens) && tokens[j].end-tokens[j].start == 1 {
+			j++
+		}
+
+		for ; j-i >= 3; j-- {
+			text := ""
+			for _, t := range tokens[i:j] {
+				text += t.text
+			}
+
+			if m, ok := f.match(token{text: text, start: tokens[i].start, end: tokens[j-1].end}); ok {
+				m.Start, m.End = tokens[i].start, tokens[j-1].end
+				matches = append(matches, m)
+				i = j - 1
+				break
+			}
+		}
+	}
+
+	return matches
+}
+
+func (f *Filter) lookup(word string) (Rule, bool) {
+	if word == "" {
+		return Rule{}, false
+	}
+	if rule, ok := f.rules[word]; ok {
+		return rule, true
+	}
+	for _, ruleWord := range f.stretched[squeeze(word)] {
+		if stretches(word, ruleWord) {
+			return f.rules[ruleWord], true
+		}
+	}
+	return Rule{}, false
+}
+
+// LoadFile reads rules from a file with one "word [policy]" per line. Lines
+// starting with '#' are comments and the policy defaults to mask.
+func LoadFile(path string) ([]Rule, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	rules := []Rule{}
+	scanner := bufio.NewScanner(file)
+
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+
+		rule := Rule{Word: fields[0], Policy: Mask, FromFile: true}
+
+		if len(fields) > 1 {
+			rule.Policy, err = ParsePolicy(fields[1])
+			if err != nil {
+				return nil, fmt.Errorf("%s:%d: %w", path, lineNumber, err)
+			}
+		}
+
+		rules = append(rules, rule)
+	}
+
+	return rules, scanner.Err()
+}
+
+var substitutions = map[rune]rune{
+	'0': 'o',
+	'1': 'i',
+	'3': 'e',
+	'4': 'a',
+	'5': 's',
+	'7': 't',
+	'8': 'b',
+	'@': 'a',
+	'$': 's',
+	'!': 'i',
+	'|': 'l',
+	'+': 't',
+}
+
+type token struct {
+	text       string
+	start, end int
+}
+
+// variants returns the token without the punctuation around it, then with
+// only the leading punctuation and finally as written, so "kerfuffle!"
+// doesn't become "kerfufflei" but "$harbert" still matches.
+func (t token) variants() []token {
+	runes := []rune(t.text)
+	isWord := func(r rune) bool {
+		return unicode.IsLetter(r) || unicode.IsDigit(r)
+	}
+
+	start, end := 0, len(runes)
+	for start < end && !isWord(runes[start]) {
+		start++
+	}
+	for end > start && !isWord(runes[end-1]) {
+		end--
+	}
+
+	if start == 0 && end == len(runes) {
+		return []token{t}
+	}
+
+	return []token{
+		{text: string(runes[start:end]), start: t.start + start, end: t.start + end},
+		{text: string(runes[:end]), start: t.start, end: t.start + end},
+		t,
+	}
+}
+
+// tokenize splits on whitespace.
+func tokenize(runes []rune) []token {
+	tokens := []token{}
+
+	for i := 0; i < len(runes); {
+		if unicode.IsSpace(runes[i]) {
+			i++
+			continue
+		}
+		start := i
+		for i < len(runes) && !unicode.IsSpace(runes[i]) {
+			i++
+		}
+		tokens = append(tokens, token{text: string(runes[start:i]), start: start, end: i})
+	}
+
+	return tokens
+}
+
+func normalize(word string) string {
+	var b strings.Builder
+
+	for _, r := range norm.NFKD.String(word) {
+		if sub, ok := substitutions[r]; ok {
+			r = sub
+		}
+		if !unicode.IsLetter(r) {
+			continue
+		}
+		b.WriteRune(unicode.ToLower(r))
+	}
+
+	return b.String()
+}
+
+// squeeze collapses repeated letters. It's only used to find the rules an
+// input could be a stretched spelling of, which stretches then checks.
+func squeeze(word string) string {
+	var b strings.Builder
+	var last rune
+
+	for _, r := range word {
+		if r != last {
+			b.WriteRune(r)
+		}
+		last = r
+	}
+
+	return b.String()
+}
+
+// stretches tells whether word is ruleWord with some of its letters
+// repeated, so "kerrrfuffle" matches "kerfuffle" but "as" doesn't match
+// "ass".
+func stretches(word, ruleWord string) bool {
+	w, r := []rune(word), []rune(ruleWord)
+	i, j := 0, 0
+
+	for i < len(w) && j < len(r) {
+		if w[i] != r[j] {
+			return false
+		}
+
+		wordRun, ruleRun := i, j
+		for i < len(w) && w[i] == w[wordRun] {
+			i++
+		}
+		for j < len(r) && r[j] == r[ruleRun] {
+			j++
+		}
+
+		if i-wordRun < j-ruleRun {
+			return false
+		}
+	}
+
+	return i == len(w) && j == len(r)
+}
@@ -0,0 +1,99 @@
+package filter
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestApplyMasksObfuscatedWords(t *testing.T) {
+	f := New([]Rule{{Word: "kerfuffle", Policy: Mask}})
+
+	cases := map[string]string{
+		"what a kerfuffle!":          "what a ****!",
+		"what a KERFUFFLE":           "what a ****",
+		"what a k3rfuffl3":           "what a ****",
+		"what a k.e.r.f.u.f.f.l.e":   "what a ****",
+		"what a kérfuffle":           "what a ****",
+		"what a k e r f u f f l e":   "what a ****",
+		"what a kerrrfuffle, really": "what a ****, really",
+		"nothing to see here":        "nothing to see here",
+	}
+
+	for body, expected := range cases {
+		result := f.Apply(body)
+		if result.Body != expected {
+			t.Errorf("Apply(%q) should be %q, got %q", body, expected, result.Body)
+		}
+	}
+}
+
+func TestApplyOnlyStretchesInput(t *testing.T) {
+	f := New([]Rule{
+		{Word: "ass", Policy: Mask},
+		{Word: "butt", Policy: Reject},
+	})
+
+	cases := map[string]string{
+		"as good as it gets": "as good as it gets",
+		"what an ass":        "what an ****",
+		"what an assss":      "what an ****",
+	}
+
+	for body, expected := range cases {
+		result := f.Apply(body)
+		if result.Body != expected {
+			t.Errorf("Apply(%q) should be %q, got %q", body, expected, result.Body)
+		}
+	}
+
+	if f.Apply("fine, but not great").Rejected() {
+		t.Error("\"but\" should not match the rule for \"butt\"")
+	}
+
+	if !f.Apply("buttttt").Rejected() {
+		t.Error("\"buttttt\" should match the rule for \"butt\"")
+	}
+}
+
+func TestApplyPolicies(t *testing.T) {
+	f := New([]Rule{
+		{Word: "fornax", Policy: Reject},
+		{Word: "sharbert", Policy: Flag},
+	})
+
+	result := f.Apply("sh@rbert is fine")
+
+	if result.Rejected() || !result.Flagged() {
+		t.Errorf("Body should only be flagged, got %v", result.Matches)
+	}
+
+	if result.Body != "sh@rbert is fine" {
+		t.Errorf("Flagged words should not be masked, got %q", result.Body)
+	}
+
+	result = f.Apply("Fornax.")
+
+	if !result.Rejected() {
+		t.Errorf("Body should be rejected, got %v", result.Matches)
+	}
+}
+
+func TestLoadFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "words.txt")
+	content := "# comment\nkerfuffle\nfornax reject\n"
+
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("Error writing words file: %s", err)
+	}
+
+	rules, err := LoadFile(path)
+
+	if err != nil {
+		t.Fatalf("Error loading rules: %s", err)
+	}
+
+	if len(rules) != 2 || rules[0].Policy != Mask || rules[1].Policy != Reject || !rules[0].FromFile {
+		t.Errorf("Unexpected rules loaded: %v", rules)
+	}
+}
@@ -0,0 +1,81 @@
+package handles
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"strings"
+)
+
+const (
+	MinLength = 3
+	MaxLength = 15
+)
+
+var (
+	ErrLength     = errors.New("handles must be between 3 and 15 characters long")
+	ErrCharacters = errors.New("handles can only contain letters, digits and underscores")
+	ErrReserved   = errors.New("handle is reserved")
+)
+
+// reserved holds handles that could be mistaken for the service itself or
+// clash with routes, compared in lowercase.
+var reserved = map[string]bool{
+	"about":         true,
+	"abuse":         true,
+	"account":       true,
+	"admin":         true,
+	"administrator": true,
+	"api":           true,
+	"app":           true,
+	"chirps":        true,
+	"chirpy":        true,
+	"chirpyred":     true,
+	"help":          true,
+	"login":         true,
+	"logout":        true,
+	"me":            true,
+	"moderator":     true,
+	"null":          true,
+	"root":          true,
+	"security":      true,
+	"settings":      true,
+	"signup":        true,
+	"staff":         true,
+	"support":       true,
+	"system":        true,
+	"undefined":     true,
+	"users":         true,
+}
+
+// Validate checks handle, given without the leading '@'. Handles keep the
+// case they were chosen with but are unique regardless of case.
+func Validate(handle string) error {
+	if len(handle) < MinLength || len(handle) > MaxLength {
+		return ErrLength
+	}
+
+	for _, r := range handle {
+		if !(r == '_' || (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9')) {
+			return ErrCharacters
+		}
+	}
+
+	if reserved[Normalize(handle)] {
+		return ErrReserved
+	}
+
+	return nil
+}
+
+// Normalize returns the form handles are compared in.
+func Normalize(handle string) string {
+	return strings.ToLower(strings.TrimPrefix(handle, "@"))
+}
+
+// Generate returns a random handle for users who haven't picked one yet.
+func Generate() string {
+	b := make([]byte, 5)
+	rand.Read(b)
+	return "user_" + hex.EncodeToString(b)
+}
@@ -0,0 +1,47 @@
+package handles
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestValidate(t *testing.T) {
+	cases := []struct {
+		handle string
+		err    error
+	}{
+		{"alice", nil},
+		{"Bob_42", nil},
+		{"ab", ErrLength},
+		{"a_very_long_handle", ErrLength},
+		{"bob.smith", ErrCharacters},
+		{"josé", ErrCharacters},
+		{"Admin", ErrReserved},
+		{"CHIRPY", ErrReserved},
+	}
+
+	for _, c := range cases {
+		err := Validate(c.handle)
+		if !errors.Is(err, c.err) {
+			t.Errorf("Validate(%q) should return %v, got %v", c.handle, c.err, err)
+		}
+	}
+}
+
+func TestNormalize(t *testing.T) {
+	if got := Normalize("@Alice_B"); got != "alice_b" {
+		t.Errorf("Expected alice_b, got %s", got)
+	}
+}
+
+func TestGenerate(t *testing.T) {
+	handle := Generate()
+
+	if err := Validate(handle); err != nil {
+		t.Errorf("Generated handle %q should be valid, got %v", handle, err)
+	}
+
+	if Generate() == handle {
+		t.Error("Generated handles should differ")
+	}
+}
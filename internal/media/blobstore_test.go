@@ -0,0 +1,108 @@
+package media
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func testBlobStore(t *testing.T, store BlobStore) {
+	ctx := context.Background()
+
+	err := store.Put(ctx, "abc/original.png", "image/png", []byte("data"))
+	if err != nil {
+		t.Fatalf("Error putting blob: %s", err)
+	}
+
+	body, err := store.Get(ctx, "abc/original.png")
+	if err != nil {
+		t.Fatalf("Error getting blob: %s", err)
+	}
+	defer body.Close()
+
+	data, _ := io.ReadAll(body)
+	if string(data) != "data" {
+		t.Errorf("Blob should contain data, got %q", data)
+	}
+
+	err = store.Delete(ctx, "abc/original.png")
+	if err != nil {
+		t.Fatalf("Error deleting blob: %s", err)
+	}
+
+	if _, err = store.Get(ctx, "abc/original.png"); err == nil {
+		t.Error("Deleted blob should not be found")
+	}
+
+	if err = store.Put(ctx, "../escape.png", "image/png", nil); err != ErrInvalidKey {
+		t.Errorf("Expected ErrInvalidKey, got %v", err)
+	}
+}
+
+func TestLocalStore(t *testing.T) {
+	store := NewLocalStore(t.TempDir(), "/assets/media/")
+
+	testBlobStore(t, store)
+
+	if url := store.URL("abc/original.png"); url != "/assets/media/abc/original.png" {
+		t.Errorf("Unexpected URL %s", url)
+	}
+}
+
+// fakeS3 is a stand-in for an S3 compatible service, keeping objects in
+// memory and rejecting unsigned requests.
+func fakeS3(t *testing.T) *httptest.Server {
+	var mu sync.Mutex
+	objects := map[string][]byte{}
+
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		auth := req.Header.Get("Authorization")
+		if !strings.HasPrefix(auth, "AWS4-HMAC-SHA256 Credential=access/") || !strings.Contains(auth, "Signature=") {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+
+		mu.Lock()
+		defer mu.Unlock()
+
+		switch req.Method {
+		case http.MethodPut:
+			data, _ := io.ReadAll(req.Body)
+			objects[req.URL.Path] = data
+		case http.MethodGet:
+			data, ok := objects[req.URL.Path]
+			if !ok {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			w.Write(data)
+		case http.MethodDelete:
+			delete(objects, req.URL.Path)
+			w.WriteHeader(http.StatusNoContent)
+		}
+	}))
+}
+
+func TestS3Store(t *testing.T) {
+	server := fakeS3(t)
+	defer server.Close()
+
+	store := &S3Store{
+		Endpoint:  server.URL,
+		Bucket:    "chirpy",
+		Region:    "us-east-1",
+		AccessKey: "access",
+		SecretKey: "secret",
+		Client:    server.Client(),
+	}
+
+	testBlobStore(t, store)
+
+	if url := store.URL("abc/original.png"); url != server.URL+"/chirpy/abc/original.png" {
+		t.Errorf("Unexpected URL %s", url)
+	}
+}
@@ -0,0 +1,345 @@
+package media
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"image"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"net/http"
+
+	"golang.org/x/image/draw"
+)
+
+const (
+	ThumbnailSize = 320
+	// Images over this many pixels, counting every frame of a GIF, are
+	// refused before being decoded.
+	MaxPixels = 40_000_000
+	// GIFs with more frames than this are refused before being decoded.
+	MaxFrames = 500
+)
+
+var (
+	ErrUnsupportedType = errors.New("unsupported media type")
+	ErrTooLarge        = errors.New("image is too large")
+)
+
+type Image struct {
+	ContentType          string
+	Extension            string
+	Width                int
+	Height               int
+	Data                 []byte
+	ThumbnailContentType string
+	ThumbnailExtension   string
+	Thumbnail            []byte
+}
+
+// ProcessImage checks the real type of data from its first bytes, whatever
+// the client said it was, and re-encodes it. Re-encoding drops EXIF and any
+// other metadata, so JPEGs are rotated upright first according to their EXIF
+// orientation.
+func ProcessImage(data []byte) (Image, error) {
+	contentType := http.DetectContentType(data)
+
+	switch contentType {
+	case "image/jpeg", "image/png", "image/gif":
+	default:
+		return Image{}, ErrUnsupportedType
+	}
+
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return Image{}, err
+	}
+
+	if config.Width*config.Height > MaxPixels {
+		return Image{}, ErrTooLarge
+	}
+
+	var img image.Image
+	var encoded bytes.Buffer
+
+	switch contentType {
+	case "image/jpeg":
+		img, err = jpeg.Decode(bytes.NewReader(data))
+		if err != nil {
+			return Image{}, err
+		}
+		img = orient(img, jpegOrientation(data))
+		err = jpeg.Encode(&encoded, img, &jpeg.Options{Quality: 90})
+	case "image/png":
+		img, err = png.Decode(bytes.NewReader(data))
+		if err != nil {
+			return Image{}, err
+		}
+		err = png.Encode(&encoded, img)
+	case "image/gif":
+		frames, pixels := gifFrames(data)
+		if frames > MaxFrames || pixels > MaxPixels {
+			return Image{}, ErrTooLarge
+		}
+
+		var animation *gif.GIF
+		animation, err = gif.DecodeAll(bytes.NewReader(data))
+		if err != nil {
+			return Image{}, err
+		}
+		img = animation.Image[0]
+		err = gif.EncodeAll(&encoded, animation)
+	}
+
+	if err != nil {
+		return Image{}, err
+	}
+
+	processed := Image{
+		ContentType: contentType,
+		Extension:   extensions[contentType],
+		Width:       img.Bounds().Dx(),
+		Height:      img.Bounds().Dy(),
+		Data:        encoded.Bytes(),
+	}
+
+	var thumbnail bytes.Buffer
+	small := resize(img, ThumbnailSize)
+
+	if contentType == "image/jpeg" {
+		processed.ThumbnailContentType = "image/jpeg"
+		err = jpeg.Encode(&thumbnail, small, &jpeg.Options{Quality: 80})
+	} else {
+		processed.ThumbnailContentType = "image/png"
+		err = png.Encode(&thumbnail, small)
+	}
+
+	if err != nil {
+		return Image{}, err
+	}
+
+	processed.ThumbnailExtension = extensions[processed.ThumbnailContentType]
+	processed.Thumbnail = thumbnail.Bytes()
+
+	return processed, nil
+}
+
+var extensions = map[string]string{
+	"image/jpeg": ".jpg",
+	"image/png":  ".png",
+	"image/gif":  ".gif",
+}
+
+// gifFrames walks the blocks of a GIF without decoding them, counting its
+// frames and the pixels they add up to. DecodeConfig only reads the first
+// frame, so an animation can be much bigger than it says. Malformed data is
+// counted as far as it goes and left for the decoder to reject.
+func gifFrames(data []byte) (frames, pixels int) {
+	if len(data) < 13 {
+		return 0, 0
+	}
+
+	i := 13
+	if data[10]&0x80 != 0 {
+		i += 3 << (data[10]&0x07 + 1)
+	}
+
+	for i < len(data) {
+		switch data[i] {
+		case 0x21:
+			i = skipSubBlocks(data, i+2)
+		case 0x2C:
+			if i+10 > len(data) {
+				return frames, pixels
+			}
+
+			width := int(binary.LittleEndian.Uint16(data[i+5:]))
+			height := int(binary.LittleEndian.Uint16(data[i+7:]))
+			frames++
+			pixels += width * height
+
+			flags := data[i+9]
+			i += 10
+			if flags&0x80 != 0 {
+				i += 3 << (flags&0x07 + 1)
+			}
+			// The LZW minimum code size comes before the image data.
+			i = skipSubBlocks(data, i+1)
+		default:
+			return frames, pixels
+		}
+	}
+
+	return frames, pixels
+}
+
+// skipSubBlocks returns the index right after the data sub-blocks starting
+// at i, which end with an empty one.
+func skipSubBlocks(data []byte, i int) int {
+	for i < len(data) {
+		size := int(data[i])
+		i++
+		if size == 0 {
+			return i
+		}
+		i += size
+	}
+
+	return i
+}
+
+// resize scales img down to fit in a size by size square, keeping its
+// aspect ratio. Smaller images are left alone.
+func resize(img image.Image, size int) image.Image {
+	width, height := img.Bounds().Dx(), img.Bounds().Dy()
+
+	if width <= size && height <= size {
+		return img
+	}
+
+	if width >= height {
+		height = max(1, height*size/width)
+		width = size
+	} else {
+		width = max(1, width*size/height)
+		height = size
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	draw.ApproxBiLinear.Scale(dst, dst.Bounds(), img, img.Bounds(), draw.Over, nil)
+
+	return dst
+}
+
+// orient applies an EXIF orientation (1 to 8) to img. It moves the pixels of
+// an *image.RGBA or *image.NRGBA as they are and converts anything else,
+// such as the YCbCr images the JPEG decoder returns, to RGBA first.
+func orient(img image.Image, orientation int) image.Image {
+	b := img.Bounds()
+	if orientation < 2 || orientation > 8 || b.Empty() {
+		return img
+	}
+
+	w, h := b.Dx(), b.Dy()
+
+	dstRect := image.Rect(0, 0, w, h)
+	if orientation >= 5 {
+		dstRect = image.Rect(0, 0, h, w)
+	}
+
+	switch src := img.(type) {
+	case *image.RGBA:
+		dst := image.NewRGBA(dstRect)
+		orientPix(dst.Pix, dst.Stride, src.Pix[src.PixOffset(b.Min.X, b.Min.Y):], src.Stride, w, h, orientation)
+		return dst
+	case *image.NRGBA:
+		dst := image.NewNRGBA(dstRect)
+		orientPix(dst.Pix, dst.Stride, src.Pix[src.PixOffset(b.Min.X, b.Min.Y):], src.Stride, w, h, orientation)
+		return dst
+	}
+
+	rgba := image.NewRGBA(image.Rect(0, 0, w, h))
+	draw.Draw(rgba, rgba.Bounds(), img, b.Min, draw.Src)
+
+	return orient(rgba, orientation)
+}
+
+// orientPix copies the 4 byte pixels of a w by h image from src to dst,
+// moving each one where the orientation puts it.
+func orientPix(dst []byte, dstStride int, src []byte, srcStride, w, h, orientation int) {
+	for y := 0; y < h; y++ {
+		row := src[y*srcStride:]
+
+		for x := 0; x < w; x++ {
+			var dx, dy int
+			switch orientation {
+			case 2:
+				dx, dy = w-1-x, y
+			case 3:
+				dx, dy = w-1-x, h-1-y
+			case 4:
+				dx, dy = x, h-1-y
+			case 5:
+				dx, dy = y, x
+			case 6:
+				dx, dy = h-1-y, x
+			case 7:
+				dx, dy = h-1-y, w-1-x
+			case 8:
+				dx, dy = y, w-1-x
+			}
+
+			i := dy*dstStride + dx*4
+			copy(dst[i:i+4], row[x*4:x*4+4])
+		}
+	}
+}
+
+// jpegOrientation returns the EXIF orientation of a JPEG, or 1 when it has
+// none.
+func jpegOrientation(data []byte) int {
+	if len(data) < 4 || data[0] != 0xFF || data[1] != 0xD8 {
+		return 1
+	}
+
+	for i := 2; i+4 <= len(data); {
+		if data[i] != 0xFF {
+			return 1
+		}
+
+		marker := data[i+1]
+		if marker == 0xDA || marker == 0xD9 {
+			return 1
+		}
+
+		size := int(binary.BigEndian.Uint16(data[i+2:]))
+		if size < 2 || i+2+size > len(data) {
+			return 1
+		}
+
+		segment := data[i+4 : i+2+size]
+		if marker == 0xE1 && bytes.HasPrefix(segment, []byte("Exif\x00\x00")) {
+			return exifOrientation(segment[6:])
+		}
+
+		i += 2 + size
+	}
+
+	return 1
+}
+
+func exifOrientation(tiff []byte) int {
+	if len(tiff) < 8 {
+		return 1
+	}
+
+	var order binary.ByteOrder
+	switch string(tiff[:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return 1
+	}
+
+	offset := int(order.Uint32(tiff[4:]))
+	if offset < 8 || offset+2 > len(tiff) {
+		return 1
+	}
+
+	count := int(order.Uint16(tiff[offset:]))
+
+	for n := 0; n < count; n++ {
+		entry := offset + 2 + n*12
+		if entry+12 > len(tiff) {
+			return 1
+		}
+		if order.Uint16(tiff[entry:]) == 0x0112 {
+			return int(order.Uint16(tiff[entry+8:]))
+		}
+	}
+
+	return 1
+}
@@ -0,0 +1,221 @@
+package media
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/color"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"testing"
+
+	"golang.org/x/image/draw"
+)
+
+func encodePNG(t *testing.T, width, height int) []byte {
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	img.Set(0, 0, color.RGBA{R: 255, A: 255})
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatalf("Error encoding png: %s", err)
+	}
+	return buf.Bytes()
+}
+
+// withOrientation inserts an EXIF segment with the given orientation right
+// after the SOI marker of a JPEG.
+func withOrientation(data []byte, orientation uint16) []byte {
+	tiff := []byte("MM\x00\x2a\x00\x00\x00\x08\x00\x01")
+	entry := make([]byte, 12)
+	binary.BigEndian.PutUint16(entry[0:], 0x0112)
+	binary.BigEndian.PutUint16(entry[2:], 3)
+	binary.BigEndian.PutUint32(entry[4:], 1)
+	binary.BigEndian.PutUint16(entry[8:], orientation)
+	tiff = append(tiff, entry...)
+	tiff = append(tiff, 0, 0, 0, 0)
+
+	segment := append([]byte("Exif\x00\x00"), tiff...)
+	header := []byte{0xFF, 0xE1, 0, 0}
+	binary.BigEndian.PutUint16(header[2:], uint16(len(segment)+2))
+
+	out := append([]byte{}, data[:2]...)
+	out = append(out, header...)
+	out = append(out, segment...)
+	return append(out, data[2:]...)
+}
+
+func TestProcessImagePNG(t *testing.T) {
+	processed, err := ProcessImage(encodePNG(t, 800, 400))
+
+	if err != nil {
+		t.Fatalf("Error processing image: %s", err)
+	}
+
+	if processed.ContentType != "image/png" || processed.Width != 800 || processed.Height != 400 {
+		t.Errorf("Unexpected image: %s %dx%d", processed.ContentType, processed.Width, processed.Height)
+	}
+
+	thumbnail, err := png.Decode(bytes.NewReader(processed.Thumbnail))
+
+	if err != nil {
+		t.Fatalf("Error decoding thumbnail: %s", err)
+	}
+
+	if thumbnail.Bounds().Dx() != ThumbnailSize || thumbnail.Bounds().Dy() != ThumbnailSize/2 {
+		t.Errorf("Unexpected thumbnail size: %v", thumbnail.Bounds())
+	}
+}
+
+func TestProcessImageStripsEXIF(t *testing.T) {
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, image.NewRGBA(image.Rect(0, 0, 40, 20)), nil); err != nil {
+		t.Fatalf("Error encoding jpeg: %s", err)
+	}
+
+	processed, err := ProcessImage(withOrientation(buf.Bytes(), 6))
+
+	if err != nil {
+		t.Fatalf("Error processing image: %s", err)
+	}
+
+	if bytes.Contains(processed.Data, []byte("Exif")) {
+		t.Error("EXIF data should be stripped")
+	}
+
+	if processed.Width != 20 || processed.Height != 40 {
+		t.Errorf("Image should be rotated upright, got %dx%d", processed.Width, processed.Height)
+	}
+}
+
+func TestOrient(t *testing.T) {
+	red := color.NRGBA{R: 255, A: 255}
+	green := color.NRGBA{G: 255, A: 255}
+
+	// A 3x2 image inside a bigger one, to check the offsets, with red at its
+	// top left and green next to it.
+	base := image.NewNRGBA(image.Rect(0, 0, 5, 4))
+	base.Set(1, 1, red)
+	base.Set(2, 1, green)
+	src := base.SubImage(image.Rect(1, 1, 4, 3)).(*image.NRGBA)
+
+	rgba := image.NewRGBA(src.Bounds())
+	draw.Draw(rgba, rgba.Bounds(), src, src.Bounds().Min, draw.Src)
+
+	ycbcr := image.NewYCbCr(src.Bounds(), image.YCbCrSubsampleRatio444)
+	for y := src.Bounds().Min.Y; y < src.Bounds().Max.Y; y++ {
+		for x := src.Bounds().Min.X; x < src.Bounds().Max.X; x++ {
+			r, g, b, _ := src.At(x, y).RGBA()
+			yy, cb, cr := color.RGBToYCbCr(uint8(r>>8), uint8(g>>8), uint8(b>>8))
+			ycbcr.Y[ycbcr.YOffset(x, y)] = yy
+			ycbcr.Cb[ycbcr.COffset(x, y)] = cb
+			ycbcr.Cr[ycbcr.COffset(x, y)] = cr
+		}
+	}
+
+	cases := map[int]struct {
+		size       image.Point
+		red, green image.Point
+	}{
+		2: {image.Pt(3, 2), image.Pt(2, 0), image.Pt(1, 0)},
+		3: {image.Pt(3, 2), image.Pt(2, 1), image.Pt(1, 1)},
+		4: {image.Pt(3, 2), image.Pt(0, 1), image.Pt(1, 1)},
+		5: {image.Pt(2, 3), image.Pt(0, 0), image.Pt(0, 1)},
+		6: {image.Pt(2, 3), image.Pt(1, 0), image.Pt(1, 1)},
+		7: {image.Pt(2, 3), image.Pt(1, 2), image.Pt(1, 1)},
+		8: {image.Pt(2, 3), image.Pt(0, 2), image.Pt(0, 1)},
+	}
+
+	isRed := func(c color.Color) bool {
+		r, g, _, _ := c.RGBA()
+		return r > 0xc000 && g < 0x4000
+	}
+	isGreen := func(c color.Color) bool {
+		r, g, _, _ := c.RGBA()
+		return g > 0xc000 && r < 0x4000
+	}
+
+	for orientation, c := range cases {
+		for name, img := range map[string]image.Image{"nrgba": src, "rgba": rgba, "ycbcr": ycbcr} {
+			got := orient(img, orientation)
+
+			if got.Bounds() != (image.Rectangle{Max: c.size}) {
+				t.Errorf("Orientation %d of %s: got bounds %v, want %v", orientation, name, got.Bounds(), c.size)
+				continue
+			}
+
+			if !isRed(got.At(c.red.X, c.red.Y)) || !isGreen(got.At(c.green.X, c.green.Y)) {
+				t.Errorf("Orientation %d of %s: red and green should be at %v and %v", orientation, name, c.red, c.green)
+			}
+		}
+	}
+
+	if got := orient(src, 1); got != image.Image(src) {
+		t.Error("Orientation 1 should leave the image alone")
+	}
+}
+
+func TestProcessImageChecksMagicBytes(t *testing.T) {
+	_, err := ProcessImage([]byte("<html><body>not an image</body></html>"))
+
+	if err != ErrUnsupportedType {
+		t.Errorf("Expected ErrUnsupportedType, got %v", err)
+	}
+}
+
+func encodeGIF(t *testing.T, frames int) []byte {
+	animation := &gif.GIF{}
+	palette := color.Palette{color.Black, color.White}
+
+	for i := 0; i < frames; i++ {
+		animation.Image = append(animation.Image, image.NewPaletted(image.Rect(0, 0, 2, 2), palette))
+		animation.Delay = append(animation.Delay, 10)
+	}
+
+	var buf bytes.Buffer
+	if err := gif.EncodeAll(&buf, animation); err != nil {
+		t.Fatalf("Error encoding gif: %s", err)
+	}
+	return buf.Bytes()
+}
+
+// bigGIF builds a GIF whose frames claim to fill a width by height screen,
+// without the image data to back them up.
+func bigGIF(width, height uint16, frames int) []byte {
+	data := []byte("GIF89a")
+	data = binary.LittleEndian.AppendUint16(data, width)
+	data = binary.LittleEndian.AppendUint16(data, height)
+	data = append(data, 0x80, 0, 0, 0, 0, 0, 255, 255, 255)
+
+	for i := 0; i < frames; i++ {
+		data = append(data, 0x2C, 0, 0, 0, 0)
+		data = binary.LittleEndian.AppendUint16(data, width)
+		data = binary.LittleEndian.AppendUint16(data, height)
+		data = append(data, 0, 2, 2, 0x4C, 0x01, 0)
+	}
+
+	return append(data, 0x3B)
+}
+
+func TestProcessImageGIF(t *testing.T) {
+	processed, err := ProcessImage(encodeGIF(t, 3))
+	if err != nil {
+		t.Fatalf("Error processing gif: %s", err)
+	}
+
+	if processed.ContentType != "image/gif" || processed.Width != 2 || processed.Height != 2 {
+		t.Errorf("Unexpected result: %s %dx%d", processed.ContentType, processed.Width, processed.Height)
+	}
+}
+
+func TestProcessImageLimitsGIFs(t *testing.T) {
+	if _, err := ProcessImage(encodeGIF(t, MaxFrames+1)); err != ErrTooLarge {
+		t.Errorf("Expected ErrTooLarge for too many frames, got %v", err)
+	}
+
+	// Each frame fits under MaxPixels, but not both of them together.
+	if _, err := ProcessImage(bigGIF(8000, 5000, 2)); err != ErrTooLarge {
+		t.Errorf("Expected ErrTooLarge for too many pixels, got %v", err)
+	}
+}
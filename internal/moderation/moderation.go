@@ -0,0 +1,61 @@
+package moderation
+
+import (
+	"errors"
+	"fmt"
+)
+
+type Category string
+
+const (
+	Spam           Category = "spam"
+	Harassment     Category = "harassment"
+	Hate           Category = "hate"
+	Violence       Category = "violence"
+	SelfHarm       Category = "self_harm"
+	Sexual         Category = "sexual"
+	Misinformation Category = "misinformation"
+	Other          Category = "other"
+)
+
+var Categories = []Category{Spam, Harassment, Hate, Violence, SelfHarm, Sexual, Misinformation, Other}
+
+func ParseCategory(s string) (Category, error) {
+	for _, c := range Categories {
+		if string(c) == s {
+			return c, nil
+		}
+	}
+	return "", fmt.Errorf("unknown report category %q", s)
+}
+
+type Action string
+
+const (
+	Dismiss Action = "dismiss"
+	Remove  Action = "remove"
+	Warn    Action = "warn"
+	Suspend Action = "suspend"
+)
+
+const (
+	StatusOpen     = "open"
+	StatusResolved = "resolved"
+)
+
+var ErrNoChirp = errors.New("only reported chirps can be removed")
+
+// ParseAction checks that s is an action a moderator can take on a case,
+// which only has a chirp to remove when a chirp was reported.
+func ParseAction(s string, hasChirp bool) (Action, error) {
+	switch Action(s) {
+	case Dismiss, Warn, Suspend:
+		return Action(s), nil
+	case Remove:
+		if !hasChirp {
+			return "", ErrNoChirp
+		}
+		return Remove, nil
+	}
+	return "", fmt.Errorf("unknown moderation action %q", s)
+}
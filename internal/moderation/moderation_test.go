@@ -0,0 +1,34 @@
+package moderation
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestParseCategory(t *testing.T) {
+	category, err := ParseCategory("self_harm")
+
+	if err != nil || category != SelfHarm {
+		t.Errorf("Expected self_harm, got %q (%v)", category, err)
+	}
+
+	if _, err := ParseCategory("Spam"); err == nil {
+		t.Error("Categories should be matched exactly")
+	}
+}
+
+func TestParseAction(t *testing.T) {
+	for _, s := range []string{"dismiss", "warn", "suspend", "remove"} {
+		if _, err := ParseAction(s, true); err != nil {
+			t.Errorf("%s should be allowed on chirp cases, got %v", s, err)
+		}
+	}
+
+	if _, err := ParseAction("remove", false); !errors.Is(err, ErrNoChirp) {
+		t.Errorf("Removing should need a chirp, got %v", err)
+	}
+
+	if _, err := ParseAction("ban", true); err == nil {
+		t.Error("Unknown actions should be refused")
+	}
+}
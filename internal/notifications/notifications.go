@@ -0,0 +1,9 @@
+package notifications
+
+type Kind string
+
+// Mentions are the only thing that notifies for now. Other kinds slot into
+// the same grouping once there's something like likes to notify about.
+const (
+	Mention Kind = "mention"
+)
@@ -0,0 +1,206 @@
+package pages
+
+import (
+	"html/template"
+	"io"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// maxDescriptionLength is about what link previews show before cutting
+// descriptions off themselves.
+const maxDescriptionLength = 200
+
+// Meta fills the OpenGraph and Twitter card tags of a page. URLs must be
+// absolute, since unfurlers don't resolve them against the page.
+type Meta struct {
+	Title       string
+	Description string
+	URL         string
+	Image       string
+	ImageAlt    string
+	// Type is the og:type, "article" for chirps and "profile" for users.
+	Type string
+	// LargeImage is set when Image is content worth showing big rather
+	// than an avatar.
+	LargeImage bool
+	// NoIndex keeps search engines from listing the page.
+	NoIndex bool
+}
+
+// Card is the Twitter card type.
+func (m Meta) Card() string {
+	if m.Image != "" && m.LargeImage {
+		return "summary_large_image"
+	}
+	return "summary"
+}
+
+type Author struct {
+	Name      string
+	Handle    string
+	URL       string
+	AvatarURL string
+}
+
+type Image struct {
+	URL     string
+	AltText string
+}
+
+type Chirp struct {
+	URL         string
+	Author      Author
+	Body        string
+	PublishedAt time.Time
+	Images      []Image
+}
+
+type User struct {
+	Author
+	Bio    string
+	Chirps []Chirp
+}
+
+// Description shortens text to fit link previews, on a word boundary when
+// there is one.
+func Description(text string) string {
+	text = strings.Join(strings.Fields(text), " ")
+
+	if utf8.RuneCountInString(text) <= maxDescriptionLength {
+		return text
+	}
+
+	cut := string([]rune(text)[:maxDescriptionLength-1])
+	if i := strings.LastIndex(cut, " "); i > maxDescriptionLength/2 {
+		cut = cut[:i]
+	}
+
+	return strings.TrimSpace(cut) + "…"
+}
+
+var templates = template.Must(template.New("layout").Funcs(template.FuncMap{
+	"date": func(t time.Time) string { return t.UTC().Format("Jan 2, 2006") },
+	"iso":  func(t time.Time) string { return t.UTC().Format(time.RFC3339) },
+}).Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>{{.Meta.Title}}</title>
+{{- if .Meta.NoIndex}}
+<meta name="robots" content="noindex">
+{{- else}}
+<meta name="description" content="{{.Meta.Description}}">
+<link rel="canonical" href="{{.Meta.URL}}">
+<meta property="og:site_name" content="Chirpy">
+<meta property="og:type" content="{{.Meta.Type}}">
+<meta property="og:title" content="{{.Meta.Title}}">
+<meta property="og:description" content="{{.Meta.Description}}">
+<meta property="og:url" content="{{.Meta.URL}}">
+{{- if .Meta.Image}}
+<meta property="og:image" content="{{.Meta.Image}}">
+{{- if .Meta.ImageAlt}}
+<meta property="og:image:alt" content="{{.Meta.ImageAlt}}">
+{{- end}}
+{{- end}}
+<meta name="twitter:card" content="{{.Meta.Card}}">
+<meta name="twitter:title" content="{{.Meta.Title}}">
+<meta name="twitter:description" content="{{.Meta.Description}}">
+{{- if .Meta.Image}}
+<meta name="twitter:image" content="{{.Meta.Image}}">
+{{- end}}
+{{- end}}
+</head>
+<body>
+<main>
+{{template "content" .}}
+</main>
+</body>
+</html>
+{{define "chirp"}}
+<article>
+<header><a href="{{.Author.URL}}"><strong>{{.Author.Name}}</strong> @{{.Author.Handle}}</a></header>
+<p style="white-space: pre-wrap">{{.Body}}</p>
+{{- range .Images}}
+<img src="{{.URL}}" alt="{{.AltText}}">
+{{- end}}
+<footer><a href="{{.URL}}"><time datetime="{{iso .PublishedAt}}">{{date .PublishedAt}}</time></a></footer>
+</article>
+{{end}}`))
+
+var chirpPage = template.Must(template.Must(templates.Clone()).Parse(
+	`{{define "content"}}{{template "chirp" .Chirp}}{{end}}`,
+))
+
+var userPage = template.Must(template.Must(templates.Clone()).Parse(`{{define "content"}}
+<header>
+{{- if .User.AvatarURL}}
+<img src="{{.User.AvatarURL}}" alt="" width="96" height="96">
+{{- end}}
+<h1>{{.User.Name}}</h1>
+<p>@{{.User.Handle}}</p>
+{{- if .User.Bio}}
+<p style="white-space: pre-wrap">{{.User.Bio}}</p>
+{{- end}}
+</header>
+{{- range .User.Chirps}}
+{{template "chirp" .}}
+{{- end}}
+{{end}}`))
+
+var notFoundPage = template.Must(template.Must(templates.Clone()).Parse(
+	`{{define "content"}}<p>This page isn't available.</p>{{end}}`,
+))
+
+// ChirpPage renders the public page of a chirp.
+func ChirpPage(w io.Writer, chirp Chirp) error {
+	meta := Meta{
+		Title:       chirp.Author.Name + " on Chirpy",
+		Description: Description(chirp.Body),
+		URL:         chirp.URL,
+		Image:       chirp.Author.AvatarURL,
+		Type:        "article",
+	}
+
+	if len(chirp.Images) > 0 {
+		meta.Image = chirp.Images[0].URL
+		meta.ImageAlt = chirp.Images[0].AltText
+		meta.LargeImage = true
+	}
+
+	return chirpPage.Execute(w, struct {
+		Meta  Meta
+		Chirp Chirp
+	}{meta, chirp})
+}
+
+// UserPage renders the public profile of a user with their latest chirps.
+func UserPage(w io.Writer, user User) error {
+	description := user.Bio
+	if description == "" {
+		description = "@" + user.Handle + " on Chirpy"
+	}
+
+	meta := Meta{
+		Title:       user.Name + " (@" + user.Handle + ") on Chirpy",
+		Description: Description(description),
+		URL:         user.URL,
+		Image:       user.AvatarURL,
+		Type:        "profile",
+	}
+
+	return userPage.Execute(w, struct {
+		Meta Meta
+		User User
+	}{meta, user})
+}
+
+// NotFoundPage is shown for anything that doesn't exist or can't be shown,
+// without telling which.
+func NotFoundPage(w io.Writer) error {
+	return notFoundPage.Execute(w, struct {
+		Meta Meta
+	}{Meta{Title: "Chirpy", NoIndex: true}})
+}
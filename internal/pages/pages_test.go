@@ -0,0 +1,130 @@
+package pages
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func testChirp() Chirp {
+	return Chirp{
+		URL: "https://chirpy.example/c/1",
+		Author: Author{
+			Name:      `Alice "Al" <Smith>`,
+			Handle:    "alice",
+			URL:       "https://chirpy.example/u/alice",
+			AvatarURL: "https://cdn.example/alice.png",
+		},
+		Body:        `<script>alert("hi")</script>`,
+		PublishedAt: time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC),
+	}
+}
+
+func TestDescription(t *testing.T) {
+	long := strings.Repeat("word ", 100)
+
+	cases := []struct {
+		text string
+		want string
+	}{
+		{"short", "short"},
+		{"  spread\n\nout  text ", "spread out text"},
+		{long, strings.TrimSpace(long[:195]) + "…"},
+		{strings.Repeat("x", 300), strings.Repeat("x", maxDescriptionLength-1) + "…"},
+	}
+
+	for _, c := range cases {
+		if got := Description(c.text); got != c.want {
+			t.Errorf("Description(%q) = %q, want %q", c.text, got, c.want)
+		}
+	}
+}
+
+func TestChirpPage(t *testing.T) {
+	var b strings.Builder
+
+	if err := ChirpPage(&b, testChirp()); err != nil {
+		t.Fatalf("ChirpPage returned an error: %s", err)
+	}
+
+	out := b.String()
+	if strings.Contains(out, "<script>") || strings.Contains(out, "<Smith>") {
+		t.Errorf("page isn't escaped: %s", out)
+	}
+
+	for _, want := range []string{
+		`<meta property="og:type" content="article">`,
+		`<meta property="og:url" content="https://chirpy.example/c/1">`,
+		`<meta property="og:image" content="https://cdn.example/alice.png">`,
+		`<meta name="twitter:card" content="summary">`,
+		`<link rel="canonical" href="https://chirpy.example/c/1">`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("page is missing %s", want)
+		}
+	}
+}
+
+func TestChirpPageImage(t *testing.T) {
+	chirp := testChirp()
+	chirp.Images = []Image{{URL: "https://cdn.example/cat.jpg", AltText: "A cat"}}
+
+	var b strings.Builder
+
+	if err := ChirpPage(&b, chirp); err != nil {
+		t.Fatalf("ChirpPage returned an error: %s", err)
+	}
+
+	out := b.String()
+	for _, want := range []string{
+		`<meta property="og:image" content="https://cdn.example/cat.jpg">`,
+		`<meta property="og:image:alt" content="A cat">`,
+		`<meta name="twitter:card" content="summary_large_image">`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("page is missing %s", want)
+		}
+	}
+}
+
+func TestUserPage(t *testing.T) {
+	chirp := testChirp()
+
+	var b strings.Builder
+
+	err := UserPage(&b, User{
+		Author: chirp.Author,
+		Bio:    "Likes fish & chips",
+		Chirps: []Chirp{chirp},
+	})
+	if err != nil {
+		t.Fatalf("UserPage returned an error: %s", err)
+	}
+
+	out := b.String()
+	for _, want := range []string{
+		`<meta property="og:type" content="profile">`,
+		`<meta property="og:description" content="Likes fish &amp; chips">`,
+		`<time datetime="2026-03-01T12:00:00Z">Mar 1, 2026</time>`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("page is missing %s", want)
+		}
+	}
+}
+
+func TestNotFoundPage(t *testing.T) {
+	var b strings.Builder
+
+	if err := NotFoundPage(&b); err != nil {
+		t.Fatalf("NotFoundPage returned an error: %s", err)
+	}
+
+	out := b.String()
+	if !strings.Contains(out, `<meta name="robots" content="noindex">`) {
+		t.Errorf("not found page can be indexed: %s", out)
+	}
+	if strings.Contains(out, "og:") {
+		t.Errorf("not found page has OpenGraph tags: %s", out)
+	}
+}
@@ -0,0 +1,222 @@
+package realtime
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"sync"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// Channel is the PostgreSQL channel events are published on, so clients
+// connected to any instance hear about them.
+const Channel = "realtime"
+
+// bufferSize is how many events a client can fall behind before it's
+// dropped. Events only say what changed, so a client that reconnects gets
+// back in sync by fetching the current state.
+const bufferSize = 32
+
+// MaxSubscriptions is how many chirps a single client can follow the
+// counters of at once.
+const MaxSubscriptions = 100
+
+var ErrTooManySubscriptions = errors.New("too many subscriptions")
+
+type EventType string
+
+const (
+	// NotificationEvent is sent to UserID when NotificationID was created or
+	// got a new actor.
+	NotificationEvent EventType = "notification"
+	// PollVoteEvent is sent to the clients subscribed to ChirpID when
+	// someone voted on its poll.
+	PollVoteEvent EventType = "poll_vote"
+	// MessageEvent is sent to UserID when ActorID sent MessageID in
+	// ConversationID.
+	MessageEvent EventType = "message"
+	// TypingEvent is sent to UserID while ActorID is typing in
+	// ConversationID.
+	TypingEvent EventType = "typing"
+)
+
+type Event struct {
+	Type           EventType     `json:"type"`
+	UserID         uuid.NullUUID `json:"user_id"`
+	ActorID        uuid.NullUUID `json:"actor_id"`
+	ChirpID        uuid.NullUUID `json:"chirp_id"`
+	NotificationID uuid.NullUUID `json:"notification_id"`
+	ConversationID uuid.NullUUID `json:"conversation_id"`
+	MessageID      uuid.NullUUID `json:"message_id"`
+}
+
+func (e Event) Payload() (string, error) {
+	data, err := json.Marshal(e)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+type Client struct {
+	userID uuid.UUID
+	chirps map[uuid.UUID]bool
+	events chan Event
+}
+
+func (c *Client) UserID() uuid.UUID {
+	return c.userID
+}
+
+// Events is closed when the client falls too far behind or the hub shuts
+// down.
+func (c *Client) Events() <-chan Event {
+	return c.events
+}
+
+func (c *Client) wants(e Event) bool {
+	switch e.Type {
+	case NotificationEvent, MessageEvent, TypingEvent:
+		return e.UserID.Valid && e.UserID.UUID == c.userID
+	case PollVoteEvent:
+		return e.ChirpID.Valid && c.chirps[e.ChirpID.UUID]
+	default:
+		return false
+	}
+}
+
+// Hub hands events to the connected clients they concern. Whether a client
+// may still see what an event points at is left to the caller.
+type Hub struct {
+	mu      sync.Mutex
+	clients map[*Client]bool
+	closed  bool
+}
+
+func NewHub() *Hub {
+	return &Hub{
+		clients: map[*Client]bool{},
+	}
+}
+
+// Register returns a client that is already closed when the hub is.
+func (h *Hub) Register(userID uuid.UUID) *Client {
+	c := &Client{
+		userID: userID,
+		chirps: map[uuid.UUID]bool{},
+		events: make(chan Event, bufferSize),
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.closed {
+		close(c.events)
+		return c
+	}
+
+	h.clients[c] = true
+	return c
+}
+
+func (h *Hub) Unregister(c *Client) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.drop(c)
+}
+
+// Subscribe follows the counters of a chirp. Subscribing twice to the same
+// chirp is a no-op.
+func (h *Hub) Subscribe(c *Client, chirpID uuid.UUID) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if c.chirps[chirpID] {
+		return nil
+	}
+
+	if len(c.chirps) >= MaxSubscriptions {
+		return ErrTooManySubscriptions
+	}
+
+	c.chirps[chirpID] = true
+	return nil
+}
+
+func (h *Hub) Unsubscribe(c *Client, chirpID uuid.UUID) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	delete(c.chirps, chirpID)
+}
+
+// Close ends every client, so open connections return and the server can
+// shut down.
+func (h *Hub) Close() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.closed = true
+
+	for c := range h.clients {
+		h.drop(c)
+	}
+}
+
+// Run dispatches every event published on Channel until ctx is done or the
+// listener stops.
+func (h *Hub) Run(ctx context.Context, notifications <-chan *pq.Notification) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case n, ok := <-notifications:
+			if !ok {
+				return
+			}
+			// The listener sends nil after reconnecting. Whatever was
+			// published while it was down is lost.
+			if n == nil {
+				continue
+			}
+
+			var e Event
+			if err := json.Unmarshal([]byte(n.Extra), &e); err != nil {
+				log.Printf("Error parsing realtime event %q: %s\n", n.Extra, err)
+				continue
+			}
+
+			h.Dispatch(e)
+		}
+	}
+}
+
+// Dispatch never blocks. Clients whose buffer is full are dropped instead of
+// holding up everyone else.
+func (h *Hub) Dispatch(e Event) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for c := range h.clients {
+		if !c.wants(e) {
+			continue
+		}
+
+		select {
+		case c.events <- e:
+		default:
+			h.drop(c)
+		}
+	}
+}
+
+func (h *Hub) drop(c *Client) {
+	if h.clients[c] {
+		delete(h.clients, c)
+		close(c.events)
+	}
+}
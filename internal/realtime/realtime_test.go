@@ -0,0 +1,119 @@
+package realtime
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+func TestRunRoutesEvents(t *testing.T) {
+	hub := NewHub()
+	recipient := hub.Register(uuid.New())
+	subscriber := hub.Register(uuid.New())
+	other := hub.Register(uuid.New())
+
+	chirpID := uuid.New()
+	if err := hub.Subscribe(subscriber, chirpID); err != nil {
+		t.Errorf("Error subscribing: %s", err)
+	}
+
+	notification := Event{
+		Type:           NotificationEvent,
+		UserID:         uuid.NullUUID{UUID: recipient.UserID(), Valid: true},
+		NotificationID: uuid.NullUUID{UUID: uuid.New(), Valid: true},
+	}
+	vote := Event{
+		Type:    PollVoteEvent,
+		ChirpID: uuid.NullUUID{UUID: chirpID, Valid: true},
+	}
+
+	notifications := make(chan *pq.Notification, 4)
+	notifications <- nil
+	notifications <- &pq.Notification{Channel: Channel, Extra: "not json"}
+	for _, e := range []Event{notification, vote} {
+		payload, err := e.Payload()
+		if err != nil {
+			t.Errorf("Error encoding event: %s", err)
+		}
+		notifications <- &pq.Notification{Channel: Channel, Extra: payload}
+	}
+	close(notifications)
+	hub.Run(context.Background(), notifications)
+
+	for name, c := range map[string]struct {
+		client *Client
+		want   Event
+	}{
+		"recipient":  {recipient, notification},
+		"subscriber": {subscriber, vote},
+	} {
+		select {
+		case got := <-c.client.Events():
+			if got != c.want {
+				t.Errorf("%s got %+v, expected %+v", name, got, c.want)
+			}
+		case <-time.After(time.Second):
+			t.Errorf("%s didn't get the event", name)
+		}
+	}
+
+	select {
+	case got := <-other.Events():
+		t.Errorf("Unrelated client should not get %+v", got)
+	default:
+	}
+}
+
+func TestSubscriptionsAreCapped(t *testing.T) {
+	hub := NewHub()
+	c := hub.Register(uuid.New())
+
+	for i := 0; i < MaxSubscriptions; i++ {
+		if err := hub.Subscribe(c, uuid.New()); err != nil {
+			t.Errorf("Error subscribing: %s", err)
+		}
+	}
+
+	if err := hub.Subscribe(c, uuid.New()); err != ErrTooManySubscriptions {
+		t.Errorf("Expected %v, got %v", ErrTooManySubscriptions, err)
+	}
+}
+
+func TestSlowClientsAreDropped(t *testing.T) {
+	hub := NewHub()
+	c := hub.Register(uuid.New())
+	e := Event{Type: NotificationEvent, UserID: uuid.NullUUID{UUID: c.UserID(), Valid: true}}
+
+	for i := 0; i <= bufferSize; i++ {
+		hub.Dispatch(e)
+	}
+
+	received := 0
+	for range c.Events() {
+		received++
+	}
+
+	if received != bufferSize {
+		t.Errorf("Expected %d buffered events before the client closed, got %d", bufferSize, received)
+	}
+}
+
+func TestCloseEndsClients(t *testing.T) {
+	hub := NewHub()
+	c := hub.Register(uuid.New())
+
+	hub.Close()
+
+	if _, ok := <-c.Events(); ok {
+		t.Error("Clients should be closed with the hub")
+	}
+
+	if _, ok := <-hub.Register(uuid.New()).Events(); ok {
+		t.Error("Registering with a closed hub should return a closed client")
+	}
+
+	hub.Unregister(c)
+}
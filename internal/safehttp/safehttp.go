@@ -0,0 +1,87 @@
+package safehttp
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"syscall"
+	"time"
+)
+
+// ErrBlocked is returned for addresses a transport must not reach, such
+// as private networks or the loopback.
+var ErrBlocked = errors.New("address is not allowed")
+
+// reserved holds the ranges IsGlobalUnicast and IsPrivate don't cover
+// but that still aren't the public internet.
+var reserved = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),
+	netip.MustParsePrefix("100.64.0.0/10"),
+	netip.MustParsePrefix("192.0.0.0/24"),
+	netip.MustParsePrefix("192.0.2.0/24"),
+	netip.MustParsePrefix("198.18.0.0/15"),
+	netip.MustParsePrefix("198.51.100.0/24"),
+	netip.MustParsePrefix("203.0.113.0/24"),
+	netip.MustParsePrefix("240.0.0.0/4"),
+	netip.MustParsePrefix("64:ff9b::/96"),
+	netip.MustParsePrefix("64:ff9b:1::/48"),
+	netip.MustParsePrefix("2001:db8::/32"),
+}
+
+// IsPublic tells whether addr is on the public internet.
+func IsPublic(addr netip.Addr) bool {
+	addr = addr.Unmap()
+
+	if !addr.IsGlobalUnicast() || addr.IsPrivate() {
+		return false
+	}
+
+	for _, p := range reserved {
+		if p.Contains(addr) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// PublicPorts returns a check allowing public addresses on the given
+// ports only.
+func PublicPorts(ports ...uint16) func(netip.AddrPort) bool {
+	return func(addr netip.AddrPort) bool {
+		for _, port := range ports {
+			if addr.Port() == port {
+				return IsPublic(addr.Addr())
+			}
+		}
+		return false
+	}
+}
+
+// NewTransport returns a transport that only connects to the addresses
+// allowed accepts. Addresses are checked when connecting, after DNS
+// resolution, so neither redirects nor DNS rebinding get around the check.
+func NewTransport(allowed func(netip.AddrPort) bool, timeout time.Duration) *http.Transport {
+	dialer := &net.Dialer{
+		Timeout: timeout,
+		Control: func(network, address string, _ syscall.RawConn) error {
+			addr, err := netip.ParseAddrPort(address)
+			if err != nil || !allowed(addr) {
+				return fmt.Errorf("%w: %s", ErrBlocked, address)
+			}
+			return nil
+		},
+	}
+
+	return &http.Transport{
+		// No proxy, which would be dialed instead of the checked address.
+		Proxy:                  nil,
+		DialContext:            dialer.DialContext,
+		TLSHandshakeTimeout:    timeout,
+		MaxResponseHeaderBytes: 64 << 10,
+		MaxIdleConns:           10,
+		IdleConnTimeout:        30 * time.Second,
+	}
+}
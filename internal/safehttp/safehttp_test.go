@@ -0,0 +1,76 @@
+package safehttp
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestIsPublic(t *testing.T) {
+	cases := map[string]bool{
+		"93.184.216.34":   true,
+		"2606:4700::1111": true,
+		"127.0.0.1":       false,
+		"10.1.2.3":        false,
+		"172.16.0.1":      false,
+		"192.168.1.1":     false,
+		"169.254.169.254": false,
+		"100.64.0.1":      false,
+		"0.0.0.0":         false,
+		"255.255.255.255": false,
+		"224.0.0.1":       false,
+		"::1":             false,
+		"fe80::1":         false,
+		"fd00::1":         false,
+		"::ffff:10.0.0.1": false,
+		"64:ff9b::a00:1":  false,
+	}
+
+	for addr, want := range cases {
+		if got := IsPublic(netip.MustParseAddr(addr)); got != want {
+			t.Errorf("IsPublic(%s) = %v, want %v", addr, got, want)
+		}
+	}
+}
+
+func TestPublicPorts(t *testing.T) {
+	allowed := PublicPorts(443)
+
+	cases := map[string]bool{
+		"93.184.216.34:443": true,
+		"93.184.216.34:80":  false,
+		"93.184.216.34:22":  false,
+		"127.0.0.1:443":     false,
+	}
+
+	for addr, want := range cases {
+		if got := allowed(netip.MustParseAddrPort(addr)); got != want {
+			t.Errorf("PublicPorts(443)(%s) = %v, want %v", addr, got, want)
+		}
+	}
+}
+
+func TestNewTransportBlocksPrivateAddresses(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	t.Cleanup(server.Close)
+
+	client := &http.Client{Transport: NewTransport(PublicPorts(80, 443), time.Second)}
+
+	for _, u := range []string{
+		server.URL,
+		strings.Replace(server.URL, "127.0.0.1", "localhost", 1),
+		"http://10.0.0.1/",
+		"http://[::1]/",
+	} {
+		req, _ := http.NewRequestWithContext(context.Background(), http.MethodGet, u, nil)
+		_, err := client.Do(req)
+		if !errors.Is(err, ErrBlocked) {
+			t.Errorf("GET %s = %v, want ErrBlocked", u, err)
+		}
+	}
+}
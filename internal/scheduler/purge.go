@@ -0,0 +1,59 @@
+package scheduler
+
+import (
+	"context"
+	"log"
+	"time"
+)
+
+type PurgeStore interface {
+	PurgeDeletedChirps(ctx context.Context, retentionDays int32) (int64, error)
+}
+
+// Purger hard-deletes chirps that were deleted longer ago than the
+// retention window, which is also how long admins have to restore them.
+// The cutoff is computed by the database, whose clock stamped the chirps.
+type Purger struct {
+	store         PurgeStore
+	retentionDays int32
+	interval      time.Duration
+}
+
+func NewPurger(store PurgeStore, retentionDays int32, interval time.Duration) *Purger {
+	return &Purger{
+		store:         store,
+		retentionDays: retentionDays,
+		interval:      interval,
+	}
+}
+
+// Run purges once right away and then on every tick until ctx is done.
+func (p *Purger) Run(ctx context.Context) {
+	ticker := time.NewTicker(p.interval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := p.Purge(ctx); err != nil {
+			log.Printf("Error purging deleted chirps: %s\n", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (p *Purger) Purge(ctx context.Context) (int64, error) {
+	purged, err := p.store.PurgeDeletedChirps(ctx, p.retentionDays)
+	if err != nil {
+		return 0, err
+	}
+
+	if purged > 0 {
+		log.Printf("Purged %d deleted chirps\n", purged)
+	}
+
+	return purged, nil
+}
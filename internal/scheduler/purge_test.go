@@ -0,0 +1,34 @@
+package scheduler
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+type fakePurgeStore struct {
+	retentionDays int32
+}
+
+func (s *fakePurgeStore) PurgeDeletedChirps(ctx context.Context, retentionDays int32) (int64, error) {
+	s.retentionDays = retentionDays
+	return 3, nil
+}
+
+func TestPurgeUsesRetention(t *testing.T) {
+	store := &fakePurgeStore{}
+
+	purged, err := NewPurger(store, 30, time.Hour).Purge(context.Background())
+
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+
+	if purged != 3 {
+		t.Errorf("Expected 3 purged chirps, got %d", purged)
+	}
+
+	if store.retentionDays != 30 {
+		t.Errorf("Expected a retention of 30 days, got %d", store.retentionDays)
+	}
+}
@@ -0,0 +1,59 @@
+package scheduler
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/firerockets/chirpy/internal/database"
+)
+
+type Store interface {
+	PublishDueChirps(ctx context.Context) ([]database.Chirp, error)
+}
+
+// Publisher publishes scheduled chirps once their publish_at has passed.
+// Schedules live in the database, so chirps that came due while the server
+// was down are published on the first run after a restart.
+type Publisher struct {
+	store    Store
+	interval time.Duration
+}
+
+func NewPublisher(store Store, interval time.Duration) *Publisher {
+	return &Publisher{
+		store:    store,
+		interval: interval,
+	}
+}
+
+// Run publishes once right away and then on every tick until ctx is done.
+func (p *Publisher) Run(ctx context.Context) {
+	ticker := time.NewTicker(p.interval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := p.Publish(ctx); err != nil {
+			log.Printf("Error publishing scheduled chirps: %s\n", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (p *Publisher) Publish(ctx context.Context) ([]database.Chirp, error) {
+	chirps, err := p.store.PublishDueChirps(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(chirps) > 0 {
+		log.Printf("Published %d scheduled chirps\n", len(chirps))
+	}
+
+	return chirps, nil
+}
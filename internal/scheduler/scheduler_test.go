@@ -0,0 +1,66 @@
+package scheduler
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/firerockets/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+type fakeStore struct {
+	calls chan struct{}
+	due   []database.Chirp
+}
+
+func (s *fakeStore) PublishDueChirps(ctx context.Context) ([]database.Chirp, error) {
+	due := s.due
+	s.due = nil
+	s.calls <- struct{}{}
+	return due, nil
+}
+
+func TestRunPublishesRightAway(t *testing.T) {
+	store := &fakeStore{
+		calls: make(chan struct{}, 10),
+		due:   []database.Chirp{{ID: uuid.New()}},
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+
+	go func() {
+		NewPublisher(store, time.Hour).Run(ctx)
+		close(done)
+	}()
+
+	select {
+	case <-store.calls:
+	case <-time.After(time.Second):
+		t.Fatal("Publisher should publish before the first tick")
+	}
+
+	cancel()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Error("Publisher should stop when the context is done")
+	}
+}
+
+func TestPublishReturnsDueChirps(t *testing.T) {
+	chirp := database.Chirp{ID: uuid.New()}
+	store := &fakeStore{calls: make(chan struct{}, 10), due: []database.Chirp{chirp}}
+
+	published, err := NewPublisher(store, time.Minute).Publish(context.Background())
+
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+
+	if len(published) != 1 || published[0].ID != chirp.ID {
+		t.Errorf("Expected %v to be published, got %v", chirp.ID, published)
+	}
+}
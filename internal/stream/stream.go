@@ -0,0 +1,192 @@
+package stream
+
+import (
+	"context"
+	"log"
+	"sync"
+
+	"github.com/firerockets/chirpy/internal/database"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// Channel is the PostgreSQL channel published chirp IDs are announced on.
+// Every instance listens to it, so subscribers hear about chirps published
+// through any of them.
+const Channel = "chirp_published"
+
+// bufferSize is how many chirps a subscriber can fall behind before it's
+// dropped. A dropped client reconnects with Last-Event-ID and catches up
+// from the database.
+const bufferSize = 32
+
+type Store interface {
+	GetChirpById(ctx context.Context, id uuid.UUID) (database.Chirp, error)
+	GetChirpHashtagsByChirpIds(ctx context.Context, chirpIds []uuid.UUID) ([]database.GetChirpHashtagsByChirpIdsRow, error)
+}
+
+// Filter picks the chirps a subscriber wants. The zero value matches every
+// chirp, which is the global feed.
+type Filter struct {
+	AuthorID uuid.NullUUID
+	Hashtag  string
+}
+
+func (f Filter) Matches(chirp database.Chirp, tags []string) bool {
+	if f.AuthorID.Valid && chirp.UserID != f.AuthorID.UUID {
+		return false
+	}
+
+	if f.Hashtag == "" {
+		return true
+	}
+
+	for _, tag := range tags {
+		if tag == f.Hashtag {
+			return true
+		}
+	}
+
+	return false
+}
+
+type Subscription struct {
+	filter Filter
+	chirps chan database.Chirp
+}
+
+// Chirps is closed when the subscriber falls too far behind or the hub
+// shuts down.
+func (s *Subscription) Chirps() <-chan database.Chirp {
+	return s.chirps
+}
+
+// Hub hands announced chirps to the subscribers whose filter they match.
+// Whether a subscriber may see a chirp is left to the caller.
+type Hub struct {
+	store  Store
+	mu     sync.Mutex
+	subs   map[*Subscription]bool
+	closed bool
+}
+
+func NewHub(store Store) *Hub {
+	return &Hub{
+		store: store,
+		subs:  map[*Subscription]bool{},
+	}
+}
+
+// Subscribe returns a subscription that is already closed when the hub is.
+func (h *Hub) Subscribe(filter Filter) *Subscription {
+	sub := &Subscription{
+		filter: filter,
+		chirps: make(chan database.Chirp, bufferSize),
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.closed {
+		close(sub.chirps)
+		return sub
+	}
+
+	h.subs[sub] = true
+	return sub
+}
+
+func (h *Hub) Unsubscribe(sub *Subscription) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.subs[sub] {
+		delete(h.subs, sub)
+		close(sub.chirps)
+	}
+}
+
+// Close ends every subscription, so open streams return and the server can
+// shut down.
+func (h *Hub) Close() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.closed = true
+
+	for sub := range h.subs {
+		delete(h.subs, sub)
+		close(sub.chirps)
+	}
+}
+
+// Run loads every chirp announced on Channel and publishes it until ctx is
+// done or the listener stops.
+func (h *Hub) Run(ctx context.Context, notifications <-chan *pq.Notification) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case n, ok := <-notifications:
+			if !ok {
+				return
+			}
+			// The listener sends nil after reconnecting. Anything announced
+			// while it was down is lost, but clients catch up when they
+			// reconnect with Last-Event-ID.
+			if n == nil {
+				continue
+			}
+
+			id, err := uuid.Parse(n.Extra)
+			if err != nil {
+				log.Printf("Error parsing announced chirp ID %q: %s\n", n.Extra, err)
+				continue
+			}
+
+			if err := h.load(ctx, id); err != nil {
+				log.Printf("Error loading announced chirp %s: %s\n", id, err)
+			}
+		}
+	}
+}
+
+func (h *Hub) load(ctx context.Context, id uuid.UUID) error {
+	chirp, err := h.store.GetChirpById(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	hashtags, err := h.store.GetChirpHashtagsByChirpIds(ctx, []uuid.UUID{id})
+	if err != nil {
+		return err
+	}
+
+	tags := make([]string, 0, len(hashtags))
+	for _, row := range hashtags {
+		tags = append(tags, row.Tag)
+	}
+
+	h.Publish(chirp, tags)
+	return nil
+}
+
+// Publish never blocks. Subscribers whose buffer is full are dropped instead
+// of holding up everyone else.
+func (h *Hub) Publish(chirp database.Chirp, tags []string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for sub := range h.subs {
+		if !sub.filter.Matches(chirp, tags) {
+			continue
+		}
+
+		select {
+		case sub.chirps <- chirp:
+		default:
+			delete(h.subs, sub)
+			close(sub.chirps)
+		}
+	}
+}
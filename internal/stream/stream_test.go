@@ -0,0 +1,126 @@
+package stream
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/firerockets/chirpy/internal/database"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+type fakeStore struct {
+	chirps map[uuid.UUID]database.Chirp
+	tags   map[uuid.UUID][]string
+}
+
+func (s *fakeStore) GetChirpById(ctx context.Context, id uuid.UUID) (database.Chirp, error) {
+	return s.chirps[id], nil
+}
+
+func (s *fakeStore) GetChirpHashtagsByChirpIds(ctx context.Context, chirpIds []uuid.UUID) ([]database.GetChirpHashtagsByChirpIdsRow, error) {
+	rows := []database.GetChirpHashtagsByChirpIdsRow{}
+	for _, id := range chirpIds {
+		for _, tag := range s.tags[id] {
+			rows = append(rows, database.GetChirpHashtagsByChirpIdsRow{ChirpID: id, Tag: tag})
+		}
+	}
+	return rows, nil
+}
+
+func TestFilterMatches(t *testing.T) {
+	author := uuid.New()
+	chirp := database.Chirp{ID: uuid.New(), UserID: author}
+
+	cases := []struct {
+		filter Filter
+		tags   []string
+		want   bool
+	}{
+		{Filter{}, nil, true},
+		{Filter{AuthorID: uuid.NullUUID{UUID: author, Valid: true}}, nil, true},
+		{Filter{AuthorID: uuid.NullUUID{UUID: uuid.New(), Valid: true}}, nil, false},
+		{Filter{Hashtag: "go"}, []string{"rust", "go"}, true},
+		{Filter{Hashtag: "go"}, []string{"rust"}, false},
+		{Filter{AuthorID: uuid.NullUUID{UUID: author, Valid: true}, Hashtag: "go"}, []string{"rust"}, false},
+	}
+
+	for _, c := range cases {
+		if got := c.filter.Matches(chirp, c.tags); got != c.want {
+			t.Errorf("%+v matching tags %v: expected %v, got %v", c.filter, c.tags, c.want, got)
+		}
+	}
+}
+
+func TestRunFansOutMatchingChirps(t *testing.T) {
+	chirp := database.Chirp{ID: uuid.New(), UserID: uuid.New()}
+	store := &fakeStore{
+		chirps: map[uuid.UUID]database.Chirp{chirp.ID: chirp},
+		tags:   map[uuid.UUID][]string{chirp.ID: {"go"}},
+	}
+
+	hub := NewHub(store)
+	everything := hub.Subscribe(Filter{})
+	tagged := hub.Subscribe(Filter{Hashtag: "go"})
+	other := hub.Subscribe(Filter{Hashtag: "rust"})
+
+	notifications := make(chan *pq.Notification, 3)
+	notifications <- nil
+	notifications <- &pq.Notification{Channel: Channel, Extra: "not an ID"}
+	notifications <- &pq.Notification{Channel: Channel, Extra: chirp.ID.String()}
+	close(notifications)
+	hub.Run(context.Background(), notifications)
+
+	for name, sub := range map[string]*Subscription{"global": everything, "hashtag": tagged} {
+		select {
+		case got := <-sub.Chirps():
+			if got.ID != chirp.ID {
+				t.Errorf("%s subscriber got %v, expected %v", name, got.ID, chirp.ID)
+			}
+		case <-time.After(time.Second):
+			t.Errorf("%s subscriber didn't get the chirp", name)
+		}
+	}
+
+	select {
+	case got := <-other.Chirps():
+		t.Errorf("Subscriber to another hashtag should not get %v", got.ID)
+	default:
+	}
+}
+
+func TestSlowSubscribersAreDropped(t *testing.T) {
+	hub := NewHub(&fakeStore{})
+	sub := hub.Subscribe(Filter{})
+
+	for i := 0; i <= bufferSize; i++ {
+		hub.Publish(database.Chirp{ID: uuid.New()}, nil)
+	}
+
+	received := 0
+	for range sub.Chirps() {
+		received++
+	}
+
+	if received != bufferSize {
+		t.Errorf("Expected %d buffered chirps before the subscription closed, got %d", bufferSize, received)
+	}
+}
+
+func TestCloseEndsSubscriptions(t *testing.T) {
+	hub := NewHub(&fakeStore{})
+	sub := hub.Subscribe(Filter{})
+
+	hub.Close()
+
+	if _, ok := <-sub.Chirps(); ok {
+		t.Error("Subscriptions should be closed with the hub")
+	}
+
+	if _, ok := <-hub.Subscribe(Filter{}).Chirps(); ok {
+		t.Error("Subscribing to a closed hub should return a closed subscription")
+	}
+
+	hub.Unsubscribe(sub)
+}
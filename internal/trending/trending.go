@@ -0,0 +1,232 @@
+package trending
+
+import (
+	"context"
+	"database/sql"
+	"log"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/firerockets/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+const (
+	// Tags used fewer times than this in a window never trend.
+	MinUses = 3
+	// Number of tags kept per window.
+	Limit = 10
+
+	bucketRetention = 2 * 7 * 24 * time.Hour
+	// Chirps changed this long before the last run have their buckets
+	// recounted again, to pick up the ones committed late.
+	recountOverlap = 10 * time.Minute
+)
+
+type Window struct {
+	Name   string
+	Length time.Duration
+}
+
+var Windows = []Window{
+	{Name: "hour", Length: time.Hour},
+	{Name: "day", Length: 24 * time.Hour},
+	{Name: "week", Length: 7 * 24 * time.Hour},
+}
+
+type Count struct {
+	HashtagID    uuid.UUID
+	Uses         int64
+	PreviousUses int64
+}
+
+type Trend struct {
+	Count
+	Rank  int
+	Score float64
+}
+
+// Score rates how fast a tag grows compared to the previous period of the
+// same length. Dividing by the square root of the previous count keeps a
+// jump from 1 to 5 uses from outranking one from 100 to 300.
+func Score(uses, previousUses int64) float64 {
+	return float64(uses-previousUses) / math.Sqrt(float64(previousUses)+1)
+}
+
+// Rank scores the counts and returns the best limit of them, dropping the
+// ones that are not growing or are used less than MinUses times.
+func Rank(counts []Count, limit int) []Trend {
+	trends := []Trend{}
+
+	for _, c := range counts {
+		score := Score(c.Uses, c.PreviousUses)
+		if c.Uses < MinUses || score <= 0 {
+			continue
+		}
+		trends = append(trends, Trend{Count: c, Score: score})
+	}
+
+	sort.Slice(trends, func(i, j int) bool {
+		if trends[i].Score != trends[j].Score {
+			return trends[i].Score > trends[j].Score
+		}
+		if trends[i].Uses != trends[j].Uses {
+			return trends[i].Uses > trends[j].Uses
+		}
+		return trends[i].HashtagID.String() < trends[j].HashtagID.String()
+	})
+
+	if len(trends) > limit {
+		trends = trends[:limit]
+	}
+
+	for i := range trends {
+		trends[i].Rank = i + 1
+	}
+
+	return trends
+}
+
+// Aggregator rolls hashtag usage up into five minute buckets and stores the
+// ranked tags of every window in trending_hashtags. Every run recounts the
+// buckets of the chirps published, deleted, removed or restored since the
+// last one, so chirps that are gone stop counting.
+type Aggregator struct {
+	db       *sql.DB
+	queries  *database.Queries
+	interval time.Duration
+	lastRun  time.Time
+}
+
+func NewAggregator(db *sql.DB, queries *database.Queries, interval time.Duration) *Aggregator {
+	return &Aggregator{
+		db:       db,
+		queries:  queries,
+		interval: interval,
+	}
+}
+
+// Run aggregates once right away and then on every tick until ctx is done.
+func (a *Aggregator) Run(ctx context.Context) {
+	ticker := time.NewTicker(a.interval)
+	defer ticker.Stop()
+
+	for {
+		if err := a.Aggregate(ctx); err != nil {
+			log.Printf("Error aggregating trending hashtags: %s\n", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Aggregate takes the time from the database, which stamps the chirps in its
+// own time zone, so the buckets and windows line up with them.
+func (a *Aggregator) Aggregate(ctx context.Context) error {
+	now, err := a.queries.GetDatabaseTime(ctx)
+	if err != nil {
+		return err
+	}
+
+	since := now.Add(-bucketRetention)
+	if !a.lastRun.IsZero() {
+		since = a.lastRun.Add(-recountOverlap)
+	}
+
+	err = a.recount(ctx, since)
+	if err != nil {
+		return err
+	}
+
+	err = a.queries.DeleteHashtagBucketsBefore(ctx, now.Add(-bucketRetention))
+	if err != nil {
+		return err
+	}
+
+	for _, window := range Windows {
+		err = a.aggregateWindow(ctx, window, now)
+		if err != nil {
+			return err
+		}
+	}
+
+	a.lastRun = now
+
+	return nil
+}
+
+// recount replaces the buckets of the chirps changed since the given time.
+func (a *Aggregator) recount(ctx context.Context, since time.Time) error {
+	tx, err := a.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	qtx := a.queries.WithTx(tx)
+
+	err = qtx.DeleteStaleHashtagBuckets(ctx, since)
+	if err != nil {
+		return err
+	}
+
+	err = qtx.UpsertHashtagBuckets(ctx, since)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (a *Aggregator) aggregateWindow(ctx context.Context, window Window, now time.Time) error {
+	rows, err := a.queries.GetHashtagWindowCounts(ctx, database.GetHashtagWindowCountsParams{
+		CurrentStart:  now.Add(-window.Length),
+		PreviousStart: now.Add(-2 * window.Length),
+	})
+	if err != nil {
+		return err
+	}
+
+	counts := make([]Count, 0, len(rows))
+	for _, r := range rows {
+		counts = append(counts, Count{
+			HashtagID:    r.HashtagID,
+			Uses:         r.Uses,
+			PreviousUses: r.PreviousUses,
+		})
+	}
+
+	tx, err := a.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	qtx := a.queries.WithTx(tx)
+
+	err = qtx.DeleteTrendingHashtags(ctx, window.Name)
+	if err != nil {
+		return err
+	}
+
+	for _, trend := range Rank(counts, Limit) {
+		err = qtx.CreateTrendingHashtag(ctx, database.CreateTrendingHashtagParams{
+			TimeWindow:   window.Name,
+			HashtagID:    trend.HashtagID,
+			Rank:         int32(trend.Rank),
+			Uses:         trend.Uses,
+			PreviousUses: trend.PreviousUses,
+			Score:        trend.Score,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
@@ -0,0 +1,45 @@
+package trending
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestRankPrefersGrowth(t *testing.T) {
+	steady := Count{HashtagID: uuid.New(), Uses: 500, PreviousUses: 480}
+	rising := Count{HashtagID: uuid.New(), Uses: 40, PreviousUses: 4}
+	shrinking := Count{HashtagID: uuid.New(), Uses: 10, PreviousUses: 50}
+	rare := Count{HashtagID: uuid.New(), Uses: 2, PreviousUses: 0}
+
+	trends := Rank([]Count{steady, rising, shrinking, rare}, Limit)
+
+	if len(trends) != 2 {
+		t.Fatalf("Expected 2 trends, got %d: %v", len(trends), trends)
+	}
+
+	if trends[0].HashtagID != rising.HashtagID || trends[0].Rank != 1 {
+		t.Errorf("Rising tag should rank first, got %v", trends[0])
+	}
+
+	if trends[1].HashtagID != steady.HashtagID || trends[1].Rank != 2 {
+		t.Errorf("Steady tag should rank second, got %v", trends[1])
+	}
+}
+
+func TestRankLimit(t *testing.T) {
+	counts := []Count{}
+	for i := 0; i < 5; i++ {
+		counts = append(counts, Count{HashtagID: uuid.New(), Uses: int64(10 + i)})
+	}
+
+	trends := Rank(counts, 3)
+
+	if len(trends) != 3 {
+		t.Fatalf("Expected 3 trends, got %d", len(trends))
+	}
+
+	if trends[0].Uses != 14 {
+		t.Errorf("Most used new tag should rank first, got %v", trends[0])
+	}
+}
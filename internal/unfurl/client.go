@@ -0,0 +1,126 @@
+package unfurl
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"time"
+
+	"github.com/firerockets/chirpy/internal/safehttp"
+	"golang.org/x/net/html/charset"
+)
+
+const (
+	fetchTimeout = 5 * time.Second
+	dialTimeout  = 3 * time.Second
+	maxRedirects = 3
+	// maxBodySize is plenty for a <head>. The rest of the page isn't read.
+	maxBodySize = 512 << 10
+	userAgent   = "Chirpy-LinkPreview/1.0"
+)
+
+var (
+	// ErrBlocked is returned for URLs that resolve to an address the
+	// fetcher must not reach, such as private networks or the loopback.
+	ErrBlocked          = safehttp.ErrBlocked
+	ErrInvalidURL       = errors.New("invalid URL")
+	ErrTooManyRedirects = errors.New("too many redirects")
+	ErrNotHTML          = errors.New("not an HTML page")
+	ErrUnavailable      = errors.New("page is unavailable")
+)
+
+// Permanent tells whether fetching the URL again can't do better.
+func Permanent(err error) bool {
+	return errors.Is(err, ErrBlocked) ||
+		errors.Is(err, ErrInvalidURL) ||
+		errors.Is(err, ErrTooManyRedirects) ||
+		errors.Is(err, ErrNotHTML) ||
+		errors.Is(err, ErrUnavailable)
+}
+
+// Fetcher fetches previews without letting chirp authors reach internal
+// services, through a transport that only connects to public addresses.
+type Fetcher struct {
+	client *http.Client
+}
+
+func NewFetcher() *Fetcher {
+	return newFetcher(safehttp.PublicPorts(80, 443))
+}
+
+func newFetcher(allowed func(netip.AddrPort) bool) *Fetcher {
+	transport := safehttp.NewTransport(allowed, dialTimeout)
+	transport.ResponseHeaderTimeout = fetchTimeout
+
+	return &Fetcher{
+		client: &http.Client{
+			Transport: transport,
+			Timeout:   fetchTimeout,
+			CheckRedirect: func(req *http.Request, via []*http.Request) error {
+				if len(via) >= maxRedirects {
+					return ErrTooManyRedirects
+				}
+				return checkURL(req.URL)
+			},
+		},
+	}
+}
+
+func checkURL(u *url.URL) error {
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Hostname() == "" || u.User != nil {
+		return fmt.Errorf("%w: %s", ErrInvalidURL, u.Redacted())
+	}
+	return nil
+}
+
+// Fetch downloads the page at rawURL and parses its preview.
+func (f *Fetcher) Fetch(ctx context.Context, rawURL string) (Preview, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return Preview{}, fmt.Errorf("%w: %s", ErrInvalidURL, err)
+	}
+
+	if err := checkURL(u); err != nil {
+		return Preview{}, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return Preview{}, fmt.Errorf("%w: %s", ErrInvalidURL, err)
+	}
+
+	req.Header.Set("User-Agent", userAgent)
+	req.Header.Set("Accept", "text/html,application/xhtml+xml")
+
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return Preview{}, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 400 && resp.StatusCode < 500 {
+		return Preview{}, fmt.Errorf("%w: %s", ErrUnavailable, resp.Status)
+	} else if resp.StatusCode != http.StatusOK {
+		return Preview{}, fmt.Errorf("unexpected status %s", resp.Status)
+	}
+
+	contentType := resp.Header.Get("Content-Type")
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil || (mediaType != "text/html" && mediaType != "application/xhtml+xml") {
+		return Preview{}, fmt.Errorf("%w: %s", ErrNotHTML, contentType)
+	}
+
+	body, err := charset.NewReader(io.LimitReader(resp.Body, maxBodySize), contentType)
+	if err != nil {
+		return Preview{}, err
+	}
+
+	// The final URL, after redirects, is what relative images resolve
+	// against.
+	return Parse(body, resp.Request.URL), nil
+}
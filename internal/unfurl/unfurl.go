@@ -0,0 +1,134 @@
+package unfurl
+
+import (
+	"io"
+	"net/url"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/net/html"
+)
+
+const (
+	maxTitleLength       = 300
+	maxDescriptionLength = 1000
+	maxSiteNameLength    = 100
+)
+
+// Preview is what a page says about itself. A Preview with an empty Title
+// means the page has none worth showing.
+type Preview struct {
+	Title       string
+	Description string
+	ImageURL    string
+	SiteName    string
+}
+
+// Parse reads the preview from the <head> of a page found at base.
+// OpenGraph tags win over Twitter cards, which win over <title> and the
+// meta description.
+func Parse(r io.Reader, base *url.URL) Preview {
+	tags := map[string]string{}
+	var title string
+
+	z := html.NewTokenizer(r)
+
+	for {
+		tt := z.Next()
+		if tt == html.ErrorToken {
+			break
+		}
+
+		if tt != html.StartTagToken && tt != html.SelfClosingTagToken {
+			continue
+		}
+
+		name, hasAttr := z.TagName()
+		tag := string(name)
+
+		if tag == "body" {
+			break
+		}
+
+		if tag == "title" && title == "" {
+			if z.Next() == html.TextToken {
+				title = string(z.Text())
+			}
+			continue
+		}
+
+		if tag != "meta" || !hasAttr {
+			continue
+		}
+
+		var key, content string
+		for {
+			attr, value, more := z.TagAttr()
+			switch strings.ToLower(string(attr)) {
+			case "property", "name":
+				key = strings.ToLower(string(value))
+			case "content":
+				content = string(value)
+			}
+			if !more {
+				break
+			}
+		}
+
+		if _, seen := tags[key]; key != "" && !seen {
+			tags[key] = content
+		}
+	}
+
+	first := func(keys ...string) string {
+		for _, k := range keys {
+			if v := strings.TrimSpace(tags[k]); v != "" {
+				return v
+			}
+		}
+		return ""
+	}
+
+	p := Preview{
+		Title:       first("og:title", "twitter:title"),
+		Description: first("og:description", "twitter:description", "description"),
+		SiteName:    first("og:site_name"),
+	}
+
+	if p.Title == "" {
+		p.Title = strings.TrimSpace(title)
+	}
+
+	p.Title = clean(p.Title, maxTitleLength)
+	p.Description = clean(p.Description, maxDescriptionLength)
+	p.SiteName = clean(p.SiteName, maxSiteNameLength)
+	p.ImageURL = imageURL(base, first("og:image:secure_url", "og:image", "twitter:image"))
+
+	return p
+}
+
+// clean collapses whitespace and cuts text to at most n characters.
+func clean(text string, n int) string {
+	text = strings.Join(strings.Fields(text), " ")
+
+	if utf8.RuneCountInString(text) <= n {
+		return text
+	}
+
+	return strings.TrimSpace(string([]rune(text)[:n-1])) + "…"
+}
+
+// imageURL resolves a relative image against the page and drops anything
+// but http and https, so clients never get a javascript: or data: URL.
+func imageURL(base *url.URL, raw string) string {
+	if raw == "" {
+		return ""
+	}
+
+	u, err := base.Parse(raw)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return ""
+	}
+
+	return u.String()
+}
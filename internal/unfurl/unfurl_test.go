@@ -0,0 +1,80 @@
+package unfurl
+
+import (
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	base, _ := url.Parse("https://news.example/articles/1")
+
+	cases := []struct {
+		name string
+		page string
+		want Preview
+	}{
+		{
+			name: "opengraph",
+			page: `<html><head>
+				<title>Fallback</title>
+				<meta property="og:title" content="  Big   news ">
+				<meta property="og:description" content="What happened &amp; why">
+				<meta property="og:image" content="/img/cover.png">
+				<meta property="og:site_name" content="News">
+				</head><body></body></html>`,
+			want: Preview{
+				Title:       "Big news",
+				Description: "What happened & why",
+				ImageURL:    "https://news.example/img/cover.png",
+				SiteName:    "News",
+			},
+		},
+		{
+			name: "twitter card",
+			page: `<head><meta name="twitter:title" content="Tweeted">
+				<meta name="twitter:image" content="https://cdn.example/a.png"></head>`,
+			want: Preview{Title: "Tweeted", ImageURL: "https://cdn.example/a.png"},
+		},
+		{
+			name: "title and description",
+			page: `<head><TITLE>Plain page</TITLE><meta name="Description" content="About it"></head>`,
+			want: Preview{Title: "Plain page", Description: "About it"},
+		},
+		{
+			name: "unsafe image",
+			page: `<head><meta property="og:title" content="x"><meta property="og:image" content="javascript:alert(1)"></head>`,
+			want: Preview{Title: "x"},
+		},
+		{
+			name: "tags in body",
+			page: `<head></head><body><meta property="og:title" content="Injected"></body>`,
+			want: Preview{},
+		},
+		{
+			name: "first tag wins",
+			page: `<head><meta property="og:title" content="First"><meta property="og:title" content="Second"></head>`,
+			want: Preview{Title: "First"},
+		},
+	}
+
+	for _, c := range cases {
+		if got := Parse(strings.NewReader(c.page), base); got != c.want {
+			t.Errorf("%s: Parse = %+v, want %+v", c.name, got, c.want)
+		}
+	}
+}
+
+func TestParseLongTitle(t *testing.T) {
+	base, _ := url.Parse("https://news.example/")
+	page := `<head><title>` + strings.Repeat("a", 1000) + `</title></head>`
+
+	got := Parse(strings.NewReader(page), base)
+
+	if n := len([]rune(got.Title)); n != maxTitleLength {
+		t.Errorf("title has %d characters, want %d", n, maxTitleLength)
+	}
+	if !strings.HasSuffix(got.Title, "…") {
+		t.Errorf("cut title doesn't end with an ellipsis: %q", got.Title)
+	}
+}
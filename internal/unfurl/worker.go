@@ -0,0 +1,103 @@
+package unfurl
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/firerockets/chirpy/internal/database"
+)
+
+const (
+	// fetchBatchSize keeps a batch within about a minute even when every
+	// page times out.
+	fetchBatchSize   = 10
+	maxFetchAttempts = 3
+	retryDelay       = 10 * time.Minute
+)
+
+type Store interface {
+	ClaimDueLinkPreviews(ctx context.Context, limit int32) ([]database.ClaimDueLinkPreviewsRow, error)
+	RetryLinkPreview(ctx context.Context, arg database.RetryLinkPreviewParams) error
+	SaveLinkPreview(ctx context.Context, arg database.SaveLinkPreviewParams) error
+}
+
+// Worker fetches the previews of newly linked URLs in the background, so
+// posting a chirp never waits on a remote site.
+type Worker struct {
+	store    Store
+	fetcher  *Fetcher
+	interval time.Duration
+}
+
+func NewWorker(store Store, fetcher *Fetcher, interval time.Duration) *Worker {
+	return &Worker{
+		store:    store,
+		fetcher:  fetcher,
+		interval: interval,
+	}
+}
+
+// Run fetches once right away and then on every tick until ctx is done.
+func (w *Worker) Run(ctx context.Context) {
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := w.Unfurl(ctx); err != nil {
+			log.Printf("Error fetching link previews: %s\n", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Unfurl fetches the previews that are due and returns how many were
+// found. URLs that fail for good are saved without a preview, so they
+// aren't fetched again.
+func (w *Worker) Unfurl(ctx context.Context) (int, error) {
+	due, err := w.store.ClaimDueLinkPreviews(ctx, fetchBatchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	found := 0
+
+	for _, link := range due {
+		preview, err := w.fetcher.Fetch(ctx, link.Url)
+
+		if err != nil && !Permanent(err) && link.Attempts < maxFetchAttempts {
+			err = w.store.RetryLinkPreview(ctx, database.RetryLinkPreviewParams{
+				Url:           link.Url,
+				NextAttemptAt: time.Now().Add(retryDelay),
+			})
+			if err != nil {
+				return found, err
+			}
+			continue
+		}
+
+		if err != nil {
+			log.Printf("No preview for %s: %s\n", link.Url, err)
+		} else if preview.Title != "" {
+			found++
+		}
+
+		err = w.store.SaveLinkPreview(ctx, database.SaveLinkPreviewParams{
+			Url:         link.Url,
+			Title:       preview.Title,
+			Description: preview.Description,
+			ImageUrl:    preview.ImageURL,
+			SiteName:    preview.SiteName,
+		})
+		if err != nil {
+			return found, err
+		}
+	}
+
+	return found, nil
+}
@@ -0,0 +1,75 @@
+package unfurl
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/firerockets/chirpy/internal/database"
+)
+
+type fakeStore struct {
+	due     []database.ClaimDueLinkPreviewsRow
+	saved   map[string]database.SaveLinkPreviewParams
+	retried map[string]time.Time
+}
+
+func (s *fakeStore) ClaimDueLinkPreviews(ctx context.Context, limit int32) ([]database.ClaimDueLinkPreviewsRow, error) {
+	due := s.due
+	s.due = nil
+	return due, nil
+}
+
+func (s *fakeStore) RetryLinkPreview(ctx context.Context, arg database.RetryLinkPreviewParams) error {
+	s.retried[arg.Url] = arg.NextAttemptAt
+	return nil
+}
+
+func (s *fakeStore) SaveLinkPreview(ctx context.Context, arg database.SaveLinkPreviewParams) error {
+	s.saved[arg.Url] = arg
+	return nil
+}
+
+func TestUnfurl(t *testing.T) {
+	server := newTestServer(t)
+
+	store := &fakeStore{
+		saved:   map[string]database.SaveLinkPreviewParams{},
+		retried: map[string]time.Time{},
+	}
+	worker := NewWorker(store, newTestFetcher(), time.Minute)
+
+	article := server.URL + "/article"
+	missing := server.URL + "/missing"
+	broken := server.URL + "/broken"
+	exhausted := server.URL + "/broken?again"
+
+	store.due = []database.ClaimDueLinkPreviewsRow{
+		{Url: article, Attempts: 1},
+		{Url: missing, Attempts: 1},
+		{Url: broken, Attempts: 1},
+		{Url: exhausted, Attempts: maxFetchAttempts},
+	}
+
+	found, err := worker.Unfurl(context.Background())
+
+	if err != nil || found != 1 {
+		t.Errorf("Expected 1 preview, got %d (%v)", found, err)
+	}
+
+	if store.saved[article].Title != "Article" {
+		t.Errorf("Article preview wasn't saved: %+v", store.saved[article])
+	}
+
+	if saved, ok := store.saved[missing]; !ok || saved.Title != "" {
+		t.Errorf("Missing pages should be saved without a preview, got %+v", saved)
+	}
+
+	if _, ok := store.retried[broken]; !ok {
+		t.Errorf("Failing pages should be retried")
+	}
+
+	if _, ok := store.saved[exhausted]; !ok {
+		t.Errorf("Pages should be given up on after %d attempts", maxFetchAttempts)
+	}
+}
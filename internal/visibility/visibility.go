@@ -0,0 +1,120 @@
+package visibility
+
+import (
+	"github.com/firerockets/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+type Mode int
+
+const (
+	// Direct applies to things the viewer asked for by name: a chirp by ID,
+	// a profile or their own bookmarks. Only blocks hide anything.
+	Direct Mode = iota
+	// Feed applies to listings and search, which leave out muted users too.
+	Feed
+)
+
+// Relations holds who a viewer can't see. Blocks go both ways, so a user
+// is hidden whether the viewer blocked them or they blocked the viewer.
+// Mutes only hide the muted user from the muter. Suspended users are hidden
+// from everyone, which the queries take care of.
+type Relations struct {
+	Viewer  uuid.NullUUID
+	blocked map[uuid.UUID]bool
+	muted   map[uuid.UUID]bool
+}
+
+func New(viewer uuid.NullUUID, blocked, muted []uuid.UUID) Relations {
+	r := Relations{
+		Viewer:  viewer,
+		blocked: map[uuid.UUID]bool{},
+		muted:   map[uuid.UUID]bool{},
+	}
+
+	for _, id := range blocked {
+		r.blocked[id] = true
+	}
+
+	for _, id := range muted {
+		r.muted[id] = true
+	}
+
+	return r
+}
+
+func (r Relations) isViewer(userID uuid.UUID) bool {
+	return r.Viewer.Valid && r.Viewer.UUID == userID
+}
+
+// Blocked tells whether there's a block between the viewer and userID, in
+// either direction.
+func (r Relations) Blocked(userID uuid.UUID) bool {
+	return r.blocked[userID]
+}
+
+func (r Relations) CanSeeUser(userID uuid.UUID, mode Mode) bool {
+	if r.isViewer(userID) {
+		return true
+	}
+	if r.blocked[userID] {
+		return false
+	}
+	return mode == Direct || !r.muted[userID]
+}
+
+// CanSeeChirp also hides drafts and scheduled chirps from everyone but
+// their author, and removed or deleted chirps from everyone.
+func (r Relations) CanSeeChirp(chirp database.Chirp, mode Mode) bool {
+	if chirp.RemovedAt.Valid || chirp.DeletedAt.Valid {
+		return false
+	}
+	if !chirp.PublishedAt.Valid && !r.isViewer(chirp.UserID) {
+		return false
+	}
+	return r.CanSeeUser(chirp.UserID, mode)
+}
+
+func (r Relations) Chirps(chirps []database.Chirp, mode Mode) []database.Chirp {
+	visible := []database.Chirp{}
+
+	for _, c := range chirps {
+		if r.CanSeeChirp(c, mode) {
+			visible = append(visible, c)
+		}
+	}
+
+	return visible
+}
+
+func (r Relations) Users(users []database.User, mode Mode) []database.User {
+	visible := []database.User{}
+
+	for _, u := range users {
+		if r.CanSeeUser(u.ID, mode) {
+			visible = append(visible, u)
+		}
+	}
+
+	return visible
+}
+
+// Excluded lists the users to leave out of paginated queries, so pages
+// aren't cut short by filtering after the fact.
+func (r Relations) Excluded(mode Mode) []uuid.UUID {
+	excluded := []uuid.UUID{}
+
+	for id := range r.blocked {
+		excluded = append(excluded, id)
+	}
+
+	if mode == Feed {
+		for id := range r.muted {
+			if !r.blocked[id] {
+				excluded = append(excluded, id)
+			}
+		}
+	}
+
+	return excluded
+}
@@ -0,0 +1,131 @@
+package visibility
+
+import (
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/firerockets/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+func published(author uuid.UUID) database.Chirp {
+	return database.Chirp{
+		ID:          uuid.New(),
+		UserID:      author,
+		PublishedAt: sql.NullTime{Time: time.Now(), Valid: true},
+	}
+}
+
+func TestBlocksHideBothWays(t *testing.T) {
+	alice, bob := uuid.New(), uuid.New()
+
+	// A block between alice and bob shows up in both of their relations,
+	// whoever created it.
+	asAlice := New(uuid.NullUUID{UUID: alice, Valid: true}, []uuid.UUID{bob}, nil)
+	asBob := New(uuid.NullUUID{UUID: bob, Valid: true}, []uuid.UUID{alice}, nil)
+
+	for _, mode := range []Mode{Direct, Feed} {
+		if asAlice.CanSeeChirp(published(bob), mode) {
+			t.Errorf("Alice should not see Bob's chirps in mode %d", mode)
+		}
+		if asBob.CanSeeChirp(published(alice), mode) {
+			t.Errorf("Bob should not see Alice's chirps in mode %d", mode)
+		}
+		if asAlice.CanSeeUser(bob, mode) || asBob.CanSeeUser(alice, mode) {
+			t.Errorf("Blocked users should not see each other in mode %d", mode)
+		}
+	}
+}
+
+func TestMutesOnlyHideFeeds(t *testing.T) {
+	alice, bob := uuid.New(), uuid.New()
+
+	asAlice := New(uuid.NullUUID{UUID: alice, Valid: true}, nil, []uuid.UUID{bob})
+	asBob := New(uuid.NullUUID{UUID: bob, Valid: true}, nil, nil)
+
+	if asAlice.CanSeeChirp(published(bob), Feed) {
+		t.Error("Muted chirps should not show up in feeds")
+	}
+
+	if !asAlice.CanSeeChirp(published(bob), Direct) {
+		t.Error("Muted chirps should still open directly")
+	}
+
+	if !asBob.CanSeeChirp(published(alice), Feed) {
+		t.Error("Mutes should not hide the muter from the muted user")
+	}
+}
+
+func TestChirpsFiltersEverything(t *testing.T) {
+	viewer, blocked, muted, other := uuid.New(), uuid.New(), uuid.New(), uuid.New()
+
+	r := New(uuid.NullUUID{UUID: viewer, Valid: true}, []uuid.UUID{blocked}, []uuid.UUID{muted})
+
+	draft := database.Chirp{ID: uuid.New(), UserID: other}
+	ownDraft := database.Chirp{ID: uuid.New(), UserID: viewer}
+	fromOther := published(other)
+
+	chirps := []database.Chirp{published(blocked), published(muted), draft, ownDraft, fromOther}
+
+	visible := r.Chirps(chirps, Feed)
+
+	if len(visible) != 2 || visible[0].ID != ownDraft.ID || visible[1].ID != fromOther.ID {
+		t.Errorf("Only the own draft and the other chirp should be visible, got %v", visible)
+	}
+
+	visible = r.Chirps(chirps, Direct)
+
+	if len(visible) != 3 {
+		t.Errorf("Direct reads should only drop blocked chirps and others' drafts, got %v", visible)
+	}
+}
+
+func TestAnonymousViewers(t *testing.T) {
+	r := New(uuid.NullUUID{}, nil, nil)
+	author := uuid.New()
+
+	if !r.CanSeeChirp(published(author), Feed) {
+		t.Error("Anonymous viewers should see published chirps")
+	}
+
+	if r.CanSeeChirp(database.Chirp{ID: uuid.New(), UserID: author}, Direct) {
+		t.Error("Anonymous viewers should not see unpublished chirps")
+	}
+}
+
+func TestExcluded(t *testing.T) {
+	blocked, muted := uuid.New(), uuid.New()
+	r := New(uuid.NullUUID{UUID: uuid.New(), Valid: true}, []uuid.UUID{blocked}, []uuid.UUID{muted, blocked})
+
+	if got := r.Excluded(Direct); len(got) != 1 || got[0] != blocked {
+		t.Errorf("Direct reads should only exclude blocked users, got %v", got)
+	}
+
+	if got := r.Excluded(Feed); len(got) != 2 {
+		t.Errorf("Feeds should exclude blocked and muted users once each, got %v", got)
+	}
+}
+
+func TestRemovedAndDeleted(t *testing.T) {
+	viewer, other := uuid.New(), uuid.New()
+	r := New(uuid.NullUUID{UUID: viewer, Valid: true}, nil, nil)
+
+	removed := published(viewer)
+	removed.RemovedAt = sql.NullTime{Time: time.Now(), Valid: true}
+
+	if r.CanSeeChirp(removed, Direct) {
+		t.Error("Removed chirps should be hidden even from their author")
+	}
+
+	deleted := published(viewer)
+	deleted.DeletedAt = sql.NullTime{Time: time.Now(), Valid: true}
+
+	if r.CanSeeChirp(deleted, Direct) {
+		t.Error("Deleted chirps should be hidden even from their author")
+	}
+
+	if !r.CanSeeChirp(published(other), Feed) {
+		t.Error("Chirps by other users should stay visible")
+	}
+}
@@ -0,0 +1,129 @@
+package widget
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"html"
+	"html/template"
+	"io"
+	"time"
+)
+
+const (
+	DefaultWidth  = 550
+	DefaultHeight = 250
+	MinWidth      = 200
+	MinHeight     = 120
+)
+
+// style is inlined so the page needs no other request than the avatar. The
+// CSP allows it by hash rather than with 'unsafe-inline'.
+const style = `
+body { margin: 0; font: 15px/1.4 system-ui, sans-serif; color: #0f1419; background: #fff; }
+.chirp { box-sizing: border-box; height: 100vh; padding: 12px 16px; border: 1px solid #cfd9de; border-radius: 12px; overflow: hidden; }
+.author { display: flex; align-items: center; gap: 8px; margin-bottom: 8px; }
+.author img { width: 40px; height: 40px; border-radius: 50%; }
+.name { font-weight: 700; }
+.handle, .date, .placeholder { color: #536471; }
+.body { white-space: pre-wrap; overflow-wrap: anywhere; margin: 0 0 8px; }
+a { color: inherit; text-decoration: none; }
+`
+
+// CSP lets the page be framed anywhere while running no scripts and loading
+// nothing but images.
+var CSP = "default-src 'none'; " +
+	"style-src '" + styleHash() + "'; " +
+	"img-src 'self' https: data:; " +
+	"base-uri 'none'; form-action 'none'; frame-ancestors *"
+
+func styleHash() string {
+	sum := sha256.Sum256([]byte(style))
+	return "sha256-" + base64.StdEncoding.EncodeToString(sum[:])
+}
+
+// Chirp holds what the widget shows. Body is plain text.
+type Chirp struct {
+	URL         string
+	AuthorName  string
+	Handle      string
+	AvatarURL   string
+	Body        string
+	PublishedAt time.Time
+}
+
+var page = template.Must(template.New("embed").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="robots" content="noindex">
+<title>{{if .Chirp}}{{.Chirp.AuthorName}} on Chirpy{{else}}Chirpy{{end}}</title>
+<style>{{.Style}}</style>
+</head>
+<body>
+<article class="chirp">
+{{- with .Chirp}}
+<header class="author">
+{{- if .AvatarURL}}<img src="{{.AvatarURL}}" alt="">{{end}}
+<div><div class="name">{{.AuthorName}}</div><div class="handle">@{{.Handle}}</div></div>
+</header>
+<p class="body">{{.Body}}</p>
+<a class="date" href="{{.URL}}" target="_blank" rel="noopener"><time datetime="{{.PublishedAt.UTC.Format "2006-01-02T15:04:05Z07:00"}}">{{.PublishedAt.UTC.Format "Jan 2, 2006"}}</time></a>
+{{- else}}
+<p class="placeholder">This chirp isn't available.</p>
+{{- end}}
+</article>
+</body>
+</html>
+`))
+
+// Page renders the standalone widget, or a placeholder when chirp is nil.
+// The placeholder doesn't tell whether the chirp was deleted, is hidden or
+// never existed.
+func Page(w io.Writer, chirp *Chirp) error {
+	return page.Execute(w, struct {
+		Style template.CSS
+		Chirp *Chirp
+	}{
+		Style: template.CSS(style),
+		Chirp: chirp,
+	})
+}
+
+// Size fits the widget within the maxwidth and maxheight an oEmbed consumer
+// asked for, where 0 means no limit.
+func Size(maxWidth, maxHeight int) (int, int) {
+	width, height := DefaultWidth, DefaultHeight
+
+	if maxWidth > 0 && maxWidth < width {
+		width = max(maxWidth, MinWidth)
+	}
+	if maxHeight > 0 && maxHeight < height {
+		height = max(maxHeight, MinHeight)
+	}
+
+	return width, height
+}
+
+// IFrame is the HTML oEmbed consumers paste into their pages.
+func IFrame(src string, width, height int) string {
+	return fmt.Sprintf(
+		`<iframe src="%s" width="%d" height="%d" style="border:0;max-width:100%%" sandbox="allow-popups allow-popups-to-escape-sandbox" loading="lazy" title="Chirp"></iframe>`,
+		html.EscapeString(src), width, height,
+	)
+}
+
+// OEmbed is a "rich" oEmbed 1.0 response. Author fields are left out for
+// placeholders.
+type OEmbed struct {
+	Version      string `json:"version"`
+	Type         string `json:"type"`
+	ProviderName string `json:"provider_name"`
+	ProviderURL  string `json:"provider_url"`
+	AuthorName   string `json:"author_name,omitempty"`
+	AuthorURL    string `json:"author_url,omitempty"`
+	HTML         string `json:"html"`
+	Width        int    `json:"width"`
+	Height       int    `json:"height"`
+	CacheAge     int    `json:"cache_age"`
+}
@@ -0,0 +1,91 @@
+package widget
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPage(t *testing.T) {
+	var b strings.Builder
+
+	err := Page(&b, &Chirp{
+		URL:         "https://chirpy.example/api/chirps/1",
+		AuthorName:  "Alice",
+		Handle:      "alice",
+		AvatarURL:   "https://cdn.example/alice.png",
+		Body:        `<script>alert("hi")</script>`,
+		PublishedAt: time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC),
+	})
+	if err != nil {
+		t.Fatalf("Page returned an error: %s", err)
+	}
+
+	out := b.String()
+	if strings.Contains(out, "<script>") {
+		t.Errorf("chirp body isn't escaped: %s", out)
+	}
+	if !strings.Contains(out, "@alice") || !strings.Contains(out, "Mar 1, 2026") {
+		t.Errorf("page is missing the author or date: %s", out)
+	}
+	if !strings.Contains(out, "<style>"+style+"</style>") {
+		t.Errorf("style was altered, so its CSP hash won't match")
+	}
+}
+
+func TestPagePlaceholder(t *testing.T) {
+	var b strings.Builder
+
+	if err := Page(&b, nil); err != nil {
+		t.Fatalf("Page returned an error: %s", err)
+	}
+
+	out := b.String()
+	if !strings.Contains(out, "isn't available") {
+		t.Errorf("placeholder page has no placeholder text: %s", out)
+	}
+	if strings.Contains(out, "<img") {
+		t.Errorf("placeholder page shows an avatar: %s", out)
+	}
+}
+
+func TestCSP(t *testing.T) {
+	for _, want := range []string{"default-src 'none'", "'" + styleHash() + "'", "frame-ancestors *"} {
+		if !strings.Contains(CSP, want) {
+			t.Errorf("CSP %q is missing %q", CSP, want)
+		}
+	}
+	if strings.Contains(CSP, "unsafe-inline") || strings.Contains(CSP, "script-src") {
+		t.Errorf("CSP %q allows scripts or inline content", CSP)
+	}
+}
+
+func TestSize(t *testing.T) {
+	cases := []struct {
+		maxWidth, maxHeight int
+		width, height       int
+	}{
+		{0, 0, DefaultWidth, DefaultHeight},
+		{1000, 1000, DefaultWidth, DefaultHeight},
+		{400, 200, 400, 200},
+		{50, 50, MinWidth, MinHeight},
+	}
+
+	for _, c := range cases {
+		width, height := Size(c.maxWidth, c.maxHeight)
+		if width != c.width || height != c.height {
+			t.Errorf("Size(%d, %d) = %d, %d, want %d, %d", c.maxWidth, c.maxHeight, width, height, c.width, c.height)
+		}
+	}
+}
+
+func TestIFrame(t *testing.T) {
+	got := IFrame(`https://chirpy.example/embed/chirps/1?a="b"`, 400, 200)
+
+	if !strings.Contains(got, `src="https://chirpy.example/embed/chirps/1?a=&#34;b&#34;"`) {
+		t.Errorf("src isn't escaped: %s", got)
+	}
+	if !strings.Contains(got, `width="400" height="200"`) {
+		t.Errorf("size is missing: %s", got)
+	}
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"context"
+
+	"github.com/firerockets/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+type linkPreviewResponse struct {
+	URL         string `json:"url"`
+	Title       string `json:"title"`
+	Description string `json:"description"`
+	ImageURL    string `json:"image_url,omitempty"`
+	SiteName    string `json:"site_name,omitempty"`
+}
+
+// loadLinkPreviews returns the preview of the first link of each chirp
+// that has one. Previews are fetched in the background, so a new chirp
+// goes without until its link was fetched.
+func (apiCfg *apiConfig) loadLinkPreviews(ctx context.Context, chirps []database.Chirp) (map[uuid.UUID]*linkPreviewResponse, error) {
+	ids := make([]uuid.UUID, 0, len(chirps))
+
+	for _, c := range chirps {
+		ids = append(ids, c.ID)
+	}
+
+	previews, err := apiCfg.dbQueries.GetLinkPreviewsByChirpIds(ctx, ids)
+
+	if err != nil {
+		return nil, err
+	}
+
+	found := map[uuid.UUID]*linkPreviewResponse{}
+
+	for _, p := range previews {
+		found[p.ChirpID] = &linkPreviewResponse{
+			URL:         p.Url,
+			Title:       p.Title,
+			Description: p.Description,
+			ImageURL:    p.ImageUrl,
+			SiteName:    p.SiteName,
+		}
+	}
+
+	return found, nil
+}
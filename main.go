@@ -1,29 +1,56 @@
 package main
 
 import (
+	"context"
 	"database/sql"
+	"errors"
 	"log"
 	"net/http"
 	"os"
+	"os/signal"
+	"strings"
 	"sync/atomic"
+	"syscall"
+	"time"
 
+	"github.com/firerockets/chirpy/internal/activitypub"
+	"github.com/firerockets/chirpy/internal/analytics"
 	"github.com/firerockets/chirpy/internal/database"
+	"github.com/firerockets/chirpy/internal/entitlements"
+	"github.com/firerockets/chirpy/internal/filter"
+	"github.com/firerockets/chirpy/internal/media"
+	"github.com/firerockets/chirpy/internal/realtime"
+	"github.com/firerockets/chirpy/internal/scheduler"
+	"github.com/firerockets/chirpy/internal/stream"
+	"github.com/firerockets/chirpy/internal/trending"
+	"github.com/firerockets/chirpy/internal/unfurl"
 	"github.com/joho/godotenv"
-	_ "github.com/lib/pq"
+	"github.com/lib/pq"
 )
 
 const (
-	filepathRoot   = "./"
-	filepathAssets = "./assets"
-	port           = "8080"
+	filepathRoot    = "./"
+	filepathAssets  = "./assets"
+	port            = "8080"
+	shutdownTimeout = 10 * time.Second
 )
 
 type apiConfig struct {
-	fileserverHits atomic.Int32
-	dbQueries      *database.Queries
-	platform       string
-	secret         string
-	polkaKey       string
+	fileserverHits    atomic.Int32
+	db                *sql.DB
+	dbQueries         *database.Queries
+	platform          string
+	secret            string
+	polkaKey          string
+	contentFilter     *filter.Filter
+	contentFilterFile string
+	blobStore         media.BlobStore
+	streamHub         *stream.Hub
+	realtimeHub       *realtime.Hub
+	baseURL           string
+	apURLs            activitypub.URLs
+	apClient          *activitypub.Client
+	impressions       *analytics.Impressions
 }
 
 func (cfg *apiConfig) middlewareMetricsInc(next http.Handler) http.Handler {
@@ -39,6 +66,8 @@ func main() {
 	platform := os.Getenv("PLATFORM")
 	secret := os.Getenv("SECRET")
 	polkaKey := os.Getenv("POLKA_KEY")
+	contentFilterFile := os.Getenv("CONTENT_FILTER_FILE")
+	baseURL := strings.TrimSuffix(os.Getenv("BASE_URL"), "/")
 
 	db, err := sql.Open("postgres", dbURL)
 
@@ -49,37 +78,236 @@ func main() {
 	dbQueries := database.New(db)
 
 	apiCfg := apiConfig{
-		fileserverHits: atomic.Int32{},
-		dbQueries:      dbQueries,
-		platform:       platform,
-		secret:         secret,
-		polkaKey:       polkaKey,
+		fileserverHits:    atomic.Int32{},
+		db:                db,
+		dbQueries:         dbQueries,
+		platform:          platform,
+		secret:            secret,
+		polkaKey:          polkaKey,
+		contentFilter:     filter.New(nil),
+		contentFilterFile: contentFilterFile,
+		blobStore:         newBlobStore(),
+		streamHub:         stream.NewHub(dbQueries),
+		realtimeHub:       realtime.NewHub(),
+		baseURL:           baseURL,
+		apURLs:            activitypub.URLs{Base: baseURL},
+		apClient:          activitypub.NewClient(),
+		impressions:       analytics.NewImpressions(dbQueries, 10*time.Second),
 	}
 
+	err = apiCfg.reloadContentFilter(context.Background())
+
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	trendingAggregator := trending.NewAggregator(db, dbQueries, time.Minute)
+	go trendingAggregator.Run(ctx)
+
+	publisher := scheduler.NewPublisher(publishingStore{&apiCfg}, 10*time.Second)
+	go publisher.Run(ctx)
+
+	purger := scheduler.NewPurger(dbQueries, chirpRetentionDays, time.Hour)
+	go purger.Run(ctx)
+
+	go apiCfg.impressions.Run(ctx)
+
+	expirer := entitlements.NewExpirer(dbQueries, time.Minute)
+	go expirer.Run(ctx)
+
+	analyticsRollup := analytics.NewRollup(db, dbQueries, 5*time.Minute)
+	go analyticsRollup.Run(ctx)
+
+	unfurler := unfurl.NewWorker(dbQueries, unfurl.NewFetcher(), 10*time.Second)
+	go unfurler.Run(ctx)
+
+	if apiCfg.federating() {
+		deliverer := activitypub.NewDeliverer(dbQueries, apiCfg.apClient, apiCfg.apURLs, 10*time.Second)
+		go deliverer.Run(ctx)
+	}
+
+	listener := pq.NewListener(dbURL, 10*time.Second, time.Minute, func(event pq.ListenerEventType, err error) {
+		if err != nil {
+			log.Printf("Error listening for database notifications: %s\n", err)
+		}
+	})
+	defer listener.Close()
+
+	for _, channel := range []string{stream.Channel, realtime.Channel, filter.Channel} {
+		err = listener.Listen(channel)
+
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	chirpNotifications := make(chan *pq.Notification)
+	realtimeNotifications := make(chan *pq.Notification)
+	filterNotifications := make(chan *pq.Notification)
+
+	go routeNotifications(ctx, listener.Notify, map[string]chan<- *pq.Notification{
+		stream.Channel:   chirpNotifications,
+		realtime.Channel: realtimeNotifications,
+		filter.Channel:   filterNotifications,
+	})
+	go apiCfg.streamHub.Run(ctx, chirpNotifications)
+	go apiCfg.realtimeHub.Run(ctx, realtimeNotifications)
+	go apiCfg.watchContentFilter(ctx, filterNotifications)
+
 	mux := http.NewServeMux()
 
 	mux.HandleFunc("GET /app/", apiCfg.appHandler)
+	mux.Handle("GET "+localMediaURLPath+"/", localMediaHandler())
 	mux.HandleFunc("GET /api/healthz", apiCfg.healthzHandler)
 	mux.HandleFunc("GET /api/chirps", apiCfg.getChirpsHandler)
+	mux.HandleFunc("GET /api/stream", apiCfg.streamHandler)
+	mux.HandleFunc("GET /api/ws", apiCfg.wsHandler)
 	mux.HandleFunc("GET /api/chirps/{chirpID}", apiCfg.getChirpByIdHandler)
 	mux.HandleFunc("DELETE /api/chirps/{chirpID}", apiCfg.deleteChirpByIdHandler)
 	mux.HandleFunc("POST /api/chirps", apiCfg.createChirpHandler)
+	mux.HandleFunc("GET /api/chirps/scheduled", apiCfg.getScheduledChirpsHandler)
+	mux.HandleFunc("PUT /api/chirps/scheduled/{chirpID}", apiCfg.updateScheduledChirpHandler)
+	mux.HandleFunc("DELETE /api/chirps/scheduled/{chirpID}", apiCfg.deleteScheduledChirpHandler)
+	mux.HandleFunc("POST /api/media", apiCfg.uploadMediaHandler)
+	mux.HandleFunc("POST /api/chirps/{chirpID}/poll/votes", apiCfg.createPollVoteHandler)
+	mux.HandleFunc("POST /api/chirps/{chirpID}/pin", apiCfg.pinChirpHandler)
+	mux.HandleFunc("DELETE /api/chirps/{chirpID}/pin", apiCfg.unpinChirpHandler)
+	mux.HandleFunc("POST /api/chirps/{chirpID}/reports", apiCfg.reportChirpHandler)
+	mux.HandleFunc("POST /api/chirps/{chirpID}/bookmark", apiCfg.createBookmarkHandler)
+	mux.HandleFunc("DELETE /api/chirps/{chirpID}/bookmark", apiCfg.deleteBookmarkHandler)
+	mux.HandleFunc("GET /api/bookmarks", apiCfg.getBookmarksHandler)
+	mux.HandleFunc("GET /api/collections", apiCfg.getCollectionsHandler)
+	mux.HandleFunc("POST /api/collections", apiCfg.createCollectionHandler)
+	mux.HandleFunc("DELETE /api/collections/{collectionID}", apiCfg.deleteCollectionHandler)
+	mux.HandleFunc("GET /api/collections/{collectionID}/chirps", apiCfg.getCollectionChirpsHandler)
+	mux.HandleFunc("POST /api/collections/{collectionID}/chirps", apiCfg.addCollectionChirpHandler)
+	mux.HandleFunc("DELETE /api/collections/{collectionID}/chirps/{chirpID}", apiCfg.removeCollectionChirpHandler)
+	mux.HandleFunc("GET /api/hashtags/{tag}/chirps", apiCfg.getHashtagChirpsHandler)
+	mux.HandleFunc("GET /api/trending", apiCfg.getTrendingHandler)
+	mux.HandleFunc("GET /api/analytics", apiCfg.getAnalyticsHandler)
+	mux.HandleFunc("GET /api/search/users", apiCfg.searchUsersHandler)
 	mux.HandleFunc("POST /api/users", apiCfg.createUserHandler)
 	mux.HandleFunc("PUT /api/users", apiCfg.updateUserHandler)
+	mux.HandleFunc("PUT /api/users/profile", apiCfg.updateProfileHandler)
+	mux.HandleFunc("PUT /api/users/avatar", apiCfg.uploadAvatarHandler)
+	mux.HandleFunc("GET /api/users/{handle}", apiCfg.getUserHandler)
+	mux.HandleFunc("POST /api/users/{handle}/block", apiCfg.blockUserHandler)
+	mux.HandleFunc("DELETE /api/users/{handle}/block", apiCfg.unblockUserHandler)
+	mux.HandleFunc("POST /api/users/{handle}/mute", apiCfg.muteUserHandler)
+	mux.HandleFunc("DELETE /api/users/{handle}/mute", apiCfg.unmuteUserHandler)
+	mux.HandleFunc("POST /api/users/{handle}/reports", apiCfg.reportUserHandler)
+	mux.HandleFunc("GET /api/warnings", apiCfg.getWarningsHandler)
+	mux.HandleFunc("GET /api/notifications", apiCfg.getNotificationsHandler)
+	mux.HandleFunc("POST /api/notifications/read", apiCfg.markAllNotificationsReadHandler)
+	mux.HandleFunc("POST /api/notifications/{notificationID}/read", apiCfg.markNotificationReadHandler)
+	mux.HandleFunc("GET /api/conversations", apiCfg.getConversationsHandler)
+	mux.HandleFunc("POST /api/conversations", apiCfg.createConversationHandler)
+	mux.HandleFunc("GET /api/conversations/{conversationID}/messages", apiCfg.getMessagesHandler)
+	mux.HandleFunc("POST /api/conversations/{conversationID}/messages", apiCfg.createMessageHandler)
+	mux.HandleFunc("POST /api/conversations/{conversationID}/read", apiCfg.markConversationReadHandler)
+	mux.HandleFunc("GET /api/blocks", apiCfg.getBlocksHandler)
+	mux.HandleFunc("GET /api/mutes", apiCfg.getMutesHandler)
 	mux.HandleFunc("POST /api/login", apiCfg.loginHandler)
 	mux.HandleFunc("POST /api/refresh", apiCfg.refreshTokenHandler)
 	mux.HandleFunc("POST /api/revoke", apiCfg.revokeRefreshTokenHandler)
 	mux.HandleFunc("POST /api/polka/webhooks", apiCfg.webhookHandler)
 	mux.HandleFunc("GET /admin/metrics", apiCfg.metricsHandler)
+	mux.HandleFunc("GET /c/{chirpID}", apiCfg.chirpPageHandler)
+	mux.HandleFunc("GET /u/{handle}", apiCfg.userPageHandler)
+	mux.HandleFunc("GET /oembed", apiCfg.oembedHandler)
+	mux.HandleFunc("GET /embed/chirps/{chirpID}", apiCfg.embedChirpHandler)
+	mux.HandleFunc("GET /users/{handle}/{format}", apiCfg.userFeedHandler)
+	mux.HandleFunc("GET /hashtags/{tag}/{format}", apiCfg.hashtagFeedHandler)
+	mux.HandleFunc("GET /.well-known/webfinger", apiCfg.webfingerHandler)
+	mux.HandleFunc("GET /ap/users/{userID}", apiCfg.actorHandler)
+	mux.HandleFunc("GET /ap/users/{userID}/outbox", apiCfg.outboxHandler)
+	mux.HandleFunc("GET /ap/users/{userID}/followers", apiCfg.followersHandler)
+	mux.HandleFunc("POST /ap/users/{userID}/inbox", apiCfg.inboxHandler)
+	mux.HandleFunc("GET /ap/chirps/{chirpID}", apiCfg.noteHandler)
 	mux.HandleFunc("POST /admin/reset", apiCfg.resetHandler)
+	mux.HandleFunc("GET /admin/hashtags/denylist", apiCfg.getHashtagDenylistHandler)
+	mux.HandleFunc("POST /admin/hashtags/denylist", apiCfg.denylistHashtagHandler)
+	mux.HandleFunc("DELETE /admin/hashtags/denylist/{tag}", apiCfg.removeDenylistedHashtagHandler)
+	mux.HandleFunc("GET /admin/filter/rules", apiCfg.getFilterRulesHandler)
+	mux.HandleFunc("PUT /admin/filter/rules", apiCfg.upsertFilterRuleHandler)
+	mux.HandleFunc("DELETE /admin/filter/rules/{word}", apiCfg.deleteFilterRuleHandler)
+	mux.HandleFunc("GET /admin/filter/flags", apiCfg.getChirpFlagsHandler)
+	mux.HandleFunc("POST /admin/chirps/{chirpID}/restore", apiCfg.restoreChirpHandler)
+	mux.HandleFunc("GET /admin/reports", apiCfg.getReportsHandler)
+	mux.HandleFunc("GET /admin/reports/{caseID}", apiCfg.getReportHandler)
+	mux.HandleFunc("POST /admin/reports/{caseID}/actions", apiCfg.moderateReportHandler)
 
 	server := &http.Server{
 		Handler: mux,
 		Addr:    ":" + port,
 	}
 
-	log.Printf("Serving files from %s on port: %s\n", filepathRoot, port)
-	log.Fatal(server.ListenAndServe())
+	// Open streams and websockets never finish on their own, so end them
+	// when shutting down instead of waiting out the timeout. Shutdown
+	// doesn't track websockets at all once they're hijacked.
+	server.RegisterOnShutdown(apiCfg.streamHub.Close)
+	server.RegisterOnShutdown(apiCfg.realtimeHub.Close)
+
+	go func() {
+		log.Printf("Serving files from %s on port: %s\n", filepathRoot, port)
+		err := server.ListenAndServe()
+		if err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Fatal(err)
+		}
+	}()
+
+	<-ctx.Done()
+	log.Println("Shutting down")
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+
+	err = server.Shutdown(shutdownCtx)
+
+	if err != nil {
+		log.Printf("Error shutting down: %s\n", err)
+	}
+
+	// Requests are done adding impressions now, so write out the rest.
+	err = apiCfg.impressions.Flush(shutdownCtx)
+
+	if err != nil {
+		log.Printf("Error flushing chirp impressions: %s\n", err)
+	}
+}
+
+// routeNotifications hands each database notification to the hub listening
+// on its channel. The nil sent after the listener reconnects goes to all of
+// them.
+func routeNotifications(ctx context.Context, notifications <-chan *pq.Notification, routes map[string]chan<- *pq.Notification) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case n, ok := <-notifications:
+			if !ok {
+				for _, route := range routes {
+					close(route)
+				}
+				return
+			}
+
+			for channel, route := range routes {
+				if n != nil && n.Channel != channel {
+					continue
+				}
+
+				select {
+				case route <- n:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}
 }
 
 func (apiCfg *apiConfig) appHandler(w http.ResponseWriter, req *http.Request) {
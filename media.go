@@ -0,0 +1,214 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/firerockets/chirpy/internal/auth"
+	"github.com/firerockets/chirpy/internal/database"
+	"github.com/firerockets/chirpy/internal/media"
+	"github.com/google/uuid"
+)
+
+const (
+	maxMediaSize      = 5 << 20
+	maxAltTextLength  = 1000
+	maxAttachments    = 4
+	localMediaURLPath = "/assets/media"
+)
+
+type mediaResponse struct {
+	ID           string `json:"id"`
+	URL          string `json:"url"`
+	ThumbnailURL string `json:"thumbnail_url"`
+	ContentType  string `json:"content_type"`
+	Width        int32  `json:"width"`
+	Height       int32  `json:"height"`
+	AltText      string `json:"alt_text"`
+}
+
+// newBlobStore picks the media storage from MEDIA_STORE. Anything but "s3"
+// stores files under filepathAssets.
+func newBlobStore() media.BlobStore {
+	if os.Getenv("MEDIA_STORE") == "s3" {
+		return &media.S3Store{
+			Endpoint:  os.Getenv("S3_ENDPOINT"),
+			Bucket:    os.Getenv("S3_BUCKET"),
+			Region:    os.Getenv("S3_REGION"),
+			AccessKey: os.Getenv("S3_ACCESS_KEY"),
+			SecretKey: os.Getenv("S3_SECRET_KEY"),
+			PublicURL: os.Getenv("S3_PUBLIC_URL"),
+		}
+	}
+
+	return media.NewLocalStore(filepath.Join(filepathAssets, "media"), localMediaURLPath)
+}
+
+// localMediaHandler serves locally stored media without listing directories.
+func localMediaHandler() http.Handler {
+	fileServer := http.FileServer(http.Dir(filepath.Join(filepathAssets, "media")))
+
+	return http.StripPrefix(localMediaURLPath+"/", http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.URL.Path == "" || strings.HasSuffix(req.URL.Path, "/") {
+			http.NotFound(w, req)
+			return
+		}
+		fileServer.ServeHTTP(w, req)
+	}))
+}
+
+func (apiCfg *apiConfig) uploadMediaHandler(w http.ResponseWriter, req *http.Request) {
+	token, err := auth.GetBearerToken(req.Header)
+
+	if err != nil {
+		respondWithError(w, "Unauthorized", http.StatusUnauthorized)
+		log.Printf("Error loading token from header: %s\n", err)
+		return
+	}
+
+	usrID, err := auth.ValidadeJWT(token, apiCfg.secret)
+
+	if err != nil {
+		respondWithError(w, "Unauthorized", http.StatusUnauthorized)
+		log.Printf("Error validating token: %s\n", err)
+		return
+	}
+
+	usr, err := apiCfg.dbQueries.GetUserById(req.Context(), usrID)
+
+	if err != nil {
+		respondWithError(w, "Unauthorized", http.StatusUnauthorized)
+		log.Printf("Error looking up user: %s\n", err)
+		return
+	}
+
+	if usr.SuspendedAt.Valid {
+		respondWithError(w, "This account is suspended", http.StatusForbidden)
+		return
+	}
+
+	req.Body = http.MaxBytesReader(w, req.Body, maxMediaSize+1<<20)
+
+	file, _, err := req.FormFile("file")
+
+	if err != nil {
+		respondWithError(w, "A file is required", http.StatusBadRequest)
+		log.Printf("Error reading multipart file: %s\n", err)
+		return
+	}
+
+	defer file.Close()
+
+	data, err := io.ReadAll(io.LimitReader(file, maxMediaSize+1))
+
+	if err != nil {
+		respondWithError(w, "Something went wrong while reading the file", http.StatusBadRequest)
+		log.Printf("Error reading uploaded file: %s\n", err)
+		return
+	}
+
+	if len(data) > maxMediaSize {
+		respondWithError(w, "File is too large", http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	altText := strings.TrimSpace(req.FormValue("alt_text"))
+
+	if utf8.RuneCountInString(altText) > maxAltTextLength {
+		respondWithError(w, "Alt text is too long", http.StatusBadRequest)
+		return
+	}
+
+	processed, err := media.ProcessImage(data)
+
+	if errors.Is(err, media.ErrUnsupportedType) {
+		respondWithError(w, "Only JPEG, PNG and GIF images are supported", http.StatusUnsupportedMediaType)
+		return
+	} else if err != nil {
+		respondWithError(w, "Invalid image", http.StatusBadRequest)
+		log.Printf("Error processing image: %s\n", err)
+		return
+	}
+
+	mediaID := uuid.New()
+	storageKey := mediaID.String() + "/original" + processed.Extension
+	thumbnailKey := mediaID.String() + "/thumbnail" + processed.ThumbnailExtension
+
+	err = apiCfg.blobStore.Put(req.Context(), storageKey, processed.ContentType, processed.Data)
+
+	if err == nil {
+		err = apiCfg.blobStore.Put(req.Context(), thumbnailKey, processed.ThumbnailContentType, processed.Thumbnail)
+	}
+
+	if err != nil {
+		respondWithError(w, "Something went wrong while storing the file", http.StatusInternalServerError)
+		log.Printf("Error storing media: %s\n", err)
+		return
+	}
+
+	medium, err := apiCfg.dbQueries.CreateMedia(req.Context(), database.CreateMediaParams{
+		ID:           mediaID,
+		UserID:       usrID,
+		StorageKey:   storageKey,
+		ThumbnailKey: thumbnailKey,
+		ContentType:  processed.ContentType,
+		Width:        int32(processed.Width),
+		Height:       int32(processed.Height),
+		AltText:      altText,
+	})
+
+	if err != nil {
+		respondWithError(w, "Something went wrong while storing the file", http.StatusInternalServerError)
+		log.Printf("Error inserting media into the database: %s\n", err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusCreated, mediaResponse{
+		ID:           medium.ID.String(),
+		URL:          apiCfg.blobStore.URL(medium.StorageKey),
+		ThumbnailURL: apiCfg.blobStore.URL(medium.ThumbnailKey),
+		ContentType:  medium.ContentType,
+		Width:        medium.Width,
+		Height:       medium.Height,
+		AltText:      medium.AltText,
+	})
+}
+
+// loadChirpMedia fetches the attachments of all chirps at once, grouped by
+// chirp in their attached order.
+func (apiCfg *apiConfig) loadChirpMedia(ctx context.Context, chirps []database.Chirp) (map[uuid.UUID][]mediaResponse, error) {
+	ids := make([]uuid.UUID, 0, len(chirps))
+
+	for _, c := range chirps {
+		ids = append(ids, c.ID)
+	}
+
+	rows, err := apiCfg.dbQueries.GetChirpMediaByChirpIds(ctx, ids)
+
+	if err != nil {
+		return nil, err
+	}
+
+	found := map[uuid.UUID][]mediaResponse{}
+
+	for _, r := range rows {
+		found[r.ChirpID] = append(found[r.ChirpID], mediaResponse{
+			ID:           r.ID.String(),
+			URL:          apiCfg.blobStore.URL(r.StorageKey),
+			ThumbnailURL: apiCfg.blobStore.URL(r.ThumbnailKey),
+			ContentType:  r.ContentType,
+			Width:        r.Width,
+			Height:       r.Height,
+			AltText:      r.AltText,
+		})
+	}
+
+	return found, nil
+}
@@ -0,0 +1,690 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/firerockets/chirpy/internal/conversations"
+	"github.com/firerockets/chirpy/internal/cursor"
+	"github.com/firerockets/chirpy/internal/database"
+	"github.com/firerockets/chirpy/internal/realtime"
+	"github.com/firerockets/chirpy/internal/visibility"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+type conversationResponse struct {
+	ID          string           `json:"id"`
+	CreatedAt   time.Time        `json:"created_at"`
+	UpdatedAt   time.Time        `json:"updated_at"`
+	Direct      bool             `json:"direct"`
+	Members     []authorResponse `json:"members"`
+	LastMessage *messageResponse `json:"last_message,omitempty"`
+	UnreadCount int64            `json:"unread_count"`
+}
+
+type messageResponse struct {
+	ID             string    `json:"id"`
+	CreatedAt      time.Time `json:"created_at"`
+	ConversationID string    `json:"conversation_id"`
+	SenderID       string    `json:"sender_id"`
+	Body           string    `json:"body"`
+}
+
+func newMessageResponse(m database.Message) messageResponse {
+	return messageResponse{
+		ID:             m.ID.String(),
+		CreatedAt:      m.CreatedAt,
+		ConversationID: m.ConversationID.String(),
+		SenderID:       m.SenderID.String(),
+		Body:           m.Body,
+	}
+}
+
+// messagingUser loads the authenticated user and writes a 403 when they're
+// suspended.
+func (apiCfg *apiConfig) messagingUser(w http.ResponseWriter, req *http.Request) (database.User, bool) {
+	usrID, ok := apiCfg.authenticateUser(w, req)
+
+	if !ok {
+		return database.User{}, false
+	}
+
+	usr, err := apiCfg.dbQueries.GetUserById(req.Context(), usrID)
+
+	if err != nil {
+		respondWithError(w, "Unauthorized", http.StatusUnauthorized)
+		log.Printf("Error looking up user: %s\n", err)
+		return database.User{}, false
+	}
+
+	if usr.SuspendedAt.Valid {
+		respondWithError(w, "This account is suspended", http.StatusForbidden)
+		return database.User{}, false
+	}
+
+	return usr, true
+}
+
+// createConversationHandler starts a conversation with the given users,
+// optionally with a first message. Starting a 1:1 conversation that already
+// exists returns it instead of creating another one.
+func (apiCfg *apiConfig) createConversationHandler(w http.ResponseWriter, req *http.Request) {
+	usr, ok := apiCfg.messagingUser(w, req)
+
+	if !ok {
+		return
+	}
+
+	type parameters struct {
+		Handles []string `json:"handles"`
+		Body    string   `json:"body"`
+	}
+
+	var params parameters
+
+	decoder := json.NewDecoder(req.Body)
+	err := decoder.Decode(&params)
+
+	if err != nil {
+		respondWithError(w, "Something went wrong while parsing the request body", http.StatusBadRequest)
+		log.Printf("Error decoding json request: %s\n", err)
+		return
+	}
+
+	var body string
+
+	if params.Body != "" {
+		body, err = conversations.NormalizeMessage(params.Body)
+
+		if err != nil {
+			respondWithError(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	if len(params.Handles) == 0 {
+		respondWithError(w, "A conversation needs at least one other user", http.StatusBadRequest)
+		return
+	}
+
+	rel, err := apiCfg.relations(req.Context(), uuid.NullUUID{UUID: usr.ID, Valid: true})
+
+	if err != nil {
+		respondWithError(w, "Something went wrong", http.StatusInternalServerError)
+		log.Printf("Error loading blocks and mutes: %s\n", err)
+		return
+	}
+
+	memberIDs := []uuid.UUID{usr.ID}
+	seen := map[uuid.UUID]bool{usr.ID: true}
+
+	for _, handle := range params.Handles {
+		member, err := apiCfg.lookupUser(req.Context(), handle)
+
+		if errors.Is(err, sql.ErrNoRows) || (err == nil && member.SuspendedAt.Valid) {
+			respondWithError(w, "User not found", http.StatusNotFound)
+			return
+		} else if err != nil {
+			respondWithError(w, "Something went wrong", http.StatusInternalServerError)
+			log.Printf("Error looking up user: %s\n", err)
+			return
+		}
+
+		if rel.Blocked(member.ID) {
+			respondWithError(w, "You can't message this user", http.StatusForbidden)
+			return
+		}
+
+		if !seen[member.ID] {
+			seen[member.ID] = true
+			memberIDs = append(memberIDs, member.ID)
+		}
+	}
+
+	if len(memberIDs) < 2 {
+		respondWithError(w, "A conversation needs at least one other user", http.StatusBadRequest)
+		return
+	}
+
+	if len(memberIDs) > conversations.MaxMembers {
+		respondWithError(w, "Conversations can have up to 8 members", http.StatusBadRequest)
+		return
+	}
+
+	conversation, created, err := apiCfg.startConversation(req.Context(), memberIDs)
+
+	if err != nil {
+		respondWithError(w, "Error creating conversation", http.StatusInternalServerError)
+		log.Printf("Error creating conversation: %s\n", err)
+		return
+	}
+
+	if body != "" {
+		_, err = apiCfg.sendMessage(req.Context(), conversation.ID, usr.ID, body)
+
+		if err != nil {
+			respondWithError(w, "Error sending message", http.StatusInternalServerError)
+			log.Printf("Error sending message: %s\n", err)
+			return
+		}
+	}
+
+	response, ok := apiCfg.conversationResponse(w, req, conversation.ID, usr.ID)
+
+	if !ok {
+		return
+	}
+
+	if created {
+		respondWithJSON(w, http.StatusCreated, response)
+	} else {
+		respondWithJSON(w, http.StatusOK, response)
+	}
+}
+
+// startConversation creates a conversation between memberIDs, the first of
+// them being the one starting it. It returns false along with the existing
+// conversation when the two users of a 1:1 conversation already have one.
+func (apiCfg *apiConfig) startConversation(ctx context.Context, memberIDs []uuid.UUID) (database.Conversation, bool, error) {
+	var directKey sql.NullString
+
+	if len(memberIDs) == 2 {
+		directKey = sql.NullString{String: conversations.DirectKey(memberIDs[0], memberIDs[1]), Valid: true}
+
+		conversation, err := apiCfg.dbQueries.GetConversationByDirectKey(ctx, directKey)
+		if err == nil {
+			return conversation, false, nil
+		} else if !errors.Is(err, sql.ErrNoRows) {
+			return database.Conversation{}, false, err
+		}
+	}
+
+	tx, err := apiCfg.db.BeginTx(ctx, nil)
+	if err != nil {
+		return database.Conversation{}, false, err
+	}
+	defer tx.Rollback()
+
+	qtx := apiCfg.dbQueries.WithTx(tx)
+
+	conversation, err := qtx.CreateConversation(ctx, directKey)
+
+	// Both users started it at the same time, so use the one that won.
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == "23505" {
+		conversation, err = apiCfg.dbQueries.GetConversationByDirectKey(ctx, directKey)
+		return conversation, false, err
+	} else if err != nil {
+		return database.Conversation{}, false, err
+	}
+
+	for _, memberID := range memberIDs {
+		err = qtx.AddConversationMember(ctx, database.AddConversationMemberParams{
+			ConversationID: conversation.ID,
+			UserID:         memberID,
+		})
+		if err != nil {
+			return database.Conversation{}, false, err
+		}
+	}
+
+	return conversation, true, tx.Commit()
+}
+
+// sendMessage adds a message to a conversation, marks it read for its
+// sender and tells the other members about it.
+func (apiCfg *apiConfig) sendMessage(ctx context.Context, conversationID, senderID uuid.UUID, body string) (database.Message, error) {
+	tx, err := apiCfg.db.BeginTx(ctx, nil)
+	if err != nil {
+		return database.Message{}, err
+	}
+	defer tx.Rollback()
+
+	qtx := apiCfg.dbQueries.WithTx(tx)
+
+	message, err := qtx.CreateMessage(ctx, database.CreateMessageParams{
+		ConversationID: conversationID,
+		SenderID:       senderID,
+		Body:           body,
+	})
+	if err != nil {
+		return database.Message{}, err
+	}
+
+	err = qtx.TouchConversation(ctx, conversationID)
+	if err != nil {
+		return database.Message{}, err
+	}
+
+	err = qtx.MarkConversationRead(ctx, database.MarkConversationReadParams{
+		ConversationID: conversationID,
+		UserID:         senderID,
+	})
+	if err != nil {
+		return database.Message{}, err
+	}
+
+	err = publishToMembers(ctx, qtx, conversationID, senderID, realtime.Event{
+		Type:           realtime.MessageEvent,
+		ActorID:        uuid.NullUUID{UUID: senderID, Valid: true},
+		ConversationID: uuid.NullUUID{UUID: conversationID, Valid: true},
+		MessageID:      uuid.NullUUID{UUID: message.ID, Valid: true},
+	})
+	if err != nil {
+		return database.Message{}, err
+	}
+
+	return message, tx.Commit()
+}
+
+// publishToMembers sends an event to every member of a conversation but
+// the one behind it.
+func publishToMembers(ctx context.Context, q *database.Queries, conversationID, actorID uuid.UUID, e realtime.Event) error {
+	members, err := q.GetConversationMembers(ctx, []uuid.UUID{conversationID})
+	if err != nil {
+		return err
+	}
+
+	for _, m := range members {
+		if m.UserID == actorID {
+			continue
+		}
+
+		e.UserID = uuid.NullUUID{UUID: m.UserID, Valid: true}
+
+		if err := publishRealtime(ctx, q, e); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// memberConversation loads the conversation in the path and writes a 404
+// when usrID isn't part of it, so other conversations can't be detected.
+func (apiCfg *apiConfig) memberConversation(w http.ResponseWriter, req *http.Request, usrID uuid.UUID) (database.Conversation, bool) {
+	conversationID, err := uuid.Parse(req.PathValue("conversationID"))
+
+	if err != nil {
+		respondWithError(w, "Invalid ID", http.StatusBadRequest)
+		log.Printf("Error validating UUID: %s\n", err)
+		return database.Conversation{}, false
+	}
+
+	conversation, err := apiCfg.dbQueries.GetMemberConversation(req.Context(), database.GetMemberConversationParams{
+		ID:     conversationID,
+		UserID: usrID,
+	})
+
+	if errors.Is(err, sql.ErrNoRows) {
+		respondWithError(w, "Conversation not found", http.StatusNotFound)
+		return database.Conversation{}, false
+	} else if err != nil {
+		respondWithError(w, "Something went wrong", http.StatusInternalServerError)
+		log.Printf("Error fetching conversation: %s\n", err)
+		return database.Conversation{}, false
+	}
+
+	return conversation, true
+}
+
+func (apiCfg *apiConfig) createMessageHandler(w http.ResponseWriter, req *http.Request) {
+	usr, ok := apiCfg.messagingUser(w, req)
+
+	if !ok {
+		return
+	}
+
+	conversation, ok := apiCfg.memberConversation(w, req, usr.ID)
+
+	if !ok {
+		return
+	}
+
+	type parameters struct {
+		Body string `json:"body"`
+	}
+
+	var params parameters
+
+	decoder := json.NewDecoder(req.Body)
+	err := decoder.Decode(&params)
+
+	if err != nil {
+		respondWithError(w, "Something went wrong while parsing the request body", http.StatusBadRequest)
+		log.Printf("Error decoding json request: %s\n", err)
+		return
+	}
+
+	body, err := conversations.NormalizeMessage(params.Body)
+
+	if err != nil {
+		respondWithError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// Blocks are checked on every message, since members may have blocked
+	// each other after the conversation started.
+	blocked, err := apiCfg.dbQueries.IsBlockedInConversation(req.Context(), database.IsBlockedInConversationParams{
+		UserID:         usr.ID,
+		ConversationID: conversation.ID,
+	})
+
+	if err != nil {
+		respondWithError(w, "Something went wrong", http.StatusInternalServerError)
+		log.Printf("Error checking blocks: %s\n", err)
+		return
+	}
+
+	if blocked {
+		respondWithError(w, "You can't message this conversation", http.StatusForbidden)
+		return
+	}
+
+	message, err := apiCfg.sendMessage(req.Context(), conversation.ID, usr.ID, body)
+
+	if err != nil {
+		respondWithError(w, "Error sending message", http.StatusInternalServerError)
+		log.Printf("Error sending message: %s\n", err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusCreated, newMessageResponse(message))
+}
+
+func (apiCfg *apiConfig) getConversationsHandler(w http.ResponseWriter, req *http.Request) {
+	usrID, ok := apiCfg.authenticateUser(w, req)
+
+	if !ok {
+		return
+	}
+
+	limit, err := parseLimit(req)
+
+	if err != nil {
+		respondWithError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	rel, err := apiCfg.relations(req.Context(), uuid.NullUUID{UUID: usrID, Valid: true})
+
+	if err != nil {
+		respondWithError(w, "Something went wrong", http.StatusInternalServerError)
+		log.Printf("Error loading blocks and mutes: %s\n", err)
+		return
+	}
+
+	params := database.GetConversationsParams{
+		UserID:          usrID,
+		ExcludedUserIds: rel.Excluded(visibility.Direct),
+		Limit:           limit,
+	}
+
+	if value := req.URL.Query().Get("cursor"); value != "" {
+		before, err := cursor.Parse(value)
+
+		if err != nil {
+			respondWithError(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		params.BeforeUpdatedAt = sql.NullTime{Time: before.At, Valid: true}
+		params.BeforeID = uuid.NullUUID{UUID: before.ID, Valid: true}
+	}
+
+	found, err := apiCfg.dbQueries.GetConversations(req.Context(), params)
+
+	if err != nil {
+		respondWithError(w, "Something went wrong", http.StatusInternalServerError)
+		log.Printf("Error fetching conversations: %s\n", err)
+		return
+	}
+
+	conversationsResponse, err := apiCfg.conversationResponses(req.Context(), found, rel)
+
+	if err != nil {
+		respondWithError(w, "Something went wrong", http.StatusInternalServerError)
+		log.Printf("Error building conversations response: %s\n", err)
+		return
+	}
+
+	type pageResponse struct {
+		Conversations []conversationResponse `json:"conversations"`
+		NextCursor    string                 `json:"next_cursor,omitempty"`
+	}
+
+	response := pageResponse{
+		Conversations: conversationsResponse,
+	}
+
+	if len(found) == int(limit) {
+		last := found[len(found)-1]
+		response.NextCursor = cursor.Cursor{At: last.UpdatedAt, ID: last.ID}.String()
+	}
+
+	respondWithJSON(w, http.StatusOK, response)
+}
+
+// conversationResponse builds the response for a single conversation of
+// usrID, writing the error response itself on failure.
+func (apiCfg *apiConfig) conversationResponse(w http.ResponseWriter, req *http.Request, conversationID, usrID uuid.UUID) (conversationResponse, bool) {
+	rel, err := apiCfg.relations(req.Context(), uuid.NullUUID{UUID: usrID, Valid: true})
+
+	if err != nil {
+		respondWithError(w, "Something went wrong", http.StatusInternalServerError)
+		log.Printf("Error loading blocks and mutes: %s\n", err)
+		return conversationResponse{}, false
+	}
+
+	found, err := apiCfg.dbQueries.GetConversation(req.Context(), database.GetConversationParams{
+		UserID:          usrID,
+		ExcludedUserIds: rel.Excluded(visibility.Direct),
+		ID:              conversationID,
+	})
+
+	if err != nil {
+		respondWithError(w, "Something went wrong", http.StatusInternalServerError)
+		log.Printf("Error fetching conversation: %s\n", err)
+		return conversationResponse{}, false
+	}
+
+	response, err := apiCfg.conversationResponses(req.Context(), []database.GetConversationsRow{database.GetConversationsRow(found)}, rel)
+
+	if err != nil {
+		respondWithError(w, "Something went wrong", http.StatusInternalServerError)
+		log.Printf("Error building conversation response: %s\n", err)
+		return conversationResponse{}, false
+	}
+
+	return response[0], true
+}
+
+// conversationResponses attaches the members and the last message to each
+// conversation. Members and messages the viewer can't see are left out, as
+// are suspended members.
+func (apiCfg *apiConfig) conversationResponses(ctx context.Context, found []database.GetConversationsRow, rel visibility.Relations) ([]conversationResponse, error) {
+	ids := make([]uuid.UUID, 0, len(found))
+
+	for _, c := range found {
+		ids = append(ids, c.ID)
+	}
+
+	members, err := apiCfg.dbQueries.GetConversationMembers(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	memberIDs := map[uuid.UUID][]uuid.UUID{}
+	listed := []uuid.UUID{}
+
+	for _, m := range members {
+		if !rel.CanSeeUser(m.UserID, visibility.Direct) {
+			continue
+		}
+		memberIDs[m.ConversationID] = append(memberIDs[m.ConversationID], m.UserID)
+		listed = append(listed, m.UserID)
+	}
+
+	users, err := apiCfg.dbQueries.GetUsersByIds(ctx, listed)
+	if err != nil {
+		return nil, err
+	}
+
+	authors := map[uuid.UUID]authorResponse{}
+
+	for _, usr := range users {
+		if usr.SuspendedAt.Valid {
+			continue
+		}
+		authors[usr.ID] = apiCfg.newAuthorResponse(usr)
+	}
+
+	lastMessages, err := apiCfg.dbQueries.GetLastMessages(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	last := map[uuid.UUID]messageResponse{}
+
+	for _, m := range lastMessages {
+		if rel.CanSeeUser(m.SenderID, visibility.Direct) {
+			last[m.ConversationID] = newMessageResponse(m)
+		}
+	}
+
+	response := []conversationResponse{}
+
+	for _, c := range found {
+		conversation := conversationResponse{
+			ID:          c.ID.String(),
+			CreatedAt:   c.CreatedAt,
+			UpdatedAt:   c.UpdatedAt,
+			Direct:      c.DirectKey.Valid,
+			Members:     []authorResponse{},
+			UnreadCount: c.UnreadCount,
+		}
+
+		for _, id := range memberIDs[c.ID] {
+			if author, ok := authors[id]; ok {
+				conversation.Members = append(conversation.Members, author)
+			}
+		}
+
+		if m, ok := last[c.ID]; ok {
+			conversation.LastMessage = &m
+		}
+
+		response = append(response, conversation)
+	}
+
+	return response, nil
+}
+
+// getMessagesHandler pages through a conversation, newest messages first.
+func (apiCfg *apiConfig) getMessagesHandler(w http.ResponseWriter, req *http.Request) {
+	usrID, ok := apiCfg.authenticateUser(w, req)
+
+	if !ok {
+		return
+	}
+
+	conversation, ok := apiCfg.memberConversation(w, req, usrID)
+
+	if !ok {
+		return
+	}
+
+	limit, err := parseLimit(req)
+
+	if err != nil {
+		respondWithError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	rel, err := apiCfg.relations(req.Context(), uuid.NullUUID{UUID: usrID, Valid: true})
+
+	if err != nil {
+		respondWithError(w, "Something went wrong", http.StatusInternalServerError)
+		log.Printf("Error loading blocks and mutes: %s\n", err)
+		return
+	}
+
+	params := database.GetMessagesParams{
+		ConversationID:  conversation.ID,
+		ExcludedUserIds: rel.Excluded(visibility.Direct),
+		Limit:           limit,
+	}
+
+	if value := req.URL.Query().Get("cursor"); value != "" {
+		before, err := cursor.Parse(value)
+
+		if err != nil {
+			respondWithError(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		params.BeforeCreatedAt = sql.NullTime{Time: before.At, Valid: true}
+		params.BeforeID = uuid.NullUUID{UUID: before.ID, Valid: true}
+	}
+
+	found, err := apiCfg.dbQueries.GetMessages(req.Context(), params)
+
+	if err != nil {
+		respondWithError(w, "Something went wrong", http.StatusInternalServerError)
+		log.Printf("Error fetching messages: %s\n", err)
+		return
+	}
+
+	type pageResponse struct {
+		Messages   []messageResponse `json:"messages"`
+		NextCursor string            `json:"next_cursor,omitempty"`
+	}
+
+	response := pageResponse{
+		Messages: []messageResponse{},
+	}
+
+	for _, m := range found {
+		response.Messages = append(response.Messages, newMessageResponse(m))
+	}
+
+	if len(found) == int(limit) {
+		last := found[len(found)-1]
+		response.NextCursor = cursor.Cursor{At: last.CreatedAt, ID: last.ID}.String()
+	}
+
+	respondWithJSON(w, http.StatusOK, response)
+}
+
+func (apiCfg *apiConfig) markConversationReadHandler(w http.ResponseWriter, req *http.Request) {
+	usrID, ok := apiCfg.authenticateUser(w, req)
+
+	if !ok {
+		return
+	}
+
+	conversation, ok := apiCfg.memberConversation(w, req, usrID)
+
+	if !ok {
+		return
+	}
+
+	err := apiCfg.dbQueries.MarkConversationRead(req.Context(), database.MarkConversationReadParams{
+		ConversationID: conversation.ID,
+		UserID:         usrID,
+	})
+
+	if err != nil {
+		respondWithError(w, "Something went wrong", http.StatusInternalServerError)
+		log.Printf("Error marking conversation as read: %s\n", err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,534 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"time"
+	"unicode/utf8"
+
+	"github.com/firerockets/chirpy/internal/activitypub"
+	"github.com/firerockets/chirpy/internal/database"
+	"github.com/firerockets/chirpy/internal/moderation"
+	"github.com/firerockets/chirpy/internal/visibility"
+	"github.com/google/uuid"
+)
+
+const maxReportCommentLength = 500
+
+// respondWithTombstone answers requests for things moderators took down
+// with a 451, so clients can tell them apart from things that never
+// existed.
+func respondWithTombstone(w http.ResponseWriter, id uuid.UUID, removedAt time.Time, reason string) {
+	type tombstoneResponse struct {
+		ID        string    `json:"id"`
+		RemovedAt time.Time `json:"removed_at"`
+		Error     string    `json:"error"`
+	}
+
+	respondWithJSON(w, http.StatusUnavailableForLegalReasons, tombstoneResponse{
+		ID:        id.String(),
+		RemovedAt: removedAt,
+		Error:     reason,
+	})
+}
+
+type reportRequest struct {
+	Category string `json:"category"`
+	Comment  string `json:"comment"`
+}
+
+// decodeReport writes the error response itself and returns false when the
+// request body isn't a valid report.
+func decodeReport(w http.ResponseWriter, req *http.Request) (moderation.Category, string, bool) {
+	var params reportRequest
+
+	decoder := json.NewDecoder(req.Body)
+	err := decoder.Decode(&params)
+
+	if err != nil {
+		respondWithError(w, "Something went wrong while parsing the request body", http.StatusBadRequest)
+		log.Printf("Error decoding json request: %s\n", err)
+		return "", "", false
+	}
+
+	category, err := moderation.ParseCategory(params.Category)
+
+	if err != nil {
+		respondWithError(w, err.Error(), http.StatusBadRequest)
+		return "", "", false
+	}
+
+	if utf8.RuneCountInString(params.Comment) > maxReportCommentLength {
+		respondWithError(w, "Report comment is too long", http.StatusBadRequest)
+		return "", "", false
+	}
+
+	return category, params.Comment, true
+}
+
+// fileReport adds a report to the open case for the reported item, opening
+// one if there isn't any yet, so repeated reports end up grouped together.
+func (apiCfg *apiConfig) fileReport(req *http.Request, reporterID uuid.UUID, openCase func(*database.Queries) (database.ModerationCase, error), category moderation.Category, comment string) error {
+	tx, err := apiCfg.db.BeginTx(req.Context(), nil)
+
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	qtx := apiCfg.dbQueries.WithTx(tx)
+
+	modCase, err := openCase(qtx)
+
+	if err != nil {
+		return err
+	}
+
+	err = qtx.CreateReport(req.Context(), database.CreateReportParams{
+		CaseID:     modCase.ID,
+		ReporterID: reporterID,
+		Category:   string(category),
+		Comment:    comment,
+	})
+
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (apiCfg *apiConfig) reportChirpHandler(w http.ResponseWriter, req *http.Request) {
+	usrID, ok := apiCfg.authenticateUser(w, req)
+
+	if !ok {
+		return
+	}
+
+	chirpID, err := uuid.Parse(req.PathValue("chirpID"))
+
+	if err != nil {
+		respondWithError(w, "Invalid ID", http.StatusBadRequest)
+		log.Printf("Error validating UUID: %s\n", err)
+		return
+	}
+
+	chirp, err := apiCfg.dbQueries.GetChirpById(req.Context(), chirpID)
+
+	if errors.Is(err, sql.ErrNoRows) {
+		respondWithError(w, "Chirp not found", http.StatusNotFound)
+		return
+	} else if err != nil {
+		respondWithError(w, "Something went wrong", http.StatusInternalServerError)
+		log.Printf("Error getting chirp: %s\n", err)
+		return
+	}
+
+	visible, err := apiCfg.canSeeChirp(req.Context(), chirp, uuid.NullUUID{UUID: usrID, Valid: true}, visibility.Direct)
+
+	if err != nil {
+		respondWithError(w, "Something went wrong", http.StatusInternalServerError)
+		log.Printf("Error loading blocks and mutes: %s\n", err)
+		return
+	}
+
+	if !visible {
+		respondWithError(w, "Chirp not found", http.StatusNotFound)
+		return
+	}
+
+	if chirp.UserID == usrID {
+		respondWithError(w, "You can't report your own chirp", http.StatusBadRequest)
+		return
+	}
+
+	category, comment, ok := decodeReport(w, req)
+
+	if !ok {
+		return
+	}
+
+	err = apiCfg.fileReport(req, usrID, func(q *database.Queries) (database.ModerationCase, error) {
+		return q.OpenChirpCase(req.Context(), database.OpenChirpCaseParams{
+			ChirpID: uuid.NullUUID{UUID: chirp.ID, Valid: true},
+			UserID:  chirp.UserID,
+		})
+	}, category, comment)
+
+	if err != nil {
+		respondWithError(w, "Something went wrong", http.StatusInternalServerError)
+		log.Printf("Error filing chirp report: %s\n", err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (apiCfg *apiConfig) reportUserHandler(w http.ResponseWriter, req *http.Request) {
+	usrID, ok := apiCfg.authenticateUser(w, req)
+
+	if !ok {
+		return
+	}
+
+	target, ok := apiCfg.targetUser(w, req, usrID)
+
+	if !ok {
+		return
+	}
+
+	category, comment, ok := decodeReport(w, req)
+
+	if !ok {
+		return
+	}
+
+	err := apiCfg.fileReport(req, usrID, func(q *database.Queries) (database.ModerationCase, error) {
+		return q.OpenUserCase(req.Context(), target.ID)
+	}, category, comment)
+
+	if err != nil {
+		respondWithError(w, "Something went wrong", http.StatusInternalServerError)
+		log.Printf("Error filing user report: %s\n", err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+type moderationCaseResponse struct {
+	ID          string     `json:"id"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+	ChirpID     string     `json:"chirp_id,omitempty"`
+	UserID      string     `json:"user_id"`
+	Status      string     `json:"status"`
+	ResolvedAt  *time.Time `json:"resolved_at,omitempty"`
+	ReportCount int64      `json:"report_count,omitempty"`
+	Categories  []string   `json:"categories,omitempty"`
+}
+
+func newModerationCaseResponse(c database.ModerationCase) moderationCaseResponse {
+	response := moderationCaseResponse{
+		ID:        c.ID.String(),
+		CreatedAt: c.CreatedAt,
+		UpdatedAt: c.UpdatedAt,
+		UserID:    c.UserID.String(),
+		Status:    c.Status,
+	}
+
+	if c.ChirpID.Valid {
+		response.ChirpID = c.ChirpID.UUID.String()
+	}
+
+	if c.ResolvedAt.Valid {
+		response.ResolvedAt = &c.ResolvedAt.Time
+	}
+
+	return response
+}
+
+func (apiCfg *apiConfig) getReportsHandler(w http.ResponseWriter, req *http.Request) {
+	if _, ok := apiCfg.authenticateAdmin(w, req); !ok {
+		return
+	}
+
+	status := req.URL.Query().Get("status")
+
+	if status == "" {
+		status = moderation.StatusOpen
+	} else if status != moderation.StatusOpen && status != moderation.StatusResolved {
+		respondWithError(w, "status must be open or resolved", http.StatusBadRequest)
+		return
+	}
+
+	limit, offset, err := parsePagination(req)
+
+	if err != nil {
+		respondWithError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	cases, err := apiCfg.dbQueries.GetModerationCases(req.Context(), database.GetModerationCasesParams{
+		Status: status,
+		Limit:  limit,
+		Offset: offset,
+	})
+
+	if err != nil {
+		respondWithError(w, "Something went wrong", http.StatusInternalServerError)
+		log.Printf("Error fetching moderation cases: %s\n", err)
+		return
+	}
+
+	response := []moderationCaseResponse{}
+
+	for _, c := range cases {
+		caseResponse := newModerationCaseResponse(database.ModerationCase{
+			ID:         c.ID,
+			CreatedAt:  c.CreatedAt,
+			UpdatedAt:  c.UpdatedAt,
+			ChirpID:    c.ChirpID,
+			UserID:     c.UserID,
+			Status:     c.Status,
+			ResolvedAt: c.ResolvedAt,
+		})
+		caseResponse.ReportCount = c.ReportCount
+		caseResponse.Categories = c.Categories
+		response = append(response, caseResponse)
+	}
+
+	respondWithJSON(w, http.StatusOK, response)
+}
+
+// lookupCase writes the error response itself and returns false when the
+// case in the path doesn't exist.
+func (apiCfg *apiConfig) lookupCase(w http.ResponseWriter, req *http.Request) (database.ModerationCase, bool) {
+	caseID, err := uuid.Parse(req.PathValue("caseID"))
+
+	if err != nil {
+		respondWithError(w, "Invalid ID", http.StatusBadRequest)
+		log.Printf("Error validating UUID: %s\n", err)
+		return database.ModerationCase{}, false
+	}
+
+	modCase, err := apiCfg.dbQueries.GetModerationCaseById(req.Context(), caseID)
+
+	if errors.Is(err, sql.ErrNoRows) {
+		respondWithError(w, "Report not found", http.StatusNotFound)
+		return database.ModerationCase{}, false
+	} else if err != nil {
+		respondWithError(w, "Something went wrong", http.StatusInternalServerError)
+		log.Printf("Error getting moderation case: %s\n", err)
+		return database.ModerationCase{}, false
+	}
+
+	return modCase, true
+}
+
+func (apiCfg *apiConfig) getReportHandler(w http.ResponseWriter, req *http.Request) {
+	if _, ok := apiCfg.authenticateAdmin(w, req); !ok {
+		return
+	}
+
+	modCase, ok := apiCfg.lookupCase(w, req)
+
+	if !ok {
+		return
+	}
+
+	reports, err := apiCfg.dbQueries.GetCaseReports(req.Context(), modCase.ID)
+
+	if err != nil {
+		respondWithError(w, "Something went wrong", http.StatusInternalServerError)
+		log.Printf("Error fetching case reports: %s\n", err)
+		return
+	}
+
+	actions, err := apiCfg.dbQueries.GetCaseActions(req.Context(), modCase.ID)
+
+	if err != nil {
+		respondWithError(w, "Something went wrong", http.StatusInternalServerError)
+		log.Printf("Error fetching case actions: %s\n", err)
+		return
+	}
+
+	type reportResponse struct {
+		ID         string    `json:"id"`
+		CreatedAt  time.Time `json:"created_at"`
+		ReporterID string    `json:"reporter_id"`
+		Category   string    `json:"category"`
+		Comment    string    `json:"comment"`
+	}
+
+	type actionResponse struct {
+		ID          string    `json:"id"`
+		CreatedAt   time.Time `json:"created_at"`
+		ModeratorID string    `json:"moderator_id"`
+		Action      string    `json:"action"`
+		Note        string    `json:"note"`
+	}
+
+	type caseDetailResponse struct {
+		moderationCaseResponse
+		Reports []reportResponse `json:"reports"`
+		Actions []actionResponse `json:"actions"`
+	}
+
+	response := caseDetailResponse{
+		moderationCaseResponse: newModerationCaseResponse(modCase),
+		Reports:                []reportResponse{},
+		Actions:                []actionResponse{},
+	}
+
+	for _, r := range reports {
+		response.Reports = append(response.Reports, reportResponse{
+			ID:         r.ID.String(),
+			CreatedAt:  r.CreatedAt,
+			ReporterID: r.ReporterID.String(),
+			Category:   r.Category,
+			Comment:    r.Comment,
+		})
+	}
+
+	for _, a := range actions {
+		response.Actions = append(response.Actions, actionResponse{
+			ID:          a.ID.String(),
+			CreatedAt:   a.CreatedAt,
+			ModeratorID: a.ModeratorID.String(),
+			Action:      a.Action,
+			Note:        a.Note,
+		})
+	}
+
+	respondWithJSON(w, http.StatusOK, response)
+}
+
+func (apiCfg *apiConfig) moderateReportHandler(w http.ResponseWriter, req *http.Request) {
+	moderator, ok := apiCfg.authenticateAdmin(w, req)
+
+	if !ok {
+		return
+	}
+
+	modCase, ok := apiCfg.lookupCase(w, req)
+
+	if !ok {
+		return
+	}
+
+	type parameters struct {
+		Action string `json:"action"`
+		Note   string `json:"note"`
+	}
+
+	var params parameters
+
+	decoder := json.NewDecoder(req.Body)
+	err := decoder.Decode(&params)
+
+	if err != nil {
+		respondWithError(w, "Something went wrong while parsing the request body", http.StatusBadRequest)
+		log.Printf("Error decoding json request: %s\n", err)
+		return
+	}
+
+	action, err := moderation.ParseAction(params.Action, modCase.ChirpID.Valid)
+
+	if err != nil {
+		respondWithError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if action == moderation.Warn && params.Note == "" {
+		respondWithError(w, "Warnings need a note for the user", http.StatusBadRequest)
+		return
+	}
+
+	tx, err := apiCfg.db.BeginTx(req.Context(), nil)
+
+	if err != nil {
+		respondWithError(w, "Something went wrong", http.StatusInternalServerError)
+		log.Printf("Error starting transaction: %s\n", err)
+		return
+	}
+	defer tx.Rollback()
+
+	qtx := apiCfg.dbQueries.WithTx(tx)
+
+	resolved, err := qtx.ResolveModerationCase(req.Context(), modCase.ID)
+
+	if errors.Is(err, sql.ErrNoRows) {
+		respondWithError(w, "This report was already resolved", http.StatusConflict)
+		return
+	} else if err != nil {
+		respondWithError(w, "Something went wrong", http.StatusInternalServerError)
+		log.Printf("Error resolving moderation case: %s\n", err)
+		return
+	}
+
+	err = qtx.CreateModerationAction(req.Context(), database.CreateModerationActionParams{
+		CaseID:      modCase.ID,
+		ModeratorID: moderator.ID,
+		Action:      string(action),
+		Note:        params.Note,
+	})
+
+	if err != nil {
+		respondWithError(w, "Something went wrong", http.StatusInternalServerError)
+		log.Printf("Error recording moderation action: %s\n", err)
+		return
+	}
+
+	switch action {
+	case moderation.Remove:
+		err = qtx.RemoveChirp(req.Context(), modCase.ChirpID.UUID)
+		if err == nil {
+			removed := database.Chirp{ID: modCase.ChirpID.UUID, UserID: modCase.UserID}
+			err = apiCfg.federate(req.Context(), qtx, modCase.UserID, activitypub.NewDelete(apiCfg.apURLs, removed))
+		}
+	case moderation.Suspend:
+		err = qtx.SuspendUser(req.Context(), modCase.UserID)
+		if err == nil {
+			err = qtx.RevokeUserRefreshTokens(req.Context(), modCase.UserID)
+		}
+	}
+
+	if err != nil {
+		respondWithError(w, "Something went wrong", http.StatusInternalServerError)
+		log.Printf("Error applying moderation action %s: %s\n", action, err)
+		return
+	}
+
+	err = tx.Commit()
+
+	if err != nil {
+		respondWithError(w, "Something went wrong", http.StatusInternalServerError)
+		log.Printf("Error committing transaction: %s\n", err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, newModerationCaseResponse(resolved))
+}
+
+func (apiCfg *apiConfig) getWarningsHandler(w http.ResponseWriter, req *http.Request) {
+	usrID, ok := apiCfg.authenticateUser(w, req)
+
+	if !ok {
+		return
+	}
+
+	warnings, err := apiCfg.dbQueries.GetUserWarnings(req.Context(), usrID)
+
+	if err != nil {
+		respondWithError(w, "Something went wrong", http.StatusInternalServerError)
+		log.Printf("Error fetching warnings: %s\n", err)
+		return
+	}
+
+	type warningResponse struct {
+		ID        string    `json:"id"`
+		CreatedAt time.Time `json:"created_at"`
+		Note      string    `json:"note"`
+		ChirpID   string    `json:"chirp_id,omitempty"`
+	}
+
+	response := []warningResponse{}
+
+	for _, warning := range warnings {
+		warningResp := warningResponse{
+			ID:        warning.ID.String(),
+			CreatedAt: warning.CreatedAt,
+			Note:      warning.Note,
+		}
+		if warning.ChirpID.Valid {
+			warningResp.ChirpID = warning.ChirpID.UUID.String()
+		}
+		response = append(response, warningResp)
+	}
+
+	respondWithJSON(w, http.StatusOK, response)
+}
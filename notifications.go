@@ -0,0 +1,316 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/firerockets/chirpy/internal/cursor"
+	"github.com/firerockets/chirpy/internal/database"
+	"github.com/firerockets/chirpy/internal/notifications"
+	"github.com/firerockets/chirpy/internal/realtime"
+	"github.com/firerockets/chirpy/internal/visibility"
+	"github.com/google/uuid"
+)
+
+// maxNotificationActors is how many of the users behind a grouped
+// notification are listed. The rest only show up in actor_count.
+const maxNotificationActors = 3
+
+// notify tells recipientID that actorID did something about one of their
+// chirps. Unread notifications of the same kind about the same chirp are
+// grouped into one. Nothing is recorded when the recipient muted the actor
+// or there is a block between them.
+func notify(ctx context.Context, q *database.Queries, recipientID, actorID uuid.UUID, kind notifications.Kind, chirpID uuid.UUID) error {
+	if recipientID == actorID {
+		return nil
+	}
+
+	blocked, err := q.IsBlockedBetween(ctx, database.IsBlockedBetweenParams{
+		BlockerID: recipientID,
+		BlockedID: actorID,
+	})
+	if err != nil {
+		return err
+	}
+
+	muted, err := q.IsMuted(ctx, database.IsMutedParams{
+		MuterID: recipientID,
+		MutedID: actorID,
+	})
+	if err != nil {
+		return err
+	}
+
+	if blocked || muted {
+		return nil
+	}
+
+	notification, err := q.UpsertNotification(ctx, database.UpsertNotificationParams{
+		UserID:  recipientID,
+		Kind:    string(kind),
+		ChirpID: chirpID,
+	})
+	if err != nil {
+		return err
+	}
+
+	err = q.AddNotificationActor(ctx, database.AddNotificationActorParams{
+		NotificationID: notification.ID,
+		ActorID:        actorID,
+	})
+	if err != nil {
+		return err
+	}
+
+	return publishRealtime(ctx, q, realtime.Event{
+		Type:           realtime.NotificationEvent,
+		UserID:         uuid.NullUUID{UUID: recipientID, Valid: true},
+		NotificationID: uuid.NullUUID{UUID: notification.ID, Valid: true},
+	})
+}
+
+// notifyMentions notifies the users mentioned in a chirp.
+func notifyMentions(ctx context.Context, q *database.Queries, chirp database.Chirp) error {
+	mentions, err := q.GetChirpMentionsByChirpIds(ctx, []uuid.UUID{chirp.ID})
+	if err != nil {
+		return err
+	}
+
+	for _, m := range mentions {
+		err = notify(ctx, q, m.UserID, chirp.UserID, notifications.Mention, chirp.ID)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+type notificationResponse struct {
+	ID         string           `json:"id"`
+	CreatedAt  time.Time        `json:"created_at"`
+	UpdatedAt  time.Time        `json:"updated_at"`
+	Kind       string           `json:"kind"`
+	ChirpID    string           `json:"chirp_id"`
+	Actors     []authorResponse `json:"actors"`
+	ActorCount int              `json:"actor_count"`
+	Read       bool             `json:"read"`
+}
+
+func (apiCfg *apiConfig) getNotificationsHandler(w http.ResponseWriter, req *http.Request) {
+	usrID, ok := apiCfg.authenticateUser(w, req)
+
+	if !ok {
+		return
+	}
+
+	limit, err := parseLimit(req)
+
+	if err != nil {
+		respondWithError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	params := database.GetNotificationsParams{
+		UserID: usrID,
+		Limit:  limit,
+	}
+
+	if value := req.URL.Query().Get("cursor"); value != "" {
+		before, err := cursor.Parse(value)
+
+		if err != nil {
+			respondWithError(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		params.BeforeUpdatedAt = sql.NullTime{Time: before.At, Valid: true}
+		params.BeforeID = uuid.NullUUID{UUID: before.ID, Valid: true}
+	}
+
+	found, err := apiCfg.dbQueries.GetNotifications(req.Context(), params)
+
+	if err != nil {
+		respondWithError(w, "Something went wrong", http.StatusInternalServerError)
+		log.Printf("Error fetching notifications: %s\n", err)
+		return
+	}
+
+	unread, err := apiCfg.countUnreadNotifications(req.Context(), usrID)
+
+	if err != nil {
+		respondWithError(w, "Something went wrong", http.StatusInternalServerError)
+		log.Printf("Error counting unread notifications: %s\n", err)
+		return
+	}
+
+	notificationsResponse, err := apiCfg.notificationResponses(req.Context(), found, usrID)
+
+	if err != nil {
+		respondWithError(w, "Something went wrong", http.StatusInternalServerError)
+		log.Printf("Error building notifications response: %s\n", err)
+		return
+	}
+
+	type pageResponse struct {
+		Notifications []notificationResponse `json:"notifications"`
+		UnreadCount   int64                  `json:"unread_count"`
+		NextCursor    string                 `json:"next_cursor,omitempty"`
+	}
+
+	response := pageResponse{
+		Notifications: notificationsResponse,
+		UnreadCount:   unread,
+	}
+
+	// The cursor follows the last row fetched rather than the last one
+	// shown, so notifications hidden below don't end the paging early.
+	if len(found) == int(limit) {
+		last := found[len(found)-1]
+		response.NextCursor = cursor.Cursor{At: last.UpdatedAt, ID: last.ID}.String()
+	}
+
+	respondWithJSON(w, http.StatusOK, response)
+}
+
+// countUnreadNotifications leaves out the notifications whose actors are all
+// hidden from the user, which notificationResponses drops too, so the count
+// always matches what reading the notifications can clear.
+func (apiCfg *apiConfig) countUnreadNotifications(ctx context.Context, usrID uuid.UUID) (int64, error) {
+	rel, err := apiCfg.relations(ctx, uuid.NullUUID{UUID: usrID, Valid: true})
+	if err != nil {
+		return 0, err
+	}
+
+	return apiCfg.dbQueries.CountUnreadNotifications(ctx, database.CountUnreadNotificationsParams{
+		UserID:          usrID,
+		ExcludedUserIds: rel.Excluded(visibility.Feed),
+	})
+}
+
+// notificationResponses attaches the actors to each notification, leaving
+// out the ones the viewer blocked or muted since. Notifications with no
+// actor left are dropped.
+func (apiCfg *apiConfig) notificationResponses(ctx context.Context, found []database.Notification, viewerID uuid.UUID) ([]notificationResponse, error) {
+	rel, err := apiCfg.relations(ctx, uuid.NullUUID{UUID: viewerID, Valid: true})
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make([]uuid.UUID, 0, len(found))
+
+	for _, n := range found {
+		ids = append(ids, n.ID)
+	}
+
+	actors, err := apiCfg.dbQueries.GetNotificationActors(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	actorIDs := map[uuid.UUID][]uuid.UUID{}
+	listed := []uuid.UUID{}
+
+	for _, a := range actors {
+		if !rel.CanSeeUser(a.ActorID, visibility.Feed) {
+			continue
+		}
+		if len(actorIDs[a.NotificationID]) < maxNotificationActors {
+			listed = append(listed, a.ActorID)
+		}
+		actorIDs[a.NotificationID] = append(actorIDs[a.NotificationID], a.ActorID)
+	}
+
+	users, err := apiCfg.dbQueries.GetUsersByIds(ctx, listed)
+	if err != nil {
+		return nil, err
+	}
+
+	authors := map[uuid.UUID]authorResponse{}
+
+	for _, usr := range users {
+		authors[usr.ID] = apiCfg.newAuthorResponse(usr)
+	}
+
+	response := []notificationResponse{}
+
+	for _, n := range found {
+		nActors := actorIDs[n.ID]
+		if len(nActors) == 0 {
+			continue
+		}
+
+		notification := notificationResponse{
+			ID:         n.ID.String(),
+			CreatedAt:  n.CreatedAt,
+			UpdatedAt:  n.UpdatedAt,
+			Kind:       n.Kind,
+			ChirpID:    n.ChirpID.String(),
+			Actors:     []authorResponse{},
+			ActorCount: len(nActors),
+			Read:       n.ReadAt.Valid,
+		}
+
+		for _, id := range nActors[:min(len(nActors), maxNotificationActors)] {
+			notification.Actors = append(notification.Actors, authors[id])
+		}
+
+		response = append(response, notification)
+	}
+
+	return response, nil
+}
+
+func (apiCfg *apiConfig) markNotificationReadHandler(w http.ResponseWriter, req *http.Request) {
+	usrID, ok := apiCfg.authenticateUser(w, req)
+
+	if !ok {
+		return
+	}
+
+	notificationID, err := uuid.Parse(req.PathValue("notificationID"))
+
+	if err != nil {
+		respondWithError(w, "Invalid ID", http.StatusBadRequest)
+		log.Printf("Error validating UUID: %s\n", err)
+		return
+	}
+
+	_, err = apiCfg.dbQueries.MarkNotificationRead(req.Context(), database.MarkNotificationReadParams{
+		ID:     notificationID,
+		UserID: usrID,
+	})
+
+	if errors.Is(err, sql.ErrNoRows) {
+		respondWithError(w, "Notification not found", http.StatusNotFound)
+		return
+	} else if err != nil {
+		respondWithError(w, "Something went wrong", http.StatusInternalServerError)
+		log.Printf("Error marking notification as read: %s\n", err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (apiCfg *apiConfig) markAllNotificationsReadHandler(w http.ResponseWriter, req *http.Request) {
+	usrID, ok := apiCfg.authenticateUser(w, req)
+
+	if !ok {
+		return
+	}
+
+	err := apiCfg.dbQueries.MarkAllNotificationsRead(req.Context(), usrID)
+
+	if err != nil {
+		respondWithError(w, "Something went wrong", http.StatusInternalServerError)
+		log.Printf("Error marking notifications as read: %s\n", err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
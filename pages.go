@@ -0,0 +1,212 @@
+package main
+
+import (
+	"bytes"
+	"database/sql"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/firerockets/chirpy/internal/database"
+	"github.com/firerockets/chirpy/internal/feeds"
+	"github.com/firerockets/chirpy/internal/pages"
+	"github.com/firerockets/chirpy/internal/visibility"
+	"github.com/google/uuid"
+)
+
+const (
+	// pageCacheAge is how long, in seconds, public pages may be cached.
+	// They're for crawlers and unfurlers, which don't need fresher.
+	pageCacheAge = 300
+	// userPageChirps is how many of the latest chirps a profile page shows.
+	userPageChirps = 20
+)
+
+func chirpPageURL(site string, chirpID string) string {
+	return site + "/c/" + chirpID
+}
+
+func userPageURL(site string, handle string) string {
+	return site + "/u/" + handle
+}
+
+// absoluteURL resolves the site-relative URLs of local media, since link
+// previews need absolute ones.
+func absoluteURL(site, u string) string {
+	if strings.HasPrefix(u, "/") {
+		return site + u
+	}
+	return u
+}
+
+func pageAuthor(site string, author authorResponse) pages.Author {
+	name := author.DisplayName
+	if name == "" {
+		name = "@" + author.Handle
+	}
+
+	return pages.Author{
+		Name:      name,
+		Handle:    author.Handle,
+		URL:       userPageURL(site, author.Handle),
+		AvatarURL: absoluteURL(site, author.AvatarURL),
+	}
+}
+
+func pageChirp(site string, chirp chirpResponse) pages.Chirp {
+	page := pages.Chirp{
+		URL:    chirpPageURL(site, chirp.ID),
+		Author: pageAuthor(site, chirp.Author),
+		Body:   chirp.Body,
+	}
+
+	if chirp.PublishedAt != nil {
+		page.PublishedAt = *chirp.PublishedAt
+	}
+
+	for _, m := range chirp.Media {
+		if strings.HasPrefix(m.ContentType, "image/") {
+			page.Images = append(page.Images, pages.Image{
+				URL:     absoluteURL(site, m.URL),
+				AltText: m.AltText,
+			})
+		}
+	}
+
+	return page
+}
+
+// chirpPageHandler serves /c/{chirpID}. Pages are public and cached, so
+// they show what anonymous viewers see.
+func (apiCfg *apiConfig) chirpPageHandler(w http.ResponseWriter, req *http.Request) {
+	chirpID, err := uuid.Parse(req.PathValue("chirpID"))
+
+	if err != nil {
+		respondWithNotFoundPage(w)
+		return
+	}
+
+	response, _, err := apiCfg.viewChirp(req.Context(), chirpID, uuid.NullUUID{})
+
+	if errors.Is(err, errChirpNotFound) || errors.Is(err, errChirpDeleted) || errors.Is(err, errChirpRemoved) {
+		respondWithNotFoundPage(w)
+		return
+	} else if err != nil {
+		respondWithError(w, "Something went wrong", http.StatusInternalServerError)
+		log.Printf("Error building chirp response: %s\n", err)
+		return
+	}
+
+	var page bytes.Buffer
+
+	err = pages.ChirpPage(&page, pageChirp(apiCfg.siteURL(req), response))
+
+	if err != nil {
+		respondWithError(w, "Something went wrong", http.StatusInternalServerError)
+		log.Printf("Error rendering chirp page: %s\n", err)
+		return
+	}
+
+	respondWithPage(w, req, page.Bytes())
+}
+
+// userPageHandler serves /u/{handle} with the user's latest chirps.
+func (apiCfg *apiConfig) userPageHandler(w http.ResponseWriter, req *http.Request) {
+	usr, err := apiCfg.lookupUser(req.Context(), req.PathValue("handle"))
+
+	if errors.Is(err, sql.ErrNoRows) {
+		respondWithNotFoundPage(w)
+		return
+	} else if err != nil {
+		respondWithError(w, "Something went wrong", http.StatusInternalServerError)
+		log.Printf("Error looking up user: %s\n", err)
+		return
+	}
+
+	rel, err := apiCfg.relations(req.Context(), uuid.NullUUID{})
+
+	if err != nil {
+		respondWithError(w, "Something went wrong", http.StatusInternalServerError)
+		log.Printf("Error loading blocks and mutes: %s\n", err)
+		return
+	}
+
+	if usr.SuspendedAt.Valid || !rel.CanSeeUser(usr.ID, visibility.Direct) {
+		respondWithNotFoundPage(w)
+		return
+	}
+
+	chirps, err := apiCfg.dbQueries.GetFeedChirps(req.Context(), database.GetFeedChirpsParams{
+		AuthorID:        uuid.NullUUID{UUID: usr.ID, Valid: true},
+		ExcludedUserIds: rel.Excluded(visibility.Feed),
+		Limit:           userPageChirps,
+	})
+
+	if err != nil {
+		respondWithError(w, "Something went wrong", http.StatusInternalServerError)
+		log.Printf("Error fetching chirps for page of %s: %s\n", usr.ID, err)
+		return
+	}
+
+	chirpsResponse, err := apiCfg.chirpResponses(req.Context(), chirps, uuid.NullUUID{}, visibility.Feed)
+
+	if err != nil {
+		respondWithError(w, "Something went wrong", http.StatusInternalServerError)
+		log.Printf("Error building chirps response: %s\n", err)
+		return
+	}
+
+	site := apiCfg.siteURL(req)
+
+	user := pages.User{
+		Author: pageAuthor(site, apiCfg.newAuthorResponse(usr)),
+		Bio:    usr.Bio,
+	}
+
+	for _, c := range chirpsResponse {
+		user.Chirps = append(user.Chirps, pageChirp(site, c))
+	}
+
+	var page bytes.Buffer
+
+	err = pages.UserPage(&page, user)
+
+	if err != nil {
+		respondWithError(w, "Something went wrong", http.StatusInternalServerError)
+		log.Printf("Error rendering user page: %s\n", err)
+		return
+	}
+
+	respondWithPage(w, req, page.Bytes())
+}
+
+// respondWithPage answers conditional requests with the ETag of the page.
+func respondWithPage(w http.ResponseWriter, req *http.Request, page []byte) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("ETag", feeds.ETag(page))
+	w.Header().Set("Cache-Control", "public, max-age="+strconv.Itoa(pageCacheAge))
+
+	http.ServeContent(w, req, "", time.Time{}, bytes.NewReader(page))
+}
+
+// respondWithNotFoundPage is also used for deleted and hidden things, so
+// crawlers drop them from their index.
+func respondWithNotFoundPage(w http.ResponseWriter) {
+	var page bytes.Buffer
+
+	err := pages.NotFoundPage(&page)
+
+	if err != nil {
+		respondWithError(w, "Not found", http.StatusNotFound)
+		log.Printf("Error rendering not found page: %s\n", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "public, max-age="+strconv.Itoa(pageCacheAge))
+	w.WriteHeader(http.StatusNotFound)
+	w.Write(page.Bytes())
+}
@@ -0,0 +1,199 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+
+	"github.com/firerockets/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+func (apiCfg *apiConfig) loadPinnedChirps(ctx context.Context, chirps []database.Chirp) (map[uuid.UUID]bool, error) {
+	ids := make([]uuid.UUID, 0, len(chirps))
+
+	for _, c := range chirps {
+		ids = append(ids, c.ID)
+	}
+
+	pinnedIDs, err := apiCfg.dbQueries.GetPinnedChirpIds(ctx, ids)
+
+	if err != nil {
+		return nil, err
+	}
+
+	pinned := map[uuid.UUID]bool{}
+
+	for _, id := range pinnedIDs {
+		pinned[id] = true
+	}
+
+	return pinned, nil
+}
+
+// pinnedFirst moves the pinned chirps of an author to the front of chirps,
+// in the order they were pinned, keeping the order of the rest.
+func pinnedFirst(chirps []database.Chirp, pinned []database.Chirp) []database.Chirp {
+	position := map[uuid.UUID]int{}
+
+	for i, c := range pinned {
+		position[c.ID] = i
+	}
+
+	sort.SliceStable(chirps, func(i, j int) bool {
+		pi, iPinned := position[chirps[i].ID]
+		pj, jPinned := position[chirps[j].ID]
+
+		if iPinned && jPinned {
+			return pi < pj
+		}
+		return iPinned && !jPinned
+	})
+
+	return chirps
+}
+
+// ownPublishedChirp loads the chirp in the path and makes sure usrID posted
+// it. Unpublished chirps are reported as not found.
+func (apiCfg *apiConfig) ownPublishedChirp(w http.ResponseWriter, req *http.Request, usrID uuid.UUID) (database.Chirp, bool) {
+	chirpID, err := uuid.Parse(req.PathValue("chirpID"))
+
+	if err != nil {
+		respondWithError(w, "Invalid ID", http.StatusBadRequest)
+		log.Printf("Error validating UUID: %s\n", err)
+		return database.Chirp{}, false
+	}
+
+	chirp, err := apiCfg.dbQueries.GetChirpById(req.Context(), chirpID)
+
+	if err != nil || chirp.DeletedAt.Valid || chirp.RemovedAt.Valid || (chirp.UserID != usrID && !chirp.PublishedAt.Valid) {
+		respondWithError(w, "Chirp not found", http.StatusNotFound)
+		return database.Chirp{}, false
+	}
+
+	if chirp.UserID != usrID {
+		respondWithError(w, "Forbiden", http.StatusForbidden)
+		log.Println("Chirp does not belong to this user")
+		return database.Chirp{}, false
+	}
+
+	if !chirp.PublishedAt.Valid {
+		respondWithError(w, "Chirp is not published yet", http.StatusBadRequest)
+		return database.Chirp{}, false
+	}
+
+	return chirp, true
+}
+
+func (apiCfg *apiConfig) pinChirpHandler(w http.ResponseWriter, req *http.Request) {
+	usrID, ok := apiCfg.authenticateUser(w, req)
+
+	if !ok {
+		return
+	}
+
+	chirp, ok := apiCfg.ownPublishedChirp(w, req, usrID)
+
+	if !ok {
+		return
+	}
+
+	ents, err := apiCfg.userEntitlements(req.Context(), usrID)
+
+	if err != nil {
+		respondWithError(w, "Something went wrong", http.StatusInternalServerError)
+		log.Printf("Error looking up entitlements: %s\n", err)
+		return
+	}
+
+	tx, err := apiCfg.db.BeginTx(req.Context(), nil)
+
+	if err != nil {
+		respondWithError(w, "Something went wrong", http.StatusInternalServerError)
+		log.Printf("Error starting transaction: %s\n", err)
+		return
+	}
+	defer tx.Rollback()
+
+	qtx := apiCfg.dbQueries.WithTx(tx)
+
+	// Locking the user serializes their pins, so concurrent requests can't
+	// go over the limit together.
+	err = qtx.LockUser(req.Context(), usrID)
+
+	if err != nil {
+		respondWithError(w, "Something went wrong", http.StatusInternalServerError)
+		log.Printf("Error locking user: %s\n", err)
+		return
+	}
+
+	count, err := qtx.CountOtherPinnedChirps(req.Context(), database.CountOtherPinnedChirpsParams{
+		UserID:  usrID,
+		ChirpID: chirp.ID,
+	})
+
+	if err != nil {
+		respondWithError(w, "Something went wrong", http.StatusInternalServerError)
+		log.Printf("Error counting pinned chirps: %s\n", err)
+		return
+	}
+
+	limit := pinLimit(ents)
+
+	if count >= int64(limit) {
+		respondWithError(w, fmt.Sprintf("You can pin up to %d chirps", limit), http.StatusForbidden)
+		return
+	}
+
+	err = qtx.CreatePinnedChirp(req.Context(), database.CreatePinnedChirpParams{
+		UserID:  usrID,
+		ChirpID: chirp.ID,
+	})
+
+	if err != nil {
+		respondWithError(w, "Something went wrong", http.StatusInternalServerError)
+		log.Printf("Error pinning chirp: %s\n", err)
+		return
+	}
+
+	err = tx.Commit()
+
+	if err != nil {
+		respondWithError(w, "Something went wrong", http.StatusInternalServerError)
+		log.Printf("Error committing pin: %s\n", err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (apiCfg *apiConfig) unpinChirpHandler(w http.ResponseWriter, req *http.Request) {
+	usrID, ok := apiCfg.authenticateUser(w, req)
+
+	if !ok {
+		return
+	}
+
+	chirpID, err := uuid.Parse(req.PathValue("chirpID"))
+
+	if err != nil {
+		respondWithError(w, "Invalid ID", http.StatusBadRequest)
+		log.Printf("Error validating UUID: %s\n", err)
+		return
+	}
+
+	err = apiCfg.dbQueries.DeletePinnedChirp(req.Context(), database.DeletePinnedChirpParams{
+		UserID:  usrID,
+		ChirpID: chirpID,
+	})
+
+	if err != nil {
+		respondWithError(w, "Something went wrong", http.StatusInternalServerError)
+		log.Printf("Error unpinning chirp: %s\n", err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
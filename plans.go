@@ -0,0 +1,66 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/firerockets/chirpy/internal/entitlements"
+	"github.com/google/uuid"
+)
+
+// subscriptionPeriodDays is how long each Chirpy Red payment from Polka
+// lasts. Polka doesn't send a period, so every upgrade buys one month.
+const subscriptionPeriodDays = 30
+
+// chirpLengthLimit is the longest chirp the user can post, as counted by
+// chirptext.Length.
+func chirpLengthLimit(ents entitlements.Set) int {
+	if ents.Has(entitlements.LongChirps) {
+		return 1000
+	}
+	return 140
+}
+
+// pinLimit is how many chirps the user can pin to their profile.
+func pinLimit(ents entitlements.Set) int {
+	if ents.Has(entitlements.ExtraPins) {
+		return 3
+	}
+	return 1
+}
+
+// userEntitlements returns what the user's subscription grants right now,
+// which is the free plan when they have none.
+func (apiCfg *apiConfig) userEntitlements(ctx context.Context, usrID uuid.UUID) (entitlements.Set, error) {
+	sub, err := apiCfg.dbQueries.GetActiveSubscription(ctx, usrID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return entitlements.Free(), nil
+	} else if err != nil {
+		return entitlements.Set{}, err
+	}
+
+	return entitlements.For(sub, time.Now()), nil
+}
+
+// requireEntitlement writes a 403 and returns false for users without the
+// entitlement.
+func (apiCfg *apiConfig) requireEntitlement(w http.ResponseWriter, req *http.Request, usrID uuid.UUID, e entitlements.Entitlement) bool {
+	ents, err := apiCfg.userEntitlements(req.Context(), usrID)
+
+	if err != nil {
+		respondWithError(w, "Something went wrong", http.StatusInternalServerError)
+		log.Printf("Error looking up entitlements: %s\n", err)
+		return false
+	}
+
+	if !ents.Has(e) {
+		respondWithError(w, "This feature requires Chirpy Red", http.StatusForbidden)
+		return false
+	}
+
+	return true
+}
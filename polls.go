@@ -0,0 +1,321 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/firerockets/chirpy/internal/chirptext"
+	"github.com/firerockets/chirpy/internal/database"
+	"github.com/firerockets/chirpy/internal/filter"
+	"github.com/firerockets/chirpy/internal/realtime"
+	"github.com/firerockets/chirpy/internal/visibility"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+const (
+	minPollOptions      = 2
+	maxPollOptions      = 4
+	maxPollOptionLength = 25
+	minPollDuration     = 5 * time.Minute
+	maxPollDuration     = 7 * 24 * time.Hour
+)
+
+type pollRequest struct {
+	Options  []string  `json:"options"`
+	ClosesAt time.Time `json:"closes_at"`
+}
+
+type pollOptionResponse struct {
+	ID    string `json:"id"`
+	Text  string `json:"text"`
+	Votes *int64 `json:"votes,omitempty"`
+}
+
+// pollResponse leaves out vote counts until the viewer has voted or the
+// poll is closed, so results can't sway the vote.
+type pollResponse struct {
+	ID            string               `json:"id"`
+	ClosesAt      time.Time            `json:"closes_at"`
+	Closed        bool                 `json:"closed"`
+	Options       []pollOptionResponse `json:"options"`
+	TotalVotes    *int64               `json:"total_votes,omitempty"`
+	VotedOptionID string               `json:"voted_option_id,omitempty"`
+}
+
+// checkPoll validates a poll to be published at opensAt and runs its options
+// through the content filter. It writes the error response itself and
+// returns false when the poll can't be created.
+func checkPoll(w http.ResponseWriter, contentFilter *filter.Filter, poll pollRequest, opensAt time.Time) ([]string, []filter.Match, bool) {
+	if len(poll.Options) < minPollOptions || len(poll.Options) > maxPollOptions {
+		respondWithError(w, "Polls need between 2 and 4 options", http.StatusBadRequest)
+		return nil, nil, false
+	}
+
+	if !checkPollDuration(w, poll.ClosesAt, opensAt) {
+		return nil, nil, false
+	}
+
+	options := []string{}
+	matches := []filter.Match{}
+	seen := map[string]bool{}
+
+	for _, option := range poll.Options {
+		option = strings.TrimSpace(chirptext.Normalize(option))
+
+		if option == "" || chirptext.Length(option) > maxPollOptionLength {
+			respondWithError(w, "Poll options must be between 1 and 25 characters long", http.StatusBadRequest)
+			return nil, nil, false
+		}
+
+		if seen[strings.ToLower(option)] {
+			respondWithError(w, "Poll options must be different from each other", http.StatusBadRequest)
+			return nil, nil, false
+		}
+		seen[strings.ToLower(option)] = true
+
+		filtered := contentFilter.Apply(option)
+
+		if filtered.Rejected() {
+			respondWithError(w, "Poll contains words that are not allowed", http.StatusBadRequest)
+			log.Println("Poll option rejected by the content filter")
+			return nil, nil, false
+		}
+
+		options = append(options, filtered.Body)
+		matches = append(matches, filtered.Matches...)
+	}
+
+	return options, matches, true
+}
+
+// checkPollDuration makes sure a poll closing at closesAt stays open long
+// enough, but not too long, once its chirp is published at opensAt.
+func checkPollDuration(w http.ResponseWriter, closesAt, opensAt time.Time) bool {
+	duration := closesAt.Sub(opensAt)
+
+	if duration < minPollDuration || duration > maxPollDuration {
+		respondWithError(w, "Polls must close between 5 minutes and 7 days after they're published", http.StatusBadRequest)
+		return false
+	}
+
+	return true
+}
+
+func createPoll(ctx context.Context, q *database.Queries, chirpID uuid.UUID, options []string, closesAt time.Time) error {
+	poll, err := q.CreatePoll(ctx, database.CreatePollParams{
+		ChirpID:  chirpID,
+		ClosesAt: closesAt.UTC(),
+	})
+	if err != nil {
+		return err
+	}
+
+	for i, option := range options {
+		err = q.CreatePollOption(ctx, database.CreatePollOptionParams{
+			PollID:   poll.ID,
+			Position: int32(i),
+			Text:     option,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// loadChirpPolls returns the polls attached to chirps as seen by viewerID,
+// keyed by chirp ID.
+func (apiCfg *apiConfig) loadChirpPolls(ctx context.Context, chirps []database.Chirp, viewerID uuid.NullUUID) (map[uuid.UUID]*pollResponse, error) {
+	found := map[uuid.UUID]*pollResponse{}
+	ids := make([]uuid.UUID, 0, len(chirps))
+
+	for _, c := range chirps {
+		ids = append(ids, c.ID)
+	}
+
+	polls, err := apiCfg.dbQueries.GetPollsByChirpIds(ctx, ids)
+
+	if err != nil || len(polls) == 0 {
+		return found, err
+	}
+
+	pollIDs := make([]uuid.UUID, 0, len(polls))
+
+	for _, p := range polls {
+		pollIDs = append(pollIDs, p.ID)
+	}
+
+	options, err := apiCfg.dbQueries.GetPollOptionsByPollIds(ctx, pollIDs)
+
+	if err != nil {
+		return nil, err
+	}
+
+	votedFor := map[uuid.UUID]uuid.UUID{}
+
+	if viewerID.Valid {
+		votes, err := apiCfg.dbQueries.GetUserPollVotes(ctx, database.GetUserPollVotesParams{
+			UserID:  viewerID.UUID,
+			PollIds: pollIDs,
+		})
+
+		if err != nil {
+			return nil, err
+		}
+
+		for _, v := range votes {
+			votedFor[v.PollID] = v.OptionID
+		}
+	}
+
+	byID := map[uuid.UUID]*pollResponse{}
+	now := time.Now().UTC()
+
+	for _, p := range polls {
+		response := &pollResponse{
+			ID:       p.ID.String(),
+			ClosesAt: p.ClosesAt,
+			Closed:   !now.Before(p.ClosesAt),
+			Options:  []pollOptionResponse{},
+		}
+
+		if optionID, ok := votedFor[p.ID]; ok {
+			response.VotedOptionID = optionID.String()
+		}
+
+		if response.Closed || response.VotedOptionID != "" {
+			response.TotalVotes = new(int64)
+		}
+
+		byID[p.ID] = response
+		found[p.ChirpID] = response
+	}
+
+	for _, o := range options {
+		response := byID[o.PollID]
+		option := pollOptionResponse{
+			ID:   o.ID.String(),
+			Text: o.Text,
+		}
+
+		if response.TotalVotes != nil {
+			option.Votes = &o.Votes
+			*response.TotalVotes += o.Votes
+		}
+
+		response.Options = append(response.Options, option)
+	}
+
+	return found, nil
+}
+
+func (apiCfg *apiConfig) createPollVoteHandler(w http.ResponseWriter, req *http.Request) {
+	usrID, ok := apiCfg.authenticateUser(w, req)
+
+	if !ok {
+		return
+	}
+
+	chirpID, err := uuid.Parse(req.PathValue("chirpID"))
+
+	if err != nil {
+		respondWithError(w, "Invalid ID", http.StatusBadRequest)
+		log.Printf("Error validating UUID: %s\n", err)
+		return
+	}
+
+	chirp, err := apiCfg.dbQueries.GetChirpById(req.Context(), chirpID)
+
+	if err != nil || !chirp.PublishedAt.Valid {
+		respondWithError(w, "Poll not found", http.StatusNotFound)
+		return
+	}
+
+	visible, err := apiCfg.canSeeChirp(req.Context(), chirp, uuid.NullUUID{UUID: usrID, Valid: true}, visibility.Direct)
+
+	if err != nil {
+		respondWithError(w, "Something went wrong", http.StatusInternalServerError)
+		log.Printf("Error loading blocks and mutes: %s\n", err)
+		return
+	}
+
+	if !visible {
+		respondWithError(w, "Poll not found", http.StatusNotFound)
+		return
+	}
+
+	poll, err := apiCfg.dbQueries.GetPollByChirpId(req.Context(), chirpID)
+
+	if err != nil {
+		respondWithError(w, "Poll not found", http.StatusNotFound)
+		return
+	}
+
+	if !time.Now().UTC().Before(poll.ClosesAt) {
+		respondWithError(w, "Poll is closed", http.StatusForbidden)
+		return
+	}
+
+	type parameters struct {
+		OptionID uuid.UUID `json:"option_id"`
+	}
+
+	var params parameters
+
+	decoder := json.NewDecoder(req.Body)
+	err = decoder.Decode(&params)
+
+	if err != nil {
+		respondWithError(w, "Something went wrong while parsing the request body", http.StatusBadRequest)
+		log.Printf("Error decoding json request: %s\n", err)
+		return
+	}
+
+	err = apiCfg.dbQueries.CreatePollVote(req.Context(), database.CreatePollVoteParams{
+		PollID:   poll.ID,
+		OptionID: params.OptionID,
+		UserID:   usrID,
+	})
+
+	// The primary key allows a single vote per user and the foreign key only
+	// accepts options of this poll, even with concurrent requests.
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == "23505" {
+		respondWithError(w, "You already voted on this poll", http.StatusConflict)
+		return
+	} else if errors.As(err, &pqErr) && pqErr.Code == "23503" {
+		respondWithError(w, "Option is not part of this poll", http.StatusBadRequest)
+		return
+	} else if err != nil {
+		respondWithError(w, "Something went wrong", http.StatusInternalServerError)
+		log.Printf("Error creating poll vote: %s\n", err)
+		return
+	}
+
+	err = publishRealtime(req.Context(), apiCfg.dbQueries, realtime.Event{
+		Type:    realtime.PollVoteEvent,
+		ChirpID: uuid.NullUUID{UUID: chirp.ID, Valid: true},
+	})
+
+	// The vote is in either way, live counters just lag until the next one.
+	if err != nil {
+		log.Printf("Error publishing poll vote: %s\n", err)
+	}
+
+	polls, err := apiCfg.loadChirpPolls(req.Context(), []database.Chirp{chirp}, uuid.NullUUID{UUID: usrID, Valid: true})
+
+	if err != nil {
+		respondWithError(w, "Something went wrong", http.StatusInternalServerError)
+		log.Printf("Error loading poll: %s\n", err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusCreated, polls[chirp.ID])
+}
@@ -0,0 +1,286 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/firerockets/chirpy/internal/chirptext"
+	"github.com/firerockets/chirpy/internal/database"
+	"github.com/firerockets/chirpy/internal/handles"
+	"github.com/firerockets/chirpy/internal/media"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+const (
+	maxDisplayNameLength = 50
+	maxBioLength         = 160
+	maxAvatarSize        = 5 << 20
+)
+
+// authorResponse is the compact user embedded in every chirp.
+type authorResponse struct {
+	ID          string `json:"id"`
+	Handle      string `json:"handle"`
+	DisplayName string `json:"display_name"`
+	AvatarURL   string `json:"avatar_url,omitempty"`
+}
+
+func (apiCfg *apiConfig) avatarURL(usr database.User) string {
+	if !usr.AvatarKey.Valid {
+		return ""
+	}
+	return apiCfg.blobStore.URL(usr.AvatarKey.String)
+}
+
+func (apiCfg *apiConfig) newAuthorResponse(usr database.User) authorResponse {
+	return authorResponse{
+		ID:          usr.ID.String(),
+		Handle:      usr.Handle,
+		DisplayName: usr.DisplayName,
+		AvatarURL:   apiCfg.avatarURL(usr),
+	}
+}
+
+// loadChirpAuthors loads the authors of chirps, leaving out suspended ones.
+func (apiCfg *apiConfig) loadChirpAuthors(ctx context.Context, chirps []database.Chirp) (map[uuid.UUID]authorResponse, error) {
+	ids := make([]uuid.UUID, 0, len(chirps))
+	seen := map[uuid.UUID]bool{}
+
+	for _, c := range chirps {
+		if !seen[c.UserID] {
+			seen[c.UserID] = true
+			ids = append(ids, c.UserID)
+		}
+	}
+
+	users, err := apiCfg.dbQueries.GetUsersByIds(ctx, ids)
+
+	if err != nil {
+		return nil, err
+	}
+
+	found := map[uuid.UUID]authorResponse{}
+
+	for _, usr := range users {
+		if usr.SuspendedAt.Valid {
+			continue
+		}
+		found[usr.ID] = apiCfg.newAuthorResponse(usr)
+	}
+
+	return found, nil
+}
+
+// lookupUser finds a user from a path value holding either their handle,
+// with or without the '@', or their ID.
+func (apiCfg *apiConfig) lookupUser(ctx context.Context, handleOrID string) (database.User, error) {
+	if id, err := uuid.Parse(handleOrID); err == nil {
+		return apiCfg.dbQueries.GetUserById(ctx, id)
+	}
+	return apiCfg.dbQueries.GetUserByHandle(ctx, strings.TrimPrefix(handleOrID, "@"))
+}
+
+func checkHandle(w http.ResponseWriter, handle string) bool {
+	err := handles.Validate(handle)
+
+	if err != nil {
+		respondWithError(w, err.Error(), http.StatusBadRequest)
+		return false
+	}
+
+	return true
+}
+
+func isHandleTaken(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == "23505" && pqErr.Constraint == "users_handle_idx"
+}
+
+// checkProfileText normalizes a display name or bio and runs it through the
+// content filter. It writes the error response itself and returns false
+// when the text can't be used.
+func (apiCfg *apiConfig) checkProfileText(w http.ResponseWriter, field, text string, maxLength int) (string, bool) {
+	text = strings.TrimSpace(chirptext.Normalize(text))
+
+	if chirptext.Length(text) > maxLength {
+		respondWithError(w, field+" is too long", http.StatusBadRequest)
+		return "", false
+	}
+
+	filtered := apiCfg.contentFilter.Apply(text)
+
+	if filtered.Rejected() {
+		respondWithError(w, field+" contains words that are not allowed", http.StatusBadRequest)
+		return "", false
+	}
+
+	return filtered.Body, true
+}
+
+func (apiCfg *apiConfig) updateProfileHandler(w http.ResponseWriter, req *http.Request) {
+	usrID, ok := apiCfg.authenticateUser(w, req)
+
+	if !ok {
+		return
+	}
+
+	type parameters struct {
+		Handle      string `json:"handle"`
+		DisplayName string `json:"display_name"`
+		Bio         string `json:"bio"`
+	}
+
+	var params parameters
+
+	decoder := json.NewDecoder(req.Body)
+	err := decoder.Decode(&params)
+
+	if err != nil {
+		respondWithError(w, "Something went wrong while parsing the request body", http.StatusBadRequest)
+		log.Printf("Error decoding json request: %s\n", err)
+		return
+	}
+
+	params.Handle = strings.TrimPrefix(params.Handle, "@")
+
+	if !checkHandle(w, params.Handle) {
+		return
+	}
+
+	displayName, ok := apiCfg.checkProfileText(w, "Display name", params.DisplayName, maxDisplayNameLength)
+
+	if !ok {
+		return
+	}
+
+	bio, ok := apiCfg.checkProfileText(w, "Bio", params.Bio, maxBioLength)
+
+	if !ok {
+		return
+	}
+
+	usr, err := apiCfg.dbQueries.UpdateUserProfile(req.Context(), database.UpdateUserProfileParams{
+		ID:          usrID,
+		Handle:      params.Handle,
+		DisplayName: displayName,
+		Bio:         bio,
+	})
+
+	if isHandleTaken(err) {
+		respondWithError(w, "Handle is already taken", http.StatusConflict)
+		return
+	} else if err != nil {
+		respondWithError(w, "Something went wrong", http.StatusInternalServerError)
+		log.Printf("Error updating profile: %s\n", err)
+		return
+	}
+
+	ents, err := apiCfg.userEntitlements(req.Context(), usr.ID)
+
+	if err != nil {
+		respondWithError(w, "Something went wrong", http.StatusInternalServerError)
+		log.Printf("Error looking up entitlements: %s\n", err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, apiCfg.newUserResponse(usr, ents))
+}
+
+func (apiCfg *apiConfig) uploadAvatarHandler(w http.ResponseWriter, req *http.Request) {
+	usrID, ok := apiCfg.authenticateUser(w, req)
+
+	if !ok {
+		return
+	}
+
+	req.Body = http.MaxBytesReader(w, req.Body, maxAvatarSize+1<<20)
+
+	file, _, err := req.FormFile("file")
+
+	if err != nil {
+		respondWithError(w, "A file is required", http.StatusBadRequest)
+		log.Printf("Error reading multipart file: %s\n", err)
+		return
+	}
+
+	defer file.Close()
+
+	data, err := io.ReadAll(io.LimitReader(file, maxAvatarSize+1))
+
+	if err != nil {
+		respondWithError(w, "Something went wrong while reading the file", http.StatusBadRequest)
+		log.Printf("Error reading uploaded file: %s\n", err)
+		return
+	}
+
+	if len(data) > maxAvatarSize {
+		respondWithError(w, "File is too large", http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	processed, err := media.ProcessImage(data)
+
+	if errors.Is(err, media.ErrUnsupportedType) {
+		respondWithError(w, "Only JPEG, PNG and GIF images are supported", http.StatusUnsupportedMediaType)
+		return
+	} else if err != nil {
+		respondWithError(w, "Invalid image", http.StatusBadRequest)
+		log.Printf("Error processing image: %s\n", err)
+		return
+	}
+
+	// Avatars are only shown small, so the thumbnail is all that's kept.
+	avatarKey := "avatars/" + usrID.String() + "/" + uuid.NewString() + processed.ThumbnailExtension
+
+	err = apiCfg.blobStore.Put(req.Context(), avatarKey, processed.ThumbnailContentType, processed.Thumbnail)
+
+	if err != nil {
+		respondWithError(w, "Something went wrong while storing the file", http.StatusInternalServerError)
+		log.Printf("Error storing avatar: %s\n", err)
+		return
+	}
+
+	previous, err := apiCfg.dbQueries.GetUserById(req.Context(), usrID)
+
+	if err != nil {
+		respondWithError(w, "Unauthorized", http.StatusUnauthorized)
+		log.Printf("Error looking up user: %s\n", err)
+		return
+	}
+
+	usr, err := apiCfg.dbQueries.UpdateUserAvatar(req.Context(), database.UpdateUserAvatarParams{
+		ID:        usrID,
+		AvatarKey: sql.NullString{String: avatarKey, Valid: true},
+	})
+
+	if err != nil {
+		respondWithError(w, "Something went wrong while storing the file", http.StatusInternalServerError)
+		log.Printf("Error updating avatar: %s\n", err)
+		return
+	}
+
+	if previous.AvatarKey.Valid {
+		err = apiCfg.blobStore.Delete(req.Context(), previous.AvatarKey.String)
+
+		if err != nil {
+			log.Printf("Error deleting previous avatar: %s\n", err)
+		}
+	}
+
+	ents, err := apiCfg.userEntitlements(req.Context(), usr.ID)
+
+	if err != nil {
+		respondWithError(w, "Something went wrong", http.StatusInternalServerError)
+		log.Printf("Error looking up entitlements: %s\n", err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, apiCfg.newUserResponse(usr, ents))
+}
@@ -0,0 +1,251 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/firerockets/chirpy/internal/database"
+	"github.com/firerockets/chirpy/internal/visibility"
+	"github.com/google/uuid"
+)
+
+// checkPublishAt validates the requested publish time of a chirp. A nil time
+// means publishing right away, unless the chirp is a draft.
+func checkPublishAt(w http.ResponseWriter, publishAt *time.Time) (sql.NullTime, bool) {
+	if publishAt == nil {
+		return sql.NullTime{}, true
+	}
+
+	if !publishAt.After(time.Now()) {
+		respondWithError(w, "publish_at must be in the future", http.StatusBadRequest)
+		return sql.NullTime{}, false
+	}
+
+	return sql.NullTime{Time: publishAt.UTC(), Valid: true}, true
+}
+
+// ownUnpublishedChirp loads the chirp in the path, making sure it belongs to
+// usrID and isn't published yet. Anything else is reported as not found.
+func (apiCfg *apiConfig) ownUnpublishedChirp(w http.ResponseWriter, req *http.Request, usrID uuid.UUID) (database.Chirp, bool) {
+	chirpID, err := uuid.Parse(req.PathValue("chirpID"))
+
+	if err != nil {
+		respondWithError(w, "Invalid ID", http.StatusBadRequest)
+		log.Printf("Error validating UUID: %s\n", err)
+		return database.Chirp{}, false
+	}
+
+	chirp, err := apiCfg.dbQueries.GetChirpById(req.Context(), chirpID)
+
+	if errors.Is(err, sql.ErrNoRows) || (err == nil && (chirp.UserID != usrID || chirp.PublishedAt.Valid || chirp.DeletedAt.Valid)) {
+		respondWithError(w, "Scheduled chirp not found", http.StatusNotFound)
+		return database.Chirp{}, false
+	} else if err != nil {
+		respondWithError(w, "Something went wrong", http.StatusInternalServerError)
+		log.Printf("Error looking up chirp: %s\n", err)
+		return database.Chirp{}, false
+	}
+
+	return chirp, true
+}
+
+func (apiCfg *apiConfig) getScheduledChirpsHandler(w http.ResponseWriter, req *http.Request) {
+	usrID, ok := apiCfg.authenticateUser(w, req)
+
+	if !ok {
+		return
+	}
+
+	chirps, err := apiCfg.dbQueries.GetUnpublishedChirpsByUserId(req.Context(), usrID)
+
+	if err != nil {
+		respondWithError(w, "Something went wrong", http.StatusInternalServerError)
+		log.Printf("Error getting scheduled chirps: %s\n", err)
+		return
+	}
+
+	chirpsResponse, err := apiCfg.chirpResponses(req.Context(), chirps, uuid.NullUUID{UUID: usrID, Valid: true}, visibility.Direct)
+
+	if err != nil {
+		respondWithError(w, "Something went wrong", http.StatusInternalServerError)
+		log.Printf("Error building chirp responses: %s\n", err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, chirpsResponse)
+}
+
+func (apiCfg *apiConfig) updateScheduledChirpHandler(w http.ResponseWriter, req *http.Request) {
+	usrID, ok := apiCfg.authenticateUser(w, req)
+
+	if !ok {
+		return
+	}
+
+	chirp, ok := apiCfg.ownUnpublishedChirp(w, req, usrID)
+
+	if !ok {
+		return
+	}
+
+	type parameters struct {
+		Body      string     `json:"body"`
+		PublishAt *time.Time `json:"publish_at"`
+		Draft     bool       `json:"draft"`
+	}
+
+	var params parameters
+
+	decoder := json.NewDecoder(req.Body)
+	err := decoder.Decode(&params)
+
+	if err != nil {
+		respondWithError(w, "Something went wrong while parsing the request body", http.StatusBadRequest)
+		log.Printf("Error decoding json request: %s\n", err)
+		return
+	}
+
+	ents, err := apiCfg.userEntitlements(req.Context(), usrID)
+
+	if err != nil {
+		respondWithError(w, "Something went wrong", http.StatusInternalServerError)
+		log.Printf("Error looking up entitlements: %s\n", err)
+		return
+	}
+
+	filtered, ok := checkChirpBody(w, apiCfg.contentFilter, ents, params.Body)
+
+	if !ok {
+		return
+	}
+
+	publishAt, ok := checkPublishAt(w, params.PublishAt)
+
+	if !ok {
+		return
+	}
+
+	// The poll was checked against the old publish time. Drafts are checked
+	// again once they're scheduled or published.
+	if !params.Draft {
+		poll, err := apiCfg.dbQueries.GetPollByChirpId(req.Context(), chirp.ID)
+
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			respondWithError(w, "Something went wrong", http.StatusInternalServerError)
+			log.Printf("Error looking up poll: %s\n", err)
+			return
+		}
+
+		opensAt := time.Now()
+		if publishAt.Valid {
+			opensAt = publishAt.Time
+		}
+
+		if err == nil && !checkPollDuration(w, poll.ClosesAt, opensAt) {
+			return
+		}
+	}
+
+	tx, err := apiCfg.db.BeginTx(req.Context(), nil)
+
+	if err != nil {
+		respondWithError(w, "Something went wrong", http.StatusInternalServerError)
+		log.Printf("Error starting transaction: %s\n", err)
+		return
+	}
+	defer tx.Rollback()
+
+	qtx := apiCfg.dbQueries.WithTx(tx)
+
+	chirp, err = qtx.UpdateUnpublishedChirp(req.Context(), database.UpdateUnpublishedChirpParams{
+		Body:      filtered.Body,
+		IsDraft:   params.Draft,
+		PublishAt: publishAt,
+		ID:        chirp.ID,
+	})
+
+	// The scheduler may have published it since it was looked up.
+	if errors.Is(err, sql.ErrNoRows) {
+		respondWithError(w, "Scheduled chirp not found", http.StatusNotFound)
+		return
+	} else if err != nil {
+		respondWithError(w, "Something went wrong", http.StatusInternalServerError)
+		log.Printf("Error updating scheduled chirp: %s\n", err)
+		return
+	}
+
+	if filtered.Flagged() {
+		err = qtx.CreateChirpFlag(req.Context(), database.CreateChirpFlagParams{
+			ChirpID: chirp.ID,
+			Reason:  flagReason(filtered),
+		})
+
+		if err != nil {
+			respondWithError(w, "Something went wrong", http.StatusInternalServerError)
+			log.Printf("Error flagging chirp for review: %s\n", err)
+			return
+		}
+	}
+
+	err = replaceChirpEntities(req.Context(), qtx, chirp)
+
+	if err != nil {
+		respondWithError(w, "Something went wrong", http.StatusInternalServerError)
+		log.Printf("Error saving chirp entities: %s\n", err)
+		return
+	}
+
+	err = apiCfg.chirpPublished(req.Context(), qtx, chirp)
+
+	if err != nil {
+		respondWithError(w, "Something went wrong", http.StatusInternalServerError)
+		log.Printf("Error following up on published chirp: %s\n", err)
+		return
+	}
+
+	err = tx.Commit()
+
+	if err != nil {
+		respondWithError(w, "Something went wrong", http.StatusInternalServerError)
+		log.Printf("Error committing chirp: %s\n", err)
+		return
+	}
+
+	chirpsResponse, err := apiCfg.chirpResponses(req.Context(), []database.Chirp{chirp}, uuid.NullUUID{UUID: usrID, Valid: true}, visibility.Direct)
+
+	if err != nil {
+		respondWithError(w, "Something went wrong", http.StatusInternalServerError)
+		log.Printf("Error building chirp response: %s\n", err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, chirpsResponse[0])
+}
+
+func (apiCfg *apiConfig) deleteScheduledChirpHandler(w http.ResponseWriter, req *http.Request) {
+	usrID, ok := apiCfg.authenticateUser(w, req)
+
+	if !ok {
+		return
+	}
+
+	chirp, ok := apiCfg.ownUnpublishedChirp(w, req, usrID)
+
+	if !ok {
+		return
+	}
+
+	err := apiCfg.dbQueries.DeleteUnpublishedChirp(req.Context(), chirp.ID)
+
+	if err != nil {
+		respondWithError(w, "Something went wrong", http.StatusInternalServerError)
+		log.Printf("Error deleting scheduled chirp: %s\n", err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/firerockets/chirpy/internal/auth"
+	"github.com/firerockets/chirpy/internal/filter"
+	"github.com/google/uuid"
+)
+
+func TestUpdateScheduledChirpRechecksPoll(t *testing.T) {
+	userID := uuid.New()
+	chirpID := uuid.New()
+	now := time.Now().UTC()
+
+	apiCfg := newTestConfig(t, map[string]fakeResult{
+		"GetChirpById": {{
+			chirpID.String(), now, now, "Which one?", userID.String(),
+			nil, now.Add(30 * time.Minute), false, nil, nil,
+		}},
+		"GetActiveSubscription": {},
+		"GetPollByChirpId": {{
+			uuid.NewString(), now, chirpID.String(), now.Add(time.Hour),
+		}},
+	})
+	apiCfg.contentFilter = filter.New(nil)
+
+	token, err := auth.MakeJWT(userID, "free", nil, apiCfg.secret, time.Hour)
+	if err != nil {
+		t.Fatalf("Error making token: %s", err)
+	}
+
+	// Publishing after the poll closes would leave it closed from the start.
+	publishAt := now.Add(2 * time.Hour).Format(time.RFC3339)
+	body := `{"body": "Which one?", "publish_at": "` + publishAt + `"}`
+
+	req := httptest.NewRequest(http.MethodPut, "/api/chirps/scheduled/"+chirpID.String(), strings.NewReader(body))
+	req.SetPathValue("chirpID", chirpID.String())
+	req.Header.Set("Authorization", "Bearer "+token)
+	w := httptest.NewRecorder()
+
+	apiCfg.updateScheduledChirpHandler(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected %d, got %d: %s", http.StatusBadRequest, w.Code, w.Body)
+	}
+
+	if !strings.Contains(w.Body.String(), "Polls must close") {
+		t.Errorf("Expected the poll to be rejected, got %s", w.Body)
+	}
+}
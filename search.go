@@ -0,0 +1,63 @@
+package main
+
+import (
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/firerockets/chirpy/internal/database"
+	"github.com/firerockets/chirpy/internal/visibility"
+)
+
+const maxSearchQueryLength = 50
+
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// searchUsersHandler matches the query as a prefix of handles and display
+// names, and fuzzily through trigram similarity. An exact handle match
+// always comes first, then users with more followers.
+func (apiCfg *apiConfig) searchUsersHandler(w http.ResponseWriter, req *http.Request) {
+	query := strings.TrimPrefix(strings.TrimSpace(req.URL.Query().Get("q")), "@")
+
+	if query == "" || len([]rune(query)) > maxSearchQueryLength {
+		respondWithError(w, "q must be between 1 and 50 characters long", http.StatusBadRequest)
+		return
+	}
+
+	limit, offset, err := parsePagination(req)
+
+	if err != nil {
+		respondWithError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	rel, err := apiCfg.relations(req.Context(), apiCfg.viewerID(req))
+
+	if err != nil {
+		respondWithError(w, "Something went wrong", http.StatusInternalServerError)
+		log.Printf("Error loading blocks and mutes: %s\n", err)
+		return
+	}
+
+	users, err := apiCfg.dbQueries.SearchUsers(req.Context(), database.SearchUsersParams{
+		Prefix:          likeEscaper.Replace(query) + "%",
+		Query:           query,
+		ExcludedUserIds: rel.Excluded(visibility.Feed),
+		Limit:           limit,
+		Offset:          offset,
+	})
+
+	if err != nil {
+		respondWithError(w, "Something went wrong", http.StatusInternalServerError)
+		log.Printf("Error searching users: %s\n", err)
+		return
+	}
+
+	results := []authorResponse{}
+
+	for _, usr := range rel.Users(users, visibility.Feed) {
+		results = append(results, apiCfg.newAuthorResponse(usr))
+	}
+
+	respondWithJSON(w, http.StatusOK, results)
+}
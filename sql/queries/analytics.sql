@@ -0,0 +1,75 @@
+-- name: AddChirpImpressions :exec
+-- Impressions of chirps purged since they were counted are dropped.
+INSERT INTO chirp_impressions_daily (chirp_id, day, impressions)
+SELECT counted.chirp_id, counted.day, counted.impressions
+FROM unnest(sqlc.arg(chirp_ids)::uuid[], sqlc.arg(days)::date[], sqlc.arg(counts)::bigint[])
+    AS counted (chirp_id, day, impressions)
+WHERE EXISTS (SELECT 1 FROM chirps WHERE chirps.id = counted.chirp_id)
+ON CONFLICT (chirp_id, day)
+DO UPDATE SET impressions = chirp_impressions_daily.impressions + EXCLUDED.impressions;
+
+-- name: DeleteChirpEngagementsSince :exec
+DELETE FROM chirp_engagements_daily
+WHERE day >= $1;
+
+-- name: RollupChirpEngagements :exec
+INSERT INTO chirp_engagements_daily (chirp_id, day, engagements)
+SELECT engaged.chirp_id, engaged.day, COUNT(*)
+FROM (
+    SELECT bookmarks.chirp_id, bookmarks.created_at::date AS day
+    FROM bookmarks
+    WHERE bookmarks.created_at >= sqlc.arg(since)::date
+    UNION ALL
+    SELECT polls.chirp_id, poll_votes.created_at::date
+    FROM poll_votes
+    JOIN polls ON polls.id = poll_votes.poll_id
+    WHERE poll_votes.created_at >= sqlc.arg(since)::date
+    UNION ALL
+    SELECT remote_interactions.chirp_id, remote_interactions.created_at::date
+    FROM remote_interactions
+    WHERE remote_interactions.created_at >= sqlc.arg(since)::date
+) AS engaged
+GROUP BY engaged.chirp_id, engaged.day;
+
+-- name: RollupFollowerCounts :exec
+-- Users who lost their last follower keep getting a row, so their count
+-- drops to zero instead of staying at yesterday's.
+INSERT INTO follower_counts_daily (user_id, day, followers)
+SELECT counted.user_id, sqlc.arg(day)::date, (
+    SELECT COUNT(*) FROM remote_followers WHERE remote_followers.user_id = counted.user_id
+)
+FROM (
+    SELECT remote_followers.user_id FROM remote_followers
+    UNION
+    SELECT follower_counts_daily.user_id FROM follower_counts_daily
+    WHERE follower_counts_daily.day = sqlc.arg(day)::date - 1 AND follower_counts_daily.followers > 0
+) AS counted
+ON CONFLICT (user_id, day) DO UPDATE SET followers = EXCLUDED.followers;
+
+-- name: GetChirpAnalytics :many
+SELECT chirps.id, chirps.body, chirps.published_at,
+    COALESCE(viewed.total, 0)::bigint AS impressions,
+    COALESCE(engaged.total, 0)::bigint AS engagements
+FROM chirps
+LEFT JOIN (
+    SELECT chirp_id, SUM(chirp_impressions_daily.impressions) AS total
+    FROM chirp_impressions_daily
+    WHERE day >= sqlc.arg(since)::date
+    GROUP BY chirp_id
+) AS viewed ON viewed.chirp_id = chirps.id
+LEFT JOIN (
+    SELECT chirp_id, SUM(chirp_engagements_daily.engagements) AS total
+    FROM chirp_engagements_daily
+    WHERE day >= sqlc.arg(since)::date
+    GROUP BY chirp_id
+) AS engaged ON engaged.chirp_id = chirps.id
+WHERE chirps.user_id = sqlc.arg(user_id)
+AND chirps.published_at IS NOT NULL AND chirps.removed_at IS NULL AND chirps.deleted_at IS NULL
+AND (viewed.total IS NOT NULL OR engaged.total IS NOT NULL)
+ORDER BY impressions DESC, engagements DESC, chirps.id
+LIMIT sqlc.arg('limit') OFFSET sqlc.arg('offset');
+
+-- name: GetFollowerCounts :many
+SELECT day, followers FROM follower_counts_daily
+WHERE user_id = sqlc.arg(user_id) AND day >= sqlc.arg(since)::date
+ORDER BY day;
\ No newline at end of file
@@ -0,0 +1,21 @@
+-- name: CreateBookmark :exec
+INSERT INTO bookmarks (user_id, chirp_id, created_at)
+VALUES ($1, $2, NOW())
+ON CONFLICT (user_id, chirp_id) DO NOTHING;
+
+-- name: DeleteBookmark :exec
+DELETE FROM bookmarks
+WHERE user_id = $1 AND chirp_id = $2;
+
+-- name: GetBookmarkedChirps :many
+SELECT chirps.* FROM chirps
+JOIN bookmarks ON bookmarks.chirp_id = chirps.id
+JOIN users ON users.id = chirps.user_id AND users.suspended_at IS NULL
+WHERE bookmarks.user_id = sqlc.arg(user_id) AND chirps.published_at IS NOT NULL AND chirps.removed_at IS NULL AND chirps.deleted_at IS NULL
+    AND NOT chirps.user_id = ANY(sqlc.arg(excluded_user_ids)::uuid[])
+ORDER BY bookmarks.created_at DESC
+LIMIT sqlc.arg('limit') OFFSET sqlc.arg('offset');
+
+-- name: GetBookmarkedChirpIds :many
+SELECT chirp_id FROM bookmarks
+WHERE user_id = $1 AND chirp_id = ANY(sqlc.arg(chirp_ids)::uuid[]);
\ No newline at end of file
@@ -0,0 +1,54 @@
+-- name: UpsertHashtag :one
+INSERT INTO hashtags (id, created_at, tag)
+VALUES (gen_random_uuid(), NOW(), $1)
+ON CONFLICT (tag) DO UPDATE SET tag = EXCLUDED.tag
+RETURNING *;
+
+-- name: CreateChirpHashtag :exec
+INSERT INTO chirp_hashtags (chirp_id, hashtag_id, start_offset, end_offset)
+VALUES ($1, $2, $3, $4);
+
+-- name: CreateChirpMention :exec
+INSERT INTO chirp_mentions (chirp_id, user_id, start_offset, end_offset)
+VALUES ($1, $2, $3, $4);
+
+-- name: CreateChirpLink :exec
+INSERT INTO chirp_links (chirp_id, url, start_offset, end_offset)
+VALUES ($1, $2, $3, $4);
+
+-- name: GetChirpHashtagsByChirpIds :many
+SELECT chirp_hashtags.chirp_id, hashtags.tag, chirp_hashtags.start_offset, chirp_hashtags.end_offset
+FROM chirp_hashtags
+JOIN hashtags ON hashtags.id = chirp_hashtags.hashtag_id
+WHERE chirp_hashtags.chirp_id = ANY(sqlc.arg(chirp_ids)::uuid[]);
+
+-- name: GetChirpMentionsByChirpIds :many
+SELECT * FROM chirp_mentions
+WHERE chirp_id = ANY(sqlc.arg(chirp_ids)::uuid[]);
+
+-- name: GetChirpLinksByChirpIds :many
+SELECT * FROM chirp_links
+WHERE chirp_id = ANY(sqlc.arg(chirp_ids)::uuid[]);
+
+-- name: GetChirpsByHashtag :many
+SELECT chirps.* FROM chirps
+JOIN users ON users.id = chirps.user_id AND users.suspended_at IS NULL
+WHERE EXISTS (
+    SELECT 1 FROM chirp_hashtags
+    JOIN hashtags ON hashtags.id = chirp_hashtags.hashtag_id
+    WHERE chirp_hashtags.chirp_id = chirps.id AND hashtags.tag = $1
+)
+AND chirps.published_at IS NOT NULL AND chirps.removed_at IS NULL AND chirps.deleted_at IS NULL
+ORDER BY chirps.published_at;
+
+-- name: DeleteChirpHashtags :exec
+DELETE FROM chirp_hashtags
+WHERE chirp_id = $1;
+
+-- name: DeleteChirpMentions :exec
+DELETE FROM chirp_mentions
+WHERE chirp_id = $1;
+
+-- name: DeleteChirpLinks :exec
+DELETE FROM chirp_links
+WHERE chirp_id = $1;
\ No newline at end of file
@@ -1,16 +1,23 @@
 -- name: CreateChirp :one
-INSERT INTO chirps (id, created_at, updated_at, body, user_id)
-VALUES (gen_random_uuid(), NOW(), NOW(), $1, $2)
+INSERT INTO chirps (id, created_at, updated_at, body, user_id, published_at, publish_at, is_draft)
+VALUES (
+    gen_random_uuid(), NOW(), NOW(), sqlc.arg(body), sqlc.arg(user_id),
+    CASE WHEN NOT sqlc.arg(is_draft)::boolean AND sqlc.narg(publish_at)::timestamptz IS NULL THEN NOW() END,
+    sqlc.narg(publish_at), sqlc.arg(is_draft)
+)
 RETURNING *;
 
 -- name: GetChirps :many
-SELECT * FROM chirps
-ORDER BY created_at;
+SELECT chirps.* FROM chirps
+JOIN users ON users.id = chirps.user_id AND users.suspended_at IS NULL
+WHERE chirps.published_at IS NOT NULL AND chirps.removed_at IS NULL AND chirps.deleted_at IS NULL
+ORDER BY chirps.published_at;
 
 -- name: GetChirpsByUserId :many
-SELECT * FROM chirps
-WHERE user_id = $1
-ORDER BY created_at;
+SELECT chirps.* FROM chirps
+JOIN users ON users.id = chirps.user_id AND users.suspended_at IS NULL
+WHERE chirps.user_id = $1 AND chirps.published_at IS NOT NULL AND chirps.removed_at IS NULL AND chirps.deleted_at IS NULL
+ORDER BY chirps.published_at;
 
 -- name: GetChirpById :one
 SELECT * FROM chirps
@@ -18,5 +25,100 @@ WHERE id = $1
 LIMIT 1;
 
 -- name: DeleteChirpById :exec
+UPDATE chirps
+SET updated_at = NOW(), deleted_at = NOW()
+WHERE id = $1 AND deleted_at IS NULL;
+
+-- name: DeleteUnpublishedChirp :exec
 DELETE FROM chirps
-WHERE id = $1;
\ No newline at end of file
+WHERE id = $1 AND published_at IS NULL;
+
+-- name: GetUnpublishedChirpsByUserId :many
+SELECT * FROM chirps
+WHERE user_id = $1 AND published_at IS NULL AND deleted_at IS NULL
+ORDER BY is_draft, publish_at, created_at;
+
+-- name: UpdateUnpublishedChirp :one
+UPDATE chirps
+SET updated_at = NOW(),
+    body = sqlc.arg(body),
+    published_at = CASE WHEN NOT sqlc.arg(is_draft)::boolean AND sqlc.narg(publish_at)::timestamptz IS NULL THEN NOW() END,
+    publish_at = sqlc.narg(publish_at),
+    is_draft = sqlc.arg(is_draft)
+WHERE id = sqlc.arg(id) AND published_at IS NULL AND deleted_at IS NULL
+RETURNING *;
+
+-- name: GetDueChirpIds :many
+SELECT id FROM chirps
+WHERE published_at IS NULL AND NOT is_draft AND publish_at <= NOW() AND deleted_at IS NULL
+ORDER BY publish_at
+LIMIT $1;
+
+-- name: PublishDueChirp :one
+-- Returns no rows when the chirp was published, unscheduled or deleted
+-- since it came due.
+UPDATE chirps
+SET updated_at = NOW(), published_at = NOW()
+WHERE id = $1 AND published_at IS NULL AND NOT is_draft AND publish_at <= NOW() AND deleted_at IS NULL
+RETURNING *;
+
+-- name: RemoveChirp :exec
+UPDATE chirps
+SET updated_at = NOW(), removed_at = NOW()
+WHERE id = $1 AND removed_at IS NULL;
+
+-- name: RestoreChirp :one
+UPDATE chirps
+SET updated_at = NOW(), deleted_at = NULL
+WHERE id = sqlc.arg(id) AND deleted_at >= NOW() - make_interval(days => sqlc.arg(retention_days)::int)
+RETURNING *;
+
+-- name: PurgeDeletedChirps :execrows
+DELETE FROM chirps
+WHERE deleted_at < NOW() - make_interval(days => sqlc.arg(retention_days)::int);
+
+-- name: AnnounceChirp :exec
+SELECT pg_notify('chirp_published', sqlc.arg(chirp_id)::text);
+
+-- name: GetChirpsPublishedAfter :many
+SELECT chirps.* FROM chirps
+JOIN users ON users.id = chirps.user_id AND users.suspended_at IS NULL
+WHERE chirps.published_at IS NOT NULL AND chirps.removed_at IS NULL AND chirps.deleted_at IS NULL
+AND (chirps.published_at, chirps.id) > (sqlc.arg(after_published_at)::timestamp, sqlc.arg(after_id)::uuid)
+AND (sqlc.narg(author_id)::uuid IS NULL OR chirps.user_id = sqlc.narg(author_id)::uuid)
+AND (sqlc.narg(hashtag)::text IS NULL OR EXISTS (
+    SELECT 1 FROM chirp_hashtags
+    JOIN hashtags ON hashtags.id = chirp_hashtags.hashtag_id
+    WHERE chirp_hashtags.chirp_id = chirps.id AND hashtags.tag = sqlc.narg(hashtag)::text
+))
+AND NOT (chirps.user_id = ANY(sqlc.arg(excluded_user_ids)::uuid[]))
+ORDER BY chirps.published_at, chirps.id
+LIMIT sqlc.arg('limit');
+
+-- name: CountPublishedChirpsByUserId :one
+SELECT COUNT(*) FROM chirps
+WHERE user_id = $1 AND published_at IS NOT NULL AND removed_at IS NULL AND deleted_at IS NULL;
+
+-- name: GetOutboxChirps :many
+SELECT * FROM chirps
+WHERE user_id = sqlc.arg(user_id) AND published_at IS NOT NULL AND removed_at IS NULL AND deleted_at IS NULL
+AND (
+    sqlc.narg(before_published_at)::timestamp IS NULL
+    OR (published_at, id) < (sqlc.narg(before_published_at)::timestamp, sqlc.narg(before_id)::uuid)
+)
+ORDER BY published_at DESC, id DESC
+LIMIT sqlc.arg('limit');
+
+-- name: GetFeedChirps :many
+SELECT chirps.* FROM chirps
+JOIN users ON users.id = chirps.user_id AND users.suspended_at IS NULL
+WHERE chirps.published_at IS NOT NULL AND chirps.removed_at IS NULL AND chirps.deleted_at IS NULL
+AND (sqlc.narg(author_id)::uuid IS NULL OR chirps.user_id = sqlc.narg(author_id)::uuid)
+AND (sqlc.narg(hashtag)::text IS NULL OR EXISTS (
+    SELECT 1 FROM chirp_hashtags
+    JOIN hashtags ON hashtags.id = chirp_hashtags.hashtag_id
+    WHERE chirp_hashtags.chirp_id = chirps.id AND hashtags.tag = sqlc.narg(hashtag)::text
+))
+AND NOT (chirps.user_id = ANY(sqlc.arg(excluded_user_ids)::uuid[]))
+ORDER BY chirps.published_at DESC, chirps.id DESC
+LIMIT sqlc.arg('limit');
\ No newline at end of file
@@ -0,0 +1,36 @@
+-- name: CreateCollection :one
+INSERT INTO collections (id, created_at, updated_at, user_id, name)
+VALUES (gen_random_uuid(), NOW(), NOW(), $1, $2)
+RETURNING *;
+
+-- name: GetCollectionsByUserId :many
+SELECT * FROM collections
+WHERE user_id = $1
+ORDER BY name;
+
+-- name: GetCollectionById :one
+SELECT * FROM collections
+WHERE id = $1
+LIMIT 1;
+
+-- name: DeleteCollection :exec
+DELETE FROM collections
+WHERE id = $1;
+
+-- name: AddChirpToCollection :exec
+INSERT INTO collection_chirps (collection_id, chirp_id, created_at)
+VALUES ($1, $2, NOW())
+ON CONFLICT (collection_id, chirp_id) DO NOTHING;
+
+-- name: RemoveChirpFromCollection :exec
+DELETE FROM collection_chirps
+WHERE collection_id = $1 AND chirp_id = $2;
+
+-- name: GetCollectionChirps :many
+SELECT chirps.* FROM chirps
+JOIN collection_chirps ON collection_chirps.chirp_id = chirps.id
+JOIN users ON users.id = chirps.user_id AND users.suspended_at IS NULL
+WHERE collection_chirps.collection_id = sqlc.arg(collection_id) AND chirps.published_at IS NOT NULL AND chirps.removed_at IS NULL AND chirps.deleted_at IS NULL
+    AND NOT chirps.user_id = ANY(sqlc.arg(excluded_user_ids)::uuid[])
+ORDER BY collection_chirps.created_at DESC
+LIMIT sqlc.arg('limit') OFFSET sqlc.arg('offset');
\ No newline at end of file
@@ -0,0 +1,24 @@
+-- name: GetFilterRules :many
+SELECT * FROM filter_rules
+ORDER BY word;
+
+-- name: UpsertFilterRule :one
+INSERT INTO filter_rules (word, policy, created_at, updated_at)
+VALUES ($1, $2, NOW(), NOW())
+ON CONFLICT (word) DO UPDATE SET policy = EXCLUDED.policy, updated_at = NOW()
+RETURNING *;
+
+-- name: DeleteFilterRule :execrows
+DELETE FROM filter_rules
+WHERE word = $1;
+
+-- name: AnnounceFilterChange :exec
+SELECT pg_notify('content_filter', '');
+
+-- name: CreateChirpFlag :exec
+INSERT INTO chirp_flags (id, created_at, chirp_id, reason)
+VALUES (gen_random_uuid(), NOW(), $1, $2);
+
+-- name: GetChirpFlags :many
+SELECT * FROM chirp_flags
+ORDER BY created_at DESC;
\ No newline at end of file
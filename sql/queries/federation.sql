@@ -0,0 +1,68 @@
+-- name: CreateActorKey :exec
+INSERT INTO actor_keys (user_id, created_at, public_key_pem, private_key_pem)
+VALUES ($1, NOW(), $2, $3)
+ON CONFLICT (user_id) DO NOTHING;
+
+-- name: GetActorKey :one
+SELECT * FROM actor_keys
+WHERE user_id = $1;
+
+-- name: UpsertRemoteFollower :exec
+INSERT INTO remote_followers (user_id, actor_id, inbox, follow_id, created_at)
+VALUES ($1, $2, $3, $4, NOW())
+ON CONFLICT (user_id, actor_id)
+DO UPDATE SET inbox = EXCLUDED.inbox, follow_id = EXCLUDED.follow_id;
+
+-- name: DeleteRemoteFollower :exec
+DELETE FROM remote_followers
+WHERE user_id = $1 AND actor_id = $2;
+
+-- name: CountRemoteFollowers :one
+SELECT COUNT(*) FROM remote_followers
+WHERE user_id = $1;
+
+-- name: UpsertRemoteInteraction :exec
+INSERT INTO remote_interactions (chirp_id, actor_id, kind, activity_id, created_at)
+VALUES ($1, $2, $3, $4, NOW())
+ON CONFLICT (chirp_id, actor_id, kind)
+DO UPDATE SET activity_id = EXCLUDED.activity_id;
+
+-- name: DeleteRemoteInteraction :exec
+DELETE FROM remote_interactions
+WHERE actor_id = $1 AND activity_id = $2;
+
+-- name: CreateDelivery :exec
+INSERT INTO deliveries (id, created_at, user_id, inbox, payload, next_attempt_at)
+VALUES (gen_random_uuid(), NOW(), $1, $2, $3, NOW());
+
+-- name: CreateFollowerDeliveries :exec
+-- Followers on the same server usually share an inbox, which gets the
+-- activity once.
+INSERT INTO deliveries (id, created_at, user_id, inbox, payload, next_attempt_at)
+SELECT gen_random_uuid(), NOW(), sqlc.arg(user_id), inbox, sqlc.arg(payload), NOW()
+FROM (SELECT DISTINCT inbox FROM remote_followers WHERE user_id = sqlc.arg(user_id)) AS inboxes;
+
+-- name: ClaimDueDeliveries :many
+-- Claimed deliveries are pushed back, so other instances leave them alone
+-- while they're attempted.
+UPDATE deliveries
+SET attempts = attempts + 1, next_attempt_at = NOW() + INTERVAL '10 minutes'
+WHERE id IN (
+    SELECT id FROM deliveries
+    WHERE next_attempt_at <= NOW()
+    ORDER BY next_attempt_at
+    LIMIT $1
+    FOR UPDATE SKIP LOCKED
+)
+RETURNING deliveries.*, (
+    SELECT private_key_pem FROM actor_keys WHERE actor_keys.user_id = deliveries.user_id
+)::text AS private_key_pem;
+
+-- name: RetryDelivery :exec
+UPDATE deliveries
+SET next_attempt_at = $2
+WHERE id = $1;
+
+-- name: DeleteDelivery :exec
+DELETE FROM deliveries
+WHERE id = $1;
\ No newline at end of file
@@ -0,0 +1,80 @@
+-- name: DeleteStaleHashtagBuckets :exec
+-- Clears the buckets holding a chirp published, deleted, removed or restored,
+-- or whose author was suspended, since the given time, for
+-- UpsertHashtagBuckets to count again.
+DELETE FROM hashtag_buckets
+WHERE bucket_start IN (
+    SELECT date_bin('5 minutes', chirps.published_at, TIMESTAMP '2000-01-01') FROM chirps
+    JOIN users ON users.id = chirps.user_id
+    WHERE (chirps.updated_at >= sqlc.arg(since)::timestamp OR users.suspended_at >= sqlc.arg(since)::timestamp)
+    AND chirps.published_at IS NOT NULL
+);
+
+-- name: UpsertHashtagBuckets :exec
+-- Counts the live chirps by authors in good standing of every bucket holding
+-- a chirp published, deleted, removed or restored, or whose author was
+-- suspended, since the given time.
+INSERT INTO hashtag_buckets (hashtag_id, bucket_start, uses)
+SELECT chirp_hashtags.hashtag_id, stale.bucket_start, COUNT(*)
+FROM (
+    SELECT DISTINCT date_bin('5 minutes', chirps.published_at, TIMESTAMP '2000-01-01') AS bucket_start
+    FROM chirps
+    JOIN users ON users.id = chirps.user_id
+    WHERE (chirps.updated_at >= sqlc.arg(since)::timestamp OR users.suspended_at >= sqlc.arg(since)::timestamp)
+    AND chirps.published_at IS NOT NULL
+) AS stale
+JOIN chirps ON chirps.published_at >= stale.bucket_start AND chirps.published_at < stale.bucket_start + INTERVAL '5 minutes'
+JOIN users ON users.id = chirps.user_id AND users.suspended_at IS NULL
+JOIN chirp_hashtags ON chirp_hashtags.chirp_id = chirps.id
+WHERE chirps.removed_at IS NULL AND chirps.deleted_at IS NULL
+GROUP BY 1, 2
+ON CONFLICT (hashtag_id, bucket_start) DO UPDATE SET uses = EXCLUDED.uses;
+
+-- name: GetDatabaseTime :one
+-- Returns the wall time NOW() stamps TIMESTAMP columns with, which the
+-- buckets are binned by.
+SELECT NOW()::timestamp AS now;
+
+-- name: DeleteHashtagBucketsBefore :exec
+DELETE FROM hashtag_buckets
+WHERE bucket_start < $1;
+
+-- name: GetHashtagWindowCounts :many
+SELECT hashtag_buckets.hashtag_id,
+    COALESCE(SUM(hashtag_buckets.uses) FILTER (WHERE hashtag_buckets.bucket_start >= sqlc.arg(current_start)::timestamp), 0)::bigint AS uses,
+    COALESCE(SUM(hashtag_buckets.uses) FILTER (WHERE hashtag_buckets.bucket_start < sqlc.arg(current_start)::timestamp), 0)::bigint AS previous_uses
+FROM hashtag_buckets
+JOIN hashtags ON hashtags.id = hashtag_buckets.hashtag_id
+WHERE hashtag_buckets.bucket_start >= sqlc.arg(previous_start)::timestamp
+AND NOT EXISTS (SELECT 1 FROM hashtag_denylist WHERE hashtag_denylist.tag = hashtags.tag)
+GROUP BY hashtag_buckets.hashtag_id;
+
+-- name: DeleteTrendingHashtags :exec
+DELETE FROM trending_hashtags
+WHERE time_window = $1;
+
+-- name: CreateTrendingHashtag :exec
+INSERT INTO trending_hashtags (time_window, hashtag_id, rank, uses, previous_uses, score, computed_at)
+VALUES ($1, $2, $3, $4, $5, $6, NOW());
+
+-- name: GetTrendingHashtags :many
+SELECT trending_hashtags.time_window, hashtags.tag, trending_hashtags.rank, trending_hashtags.uses,
+    trending_hashtags.previous_uses, trending_hashtags.score, trending_hashtags.computed_at
+FROM trending_hashtags
+JOIN hashtags ON hashtags.id = trending_hashtags.hashtag_id
+WHERE NOT EXISTS (SELECT 1 FROM hashtag_denylist WHERE hashtag_denylist.tag = hashtags.tag)
+ORDER BY trending_hashtags.time_window, trending_hashtags.rank;
+
+-- name: CreateDenylistedHashtag :one
+INSERT INTO hashtag_denylist (tag, created_at, reason)
+VALUES ($1, NOW(), $2)
+ON CONFLICT (tag) DO UPDATE SET reason = EXCLUDED.reason
+RETURNING *;
+
+-- name: GetDenylistedHashtags :many
+SELECT * FROM hashtag_denylist
+ORDER BY tag;
+
+-- name: DeleteDenylistedHashtag :exec
+DELETE FROM hashtag_denylist
+WHERE tag = $1;
\ No newline at end of file
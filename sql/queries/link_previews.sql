@@ -0,0 +1,39 @@
+-- name: QueueLinkPreview :exec
+INSERT INTO link_previews (url, created_at, next_attempt_at)
+VALUES ($1, NOW(), NOW())
+ON CONFLICT (url) DO NOTHING;
+
+-- name: ClaimDueLinkPreviews :many
+-- Claimed previews are pushed back, so other instances leave them alone
+-- while they're fetched.
+UPDATE link_previews
+SET attempts = attempts + 1, next_attempt_at = NOW() + INTERVAL '5 minutes'
+WHERE url IN (
+    SELECT url FROM link_previews
+    WHERE fetched_at IS NULL AND next_attempt_at <= NOW()
+    ORDER BY next_attempt_at
+    LIMIT $1
+    FOR UPDATE SKIP LOCKED
+)
+RETURNING url, attempts;
+
+-- name: SaveLinkPreview :exec
+UPDATE link_previews
+SET fetched_at = NOW(), title = $2, description = $3, image_url = $4, site_name = $5
+WHERE url = $1;
+
+-- name: RetryLinkPreview :exec
+UPDATE link_previews
+SET next_attempt_at = $2
+WHERE url = $1;
+
+-- name: GetLinkPreviewsByChirpIds :many
+-- Only the first link of a chirp that has a preview is returned.
+SELECT DISTINCT ON (chirp_links.chirp_id)
+    chirp_links.chirp_id, link_previews.url, link_previews.title, link_previews.description,
+    link_previews.image_url, link_previews.site_name
+FROM chirp_links
+JOIN link_previews ON link_previews.url = chirp_links.url
+WHERE chirp_links.chirp_id = ANY(sqlc.arg(chirp_ids)::uuid[])
+AND link_previews.fetched_at IS NOT NULL AND link_previews.title <> ''
+ORDER BY chirp_links.chirp_id, chirp_links.start_offset;
\ No newline at end of file
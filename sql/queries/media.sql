@@ -0,0 +1,21 @@
+-- name: CreateMedia :one
+INSERT INTO media (id, created_at, user_id, storage_key, thumbnail_key, content_type, width, height, alt_text)
+VALUES ($1, NOW(), $2, $3, $4, $5, $6, $7, $8)
+RETURNING *;
+
+-- name: GetMediaById :one
+SELECT * FROM media
+WHERE id = $1
+LIMIT 1;
+
+-- name: AttachMediaToChirp :exec
+INSERT INTO chirp_media (chirp_id, media_id, position)
+VALUES ($1, $2, $3);
+
+-- name: GetChirpMediaByChirpIds :many
+SELECT chirp_media.chirp_id, chirp_media.position, media.id, media.storage_key, media.thumbnail_key,
+    media.content_type, media.width, media.height, media.alt_text
+FROM chirp_media
+JOIN media ON media.id = chirp_media.media_id
+WHERE chirp_media.chirp_id = ANY(sqlc.arg(chirp_ids)::uuid[])
+ORDER BY chirp_media.position;
\ No newline at end of file
@@ -0,0 +1,99 @@
+-- name: CreateConversation :one
+INSERT INTO conversations (id, created_at, updated_at, direct_key)
+VALUES (gen_random_uuid(), NOW(), NOW(), $1)
+RETURNING *;
+
+-- name: GetConversationByDirectKey :one
+SELECT * FROM conversations
+WHERE direct_key = $1;
+
+-- name: AddConversationMember :exec
+INSERT INTO conversation_members (conversation_id, user_id, joined_at)
+VALUES ($1, $2, NOW());
+
+-- name: GetMemberConversation :one
+SELECT conversations.* FROM conversations
+JOIN conversation_members ON conversation_members.conversation_id = conversations.id
+WHERE conversations.id = $1 AND conversation_members.user_id = $2;
+
+-- name: GetConversations :many
+SELECT conversations.*, (
+    SELECT COUNT(*) FROM messages
+    JOIN users ON users.id = messages.sender_id AND users.suspended_at IS NULL
+    WHERE messages.conversation_id = conversations.id
+    AND messages.sender_id <> sqlc.arg(user_id)
+    AND NOT messages.sender_id = ANY(sqlc.arg(excluded_user_ids)::uuid[])
+    AND (conversation_members.last_read_at IS NULL OR messages.created_at > conversation_members.last_read_at)
+) AS unread_count
+FROM conversations
+JOIN conversation_members ON conversation_members.conversation_id = conversations.id
+WHERE conversation_members.user_id = sqlc.arg(user_id)
+AND (
+    sqlc.narg(before_updated_at)::timestamp IS NULL
+    OR (conversations.updated_at, conversations.id) < (sqlc.narg(before_updated_at)::timestamp, sqlc.narg(before_id)::uuid)
+)
+ORDER BY conversations.updated_at DESC, conversations.id DESC
+LIMIT sqlc.arg('limit');
+
+-- name: GetConversation :one
+SELECT conversations.*, (
+    SELECT COUNT(*) FROM messages
+    JOIN users ON users.id = messages.sender_id AND users.suspended_at IS NULL
+    WHERE messages.conversation_id = conversations.id
+    AND messages.sender_id <> sqlc.arg(user_id)
+    AND NOT messages.sender_id = ANY(sqlc.arg(excluded_user_ids)::uuid[])
+    AND (conversation_members.last_read_at IS NULL OR messages.created_at > conversation_members.last_read_at)
+) AS unread_count
+FROM conversations
+JOIN conversation_members ON conversation_members.conversation_id = conversations.id
+WHERE conversations.id = sqlc.arg(id) AND conversation_members.user_id = sqlc.arg(user_id);
+
+-- name: GetConversationMembers :many
+SELECT conversation_id, user_id FROM conversation_members
+WHERE conversation_id = ANY(sqlc.arg(conversation_ids)::uuid[])
+ORDER BY joined_at, user_id;
+
+-- name: IsBlockedInConversation :one
+SELECT EXISTS (
+    SELECT 1 FROM conversation_members
+    JOIN blocks ON (blocks.blocker_id = conversation_members.user_id AND blocks.blocked_id = sqlc.arg(user_id))
+        OR (blocks.blocker_id = sqlc.arg(user_id) AND blocks.blocked_id = conversation_members.user_id)
+    WHERE conversation_members.conversation_id = sqlc.arg(conversation_id)
+);
+
+-- name: MarkConversationRead :exec
+UPDATE conversation_members
+SET last_read_at = NOW()
+WHERE conversation_id = $1 AND user_id = $2;
+
+-- name: CreateMessage :one
+INSERT INTO messages (id, created_at, conversation_id, sender_id, body)
+VALUES (gen_random_uuid(), NOW(), $1, $2, $3)
+RETURNING *;
+
+-- name: TouchConversation :exec
+UPDATE conversations
+SET updated_at = NOW()
+WHERE id = $1;
+
+-- name: GetMessageById :one
+SELECT * FROM messages
+WHERE id = $1;
+
+-- name: GetMessages :many
+SELECT messages.* FROM messages
+JOIN users ON users.id = messages.sender_id AND users.suspended_at IS NULL
+WHERE messages.conversation_id = sqlc.arg(conversation_id)
+AND NOT messages.sender_id = ANY(sqlc.arg(excluded_user_ids)::uuid[])
+AND (
+    sqlc.narg(before_created_at)::timestamp IS NULL
+    OR (messages.created_at, messages.id) < (sqlc.narg(before_created_at)::timestamp, sqlc.narg(before_id)::uuid)
+)
+ORDER BY messages.created_at DESC, messages.id DESC
+LIMIT sqlc.arg('limit');
+
+-- name: GetLastMessages :many
+SELECT DISTINCT ON (messages.conversation_id) messages.* FROM messages
+JOIN users ON users.id = messages.sender_id AND users.suspended_at IS NULL
+WHERE messages.conversation_id = ANY(sqlc.arg(conversation_ids)::uuid[])
+ORDER BY messages.conversation_id, messages.created_at DESC, messages.id DESC;
\ No newline at end of file
@@ -0,0 +1,60 @@
+-- name: OpenChirpCase :one
+INSERT INTO moderation_cases (id, created_at, updated_at, chirp_id, user_id, status)
+VALUES (gen_random_uuid(), NOW(), NOW(), $1, $2, 'open')
+ON CONFLICT (chirp_id) WHERE status = 'open' AND chirp_id IS NOT NULL
+DO UPDATE SET updated_at = NOW()
+RETURNING *;
+
+-- name: OpenUserCase :one
+INSERT INTO moderation_cases (id, created_at, updated_at, chirp_id, user_id, status)
+VALUES (gen_random_uuid(), NOW(), NOW(), NULL, $1, 'open')
+ON CONFLICT (user_id) WHERE status = 'open' AND chirp_id IS NULL
+DO UPDATE SET updated_at = NOW()
+RETURNING *;
+
+-- name: CreateReport :exec
+INSERT INTO reports (id, created_at, case_id, reporter_id, category, comment)
+VALUES (gen_random_uuid(), NOW(), $1, $2, $3, $4)
+ON CONFLICT (case_id, reporter_id) DO NOTHING;
+
+-- name: GetModerationCases :many
+SELECT moderation_cases.*, COUNT(reports.id) AS report_count,
+    ARRAY_AGG(DISTINCT reports.category)::text[] AS categories
+FROM moderation_cases
+JOIN reports ON reports.case_id = moderation_cases.id
+WHERE moderation_cases.status = $1
+GROUP BY moderation_cases.id
+ORDER BY report_count DESC, moderation_cases.created_at
+LIMIT $2 OFFSET $3;
+
+-- name: GetModerationCaseById :one
+SELECT * FROM moderation_cases
+WHERE id = $1
+LIMIT 1;
+
+-- name: GetCaseReports :many
+SELECT * FROM reports
+WHERE case_id = $1
+ORDER BY created_at;
+
+-- name: GetCaseActions :many
+SELECT * FROM moderation_actions
+WHERE case_id = $1
+ORDER BY created_at;
+
+-- name: ResolveModerationCase :one
+UPDATE moderation_cases
+SET updated_at = NOW(), status = 'resolved', resolved_at = NOW()
+WHERE id = $1 AND status = 'open'
+RETURNING *;
+
+-- name: CreateModerationAction :exec
+INSERT INTO moderation_actions (id, created_at, case_id, moderator_id, action, note)
+VALUES (gen_random_uuid(), NOW(), $1, $2, $3, $4);
+
+-- name: GetUserWarnings :many
+SELECT moderation_actions.id, moderation_actions.created_at, moderation_actions.note, moderation_cases.chirp_id
+FROM moderation_actions
+JOIN moderation_cases ON moderation_cases.id = moderation_actions.case_id
+WHERE moderation_cases.user_id = $1 AND moderation_actions.action = 'warn'
+ORDER BY moderation_actions.created_at DESC;
\ No newline at end of file
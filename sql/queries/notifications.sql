@@ -0,0 +1,59 @@
+-- name: UpsertNotification :one
+INSERT INTO notifications (id, created_at, updated_at, user_id, kind, chirp_id)
+VALUES (gen_random_uuid(), NOW(), NOW(), $1, $2, $3)
+ON CONFLICT (user_id, kind, chirp_id) WHERE read_at IS NULL
+DO UPDATE SET updated_at = NOW()
+RETURNING *;
+
+-- name: AddNotificationActor :exec
+INSERT INTO notification_actors (notification_id, actor_id, created_at)
+VALUES ($1, $2, NOW())
+ON CONFLICT DO NOTHING;
+
+-- name: GetNotifications :many
+SELECT notifications.* FROM notifications
+JOIN chirps ON chirps.id = notifications.chirp_id
+WHERE notifications.user_id = sqlc.arg(user_id)
+AND chirps.removed_at IS NULL AND chirps.deleted_at IS NULL
+AND (
+    sqlc.narg(before_updated_at)::timestamp IS NULL
+    OR (notifications.updated_at, notifications.id) < (sqlc.narg(before_updated_at)::timestamp, sqlc.narg(before_id)::uuid)
+)
+ORDER BY notifications.updated_at DESC, notifications.id DESC
+LIMIT sqlc.arg('limit');
+
+-- name: GetNotificationActors :many
+SELECT notification_actors.notification_id, notification_actors.actor_id FROM notification_actors
+JOIN users ON users.id = notification_actors.actor_id AND users.suspended_at IS NULL
+WHERE notification_actors.notification_id = ANY(sqlc.arg(notification_ids)::uuid[])
+ORDER BY notification_actors.created_at DESC;
+
+-- name: GetNotificationById :one
+SELECT * FROM notifications
+WHERE id = $1 AND user_id = $2;
+
+-- name: CountUnreadNotifications :one
+-- Only notifications with an actor the user can still see are counted, as
+-- the others aren't listed either.
+SELECT COUNT(*) FROM notifications
+JOIN chirps ON chirps.id = notifications.chirp_id
+WHERE notifications.user_id = sqlc.arg(user_id) AND notifications.read_at IS NULL
+AND chirps.removed_at IS NULL AND chirps.deleted_at IS NULL
+AND EXISTS (
+    SELECT 1 FROM notification_actors
+    JOIN users ON users.id = notification_actors.actor_id
+    WHERE notification_actors.notification_id = notifications.id
+    AND users.suspended_at IS NULL
+    AND NOT notification_actors.actor_id = ANY(sqlc.arg(excluded_user_ids)::uuid[])
+);
+
+-- name: MarkNotificationRead :one
+UPDATE notifications
+SET read_at = COALESCE(read_at, NOW())
+WHERE id = $1 AND user_id = $2
+RETURNING id;
+
+-- name: MarkAllNotificationsRead :exec
+UPDATE notifications
+SET read_at = NOW()
+WHERE user_id = $1 AND read_at IS NULL;
\ No newline at end of file
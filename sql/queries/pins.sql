@@ -0,0 +1,23 @@
+-- name: CreatePinnedChirp :exec
+INSERT INTO pinned_chirps (user_id, chirp_id, pinned_at)
+VALUES ($1, $2, NOW())
+ON CONFLICT DO NOTHING;
+
+-- name: DeletePinnedChirp :exec
+DELETE FROM pinned_chirps
+WHERE user_id = $1 AND chirp_id = $2;
+
+-- name: CountOtherPinnedChirps :one
+SELECT COUNT(*) FROM pinned_chirps
+WHERE user_id = $1 AND chirp_id <> $2;
+
+-- name: GetPinnedChirpsByUserId :many
+SELECT chirps.* FROM chirps
+JOIN pinned_chirps ON pinned_chirps.chirp_id = chirps.id
+JOIN users ON users.id = chirps.user_id AND users.suspended_at IS NULL
+WHERE pinned_chirps.user_id = $1 AND chirps.published_at IS NOT NULL AND chirps.removed_at IS NULL AND chirps.deleted_at IS NULL
+ORDER BY pinned_chirps.pinned_at DESC;
+
+-- name: GetPinnedChirpIds :many
+SELECT chirp_id FROM pinned_chirps
+WHERE chirp_id = ANY(sqlc.arg(chirp_ids)::uuid[]);
\ No newline at end of file
@@ -0,0 +1,34 @@
+-- name: CreatePoll :one
+INSERT INTO polls (id, created_at, chirp_id, closes_at)
+VALUES (gen_random_uuid(), NOW(), $1, $2)
+RETURNING *;
+
+-- name: CreatePollOption :exec
+INSERT INTO poll_options (id, poll_id, position, text)
+VALUES (gen_random_uuid(), $1, $2, $3);
+
+-- name: GetPollByChirpId :one
+SELECT * FROM polls
+WHERE chirp_id = $1
+LIMIT 1;
+
+-- name: GetPollsByChirpIds :many
+SELECT * FROM polls
+WHERE chirp_id = ANY(sqlc.arg(chirp_ids)::uuid[]);
+
+-- name: GetPollOptionsByPollIds :many
+SELECT poll_options.id, poll_options.poll_id, poll_options.position, poll_options.text,
+    COUNT(poll_votes.user_id) AS votes
+FROM poll_options
+LEFT JOIN poll_votes ON poll_votes.option_id = poll_options.id
+WHERE poll_options.poll_id = ANY(sqlc.arg(poll_ids)::uuid[])
+GROUP BY poll_options.id
+ORDER BY poll_options.position;
+
+-- name: GetUserPollVotes :many
+SELECT * FROM poll_votes
+WHERE user_id = sqlc.arg(user_id) AND poll_id = ANY(sqlc.arg(poll_ids)::uuid[]);
+
+-- name: CreatePollVote :exec
+INSERT INTO poll_votes (poll_id, option_id, user_id, created_at)
+VALUES ($1, $2, $3, NOW());
\ No newline at end of file
@@ -0,0 +1,2 @@
+-- name: PublishRealtimeEvent :exec
+SELECT pg_notify('realtime', sqlc.arg(payload)::text);
\ No newline at end of file
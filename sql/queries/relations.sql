@@ -0,0 +1,54 @@
+-- name: CreateBlock :exec
+INSERT INTO blocks (blocker_id, blocked_id, created_at)
+VALUES ($1, $2, NOW())
+ON CONFLICT DO NOTHING;
+
+-- name: DeleteBlock :exec
+DELETE FROM blocks
+WHERE blocker_id = $1 AND blocked_id = $2;
+
+-- name: CreateMute :exec
+INSERT INTO mutes (muter_id, muted_id, created_at)
+VALUES ($1, $2, NOW())
+ON CONFLICT DO NOTHING;
+
+-- name: DeleteMute :exec
+DELETE FROM mutes
+WHERE muter_id = $1 AND muted_id = $2;
+
+-- name: GetBlockedUserIds :many
+SELECT blocked_id FROM blocks
+WHERE blocker_id = $1
+UNION
+SELECT blocker_id FROM blocks
+WHERE blocked_id = $1;
+
+-- name: GetMutedUserIds :many
+SELECT muted_id FROM mutes
+WHERE muter_id = $1;
+
+-- name: IsBlockedBetween :one
+SELECT EXISTS (
+    SELECT 1 FROM blocks
+    WHERE (blocker_id = $1 AND blocked_id = $2) OR (blocker_id = $2 AND blocked_id = $1)
+);
+
+-- name: GetBlocks :many
+SELECT users.* FROM users
+JOIN blocks ON blocks.blocked_id = users.id
+WHERE blocks.blocker_id = $1
+ORDER BY blocks.created_at DESC
+LIMIT $2 OFFSET $3;
+
+-- name: GetMutes :many
+SELECT users.* FROM users
+JOIN mutes ON mutes.muted_id = users.id
+WHERE mutes.muter_id = $1
+ORDER BY mutes.created_at DESC
+LIMIT $2 OFFSET $3;
+
+-- name: IsMuted :one
+SELECT EXISTS (
+    SELECT 1 FROM mutes
+    WHERE muter_id = $1 AND muted_id = $2
+);
\ No newline at end of file
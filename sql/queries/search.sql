@@ -0,0 +1,10 @@
+-- name: SearchUsers :many
+SELECT * FROM users
+WHERE (handle ILIKE sqlc.arg(prefix) OR display_name ILIKE sqlc.arg(prefix)
+    OR handle % sqlc.arg(query)::text OR display_name % sqlc.arg(query)::text)
+    AND NOT id = ANY(sqlc.arg(excluded_user_ids)::uuid[]) AND suspended_at IS NULL
+ORDER BY LOWER(handle) = LOWER(sqlc.arg(query)) DESC,
+    (SELECT COUNT(*) FROM remote_followers WHERE remote_followers.user_id = users.id) DESC,
+    GREATEST(similarity(handle, sqlc.arg(query)), similarity(display_name, sqlc.arg(query))) DESC,
+    handle
+LIMIT sqlc.arg('limit') OFFSET sqlc.arg('offset');
\ No newline at end of file
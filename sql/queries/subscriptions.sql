@@ -0,0 +1,26 @@
+-- name: ExpireSubscriptions :execrows
+UPDATE subscriptions
+SET updated_at = NOW(), status = 'expired'
+WHERE status = 'active' AND source <> 'legacy' AND current_period_end <= sqlc.arg(now)::timestamptz;
+
+-- name: GetActiveSubscription :one
+SELECT * FROM subscriptions
+WHERE user_id = $1 AND status = 'active';
+
+-- name: RenewSubscription :one
+-- Renewing before the period ends adds a period after it. A lapsed or legacy
+-- subscription starts its new period now.
+INSERT INTO subscriptions (id, user_id, plan, status, current_period_start, current_period_end, source, created_at, updated_at)
+VALUES (
+    gen_random_uuid(), sqlc.arg(user_id), sqlc.arg(plan), 'active',
+    NOW(), NOW() + make_interval(days => sqlc.arg(period_days)::int),
+    sqlc.arg(source), NOW(), NOW()
+)
+ON CONFLICT (user_id) WHERE status = 'active'
+DO UPDATE SET
+    updated_at = NOW(),
+    plan = EXCLUDED.plan,
+    source = EXCLUDED.source,
+    current_period_start = CASE WHEN subscriptions.source = 'legacy' THEN NOW() ELSE GREATEST(subscriptions.current_period_end, NOW()) END,
+    current_period_end = CASE WHEN subscriptions.source = 'legacy' THEN NOW() ELSE GREATEST(subscriptions.current_period_end, NOW()) END + make_interval(days => sqlc.arg(period_days)::int)
+RETURNING *;
\ No newline at end of file
@@ -1,6 +1,6 @@
 -- name: CreateUser :one
-INSERT INTO users (id, created_at, updated_at, email, hashed_password)
-VALUES (gen_random_uuid(), NOW(), NOW(), $1, $2)
+INSERT INTO users (id, created_at, updated_at, email, hashed_password, handle)
+VALUES (gen_random_uuid(), NOW(), NOW(), $1, $2, $3)
 RETURNING *;
 
 -- name: DeleteAllUsers :exec
@@ -11,18 +11,44 @@ SELECT * FROM users
 WHERE email = $1
 LIMIT 1;
 
+-- name: GetUserByHandle :one
+SELECT * FROM users
+WHERE LOWER(handle) = LOWER($1)
+LIMIT 1;
+
 -- name: GetUserById :one
 SELECT * FROM users
 WHERE users.id = $1
 LIMIT 1;
 
+-- name: GetUsersByIds :many
+SELECT * FROM users
+WHERE id = ANY(sqlc.arg(ids)::uuid[]);
+
+-- name: LockUser :exec
+SELECT id FROM users
+WHERE id = $1
+FOR UPDATE;
+
 -- name: UpdateUserForId :one
 UPDATE users
 SET updated_at = NOW(), email = $2, hashed_password = $3
 WHERE id = $1
 RETURNING *;
 
--- name: UpgradeUserById :exec
+-- name: UpdateUserAvatar :one
+UPDATE users
+SET updated_at = NOW(), avatar_key = $2
+WHERE id = $1
+RETURNING *;
+
+-- name: UpdateUserProfile :one
+UPDATE users
+SET updated_at = NOW(), handle = $2, display_name = $3, bio = $4
+WHERE id = $1
+RETURNING *;
+
+-- name: SuspendUser :exec
 UPDATE users
-SET updated_at = NOW(), is_chirpy_red = true
-WHERE id = $1;
\ No newline at end of file
+SET updated_at = NOW(), suspended_at = NOW()
+WHERE id = $1 AND suspended_at IS NULL;
\ No newline at end of file
@@ -0,0 +1,14 @@
+-- +goose Up
+ALTER TABLE users
+ADD is_admin BOOLEAN DEFAULT false;
+
+UPDATE users
+SET is_admin = false;
+
+ALTER TABLE users
+ALTER COLUMN is_admin
+SET NOT NULL;
+
+-- +goose Down
+ALTER TABLE users
+DROP COLUMN is_admin;
@@ -0,0 +1,31 @@
+-- +goose Up
+CREATE TABLE hashtag_buckets (
+    hashtag_id UUID NOT NULL REFERENCES hashtags(id) ON DELETE CASCADE,
+    bucket_start TIMESTAMP NOT NULL,
+    uses INTEGER NOT NULL,
+    PRIMARY KEY (hashtag_id, bucket_start)
+);
+
+CREATE INDEX hashtag_buckets_bucket_start_idx ON hashtag_buckets (bucket_start);
+
+CREATE TABLE trending_hashtags (
+    time_window TEXT NOT NULL,
+    hashtag_id UUID NOT NULL REFERENCES hashtags(id) ON DELETE CASCADE,
+    rank INTEGER NOT NULL,
+    uses BIGINT NOT NULL,
+    previous_uses BIGINT NOT NULL,
+    score DOUBLE PRECISION NOT NULL,
+    computed_at TIMESTAMP NOT NULL,
+    PRIMARY KEY (time_window, hashtag_id)
+);
+
+CREATE TABLE hashtag_denylist (
+    tag TEXT PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    reason TEXT NOT NULL
+);
+
+-- +goose Down
+DROP TABLE hashtag_denylist;
+DROP TABLE trending_hashtags;
+DROP TABLE hashtag_buckets;
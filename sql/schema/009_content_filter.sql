@@ -0,0 +1,24 @@
+-- +goose Up
+CREATE TABLE filter_rules (
+    word TEXT PRIMARY KEY,
+    policy TEXT NOT NULL CHECK (policy IN ('mask', 'reject', 'flag')),
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL
+);
+
+INSERT INTO filter_rules (word, policy, created_at, updated_at)
+VALUES
+    ('kerfuffle', 'mask', NOW(), NOW()),
+    ('sharbert', 'mask', NOW(), NOW()),
+    ('fornax', 'mask', NOW(), NOW());
+
+CREATE TABLE chirp_flags (
+    id UUID PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    chirp_id UUID NOT NULL REFERENCES chirps(id) ON DELETE CASCADE,
+    reason TEXT NOT NULL
+);
+
+-- +goose Down
+DROP TABLE chirp_flags;
+DROP TABLE filter_rules;
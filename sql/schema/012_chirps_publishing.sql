@@ -0,0 +1,34 @@
+-- +goose Up
+ALTER TABLE chirps
+ADD published_at TIMESTAMP;
+
+UPDATE chirps
+SET published_at = created_at;
+
+ALTER TABLE chirps
+ADD publish_at TIMESTAMP;
+
+ALTER TABLE chirps
+ADD is_draft BOOLEAN DEFAULT false;
+
+UPDATE chirps
+SET is_draft = false;
+
+ALTER TABLE chirps
+ALTER COLUMN is_draft
+SET NOT NULL;
+
+CREATE INDEX chirps_publish_at_idx ON chirps (publish_at)
+WHERE published_at IS NULL AND NOT is_draft;
+
+-- +goose Down
+DROP INDEX chirps_publish_at_idx;
+
+ALTER TABLE chirps
+DROP COLUMN is_draft;
+
+ALTER TABLE chirps
+DROP COLUMN publish_at;
+
+ALTER TABLE chirps
+DROP COLUMN published_at;
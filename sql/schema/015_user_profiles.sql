@@ -0,0 +1,36 @@
+-- +goose Up
+ALTER TABLE users
+ADD handle TEXT;
+
+UPDATE users
+SET handle = 'user_' || LEFT(REPLACE(id::text, '-', ''), 10);
+
+ALTER TABLE users
+ALTER COLUMN handle
+SET NOT NULL;
+
+CREATE UNIQUE INDEX users_handle_idx ON users (LOWER(handle));
+
+ALTER TABLE users
+ADD display_name TEXT NOT NULL DEFAULT '';
+
+ALTER TABLE users
+ADD bio TEXT NOT NULL DEFAULT '';
+
+ALTER TABLE users
+ADD avatar_key TEXT;
+
+-- +goose Down
+ALTER TABLE users
+DROP COLUMN avatar_key;
+
+ALTER TABLE users
+DROP COLUMN bio;
+
+ALTER TABLE users
+DROP COLUMN display_name;
+
+DROP INDEX users_handle_idx;
+
+ALTER TABLE users
+DROP COLUMN handle;
@@ -0,0 +1,54 @@
+-- +goose Up
+ALTER TABLE chirps
+ADD removed_at TIMESTAMP;
+
+ALTER TABLE users
+ADD suspended_at TIMESTAMP;
+
+-- Reports of the same chirp, or of the same user outside of a chirp, are
+-- grouped in a single open case until a moderator acts on it.
+CREATE TABLE moderation_cases (
+    id UUID PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    chirp_id UUID REFERENCES chirps(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    status TEXT NOT NULL,
+    resolved_at TIMESTAMP
+);
+
+CREATE UNIQUE INDEX moderation_cases_open_chirp_idx ON moderation_cases (chirp_id)
+WHERE status = 'open' AND chirp_id IS NOT NULL;
+
+CREATE UNIQUE INDEX moderation_cases_open_user_idx ON moderation_cases (user_id)
+WHERE status = 'open' AND chirp_id IS NULL;
+
+CREATE TABLE reports (
+    id UUID PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    case_id UUID NOT NULL REFERENCES moderation_cases(id) ON DELETE CASCADE,
+    reporter_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    category TEXT NOT NULL,
+    comment TEXT NOT NULL,
+    UNIQUE (case_id, reporter_id)
+);
+
+CREATE TABLE moderation_actions (
+    id UUID PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    case_id UUID NOT NULL REFERENCES moderation_cases(id) ON DELETE CASCADE,
+    moderator_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    action TEXT NOT NULL,
+    note TEXT NOT NULL
+);
+
+-- +goose Down
+DROP TABLE moderation_actions;
+DROP TABLE reports;
+DROP TABLE moderation_cases;
+
+ALTER TABLE users
+DROP COLUMN suspended_at;
+
+ALTER TABLE chirps
+DROP COLUMN removed_at;
@@ -0,0 +1,28 @@
+-- +goose Up
+CREATE TABLE notifications (
+    id UUID PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    kind TEXT NOT NULL,
+    chirp_id UUID NOT NULL REFERENCES chirps(id) ON DELETE CASCADE,
+    read_at TIMESTAMP
+);
+
+-- Unread notifications of the same kind about the same chirp are grouped
+-- into one, so a burst shows up as a single entry with several actors.
+CREATE UNIQUE INDEX notifications_unread_group_idx ON notifications (user_id, kind, chirp_id)
+WHERE read_at IS NULL;
+
+CREATE INDEX notifications_user_idx ON notifications (user_id, updated_at DESC, id DESC);
+
+CREATE TABLE notification_actors (
+    notification_id UUID NOT NULL REFERENCES notifications(id) ON DELETE CASCADE,
+    actor_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    created_at TIMESTAMP NOT NULL,
+    PRIMARY KEY (notification_id, actor_id)
+);
+
+-- +goose Down
+DROP TABLE notification_actors;
+DROP TABLE notifications;
@@ -0,0 +1,48 @@
+-- +goose Up
+-- Keys are generated the first time a user is federated.
+CREATE TABLE actor_keys (
+    user_id UUID PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    created_at TIMESTAMP NOT NULL,
+    public_key_pem TEXT NOT NULL,
+    private_key_pem TEXT NOT NULL
+);
+
+CREATE TABLE remote_followers (
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    actor_id TEXT NOT NULL,
+    inbox TEXT NOT NULL,
+    follow_id TEXT NOT NULL,
+    created_at TIMESTAMP NOT NULL,
+    PRIMARY KEY (user_id, actor_id)
+);
+
+-- Likes and Announces of local chirps by remote actors.
+CREATE TABLE remote_interactions (
+    chirp_id UUID NOT NULL REFERENCES chirps(id) ON DELETE CASCADE,
+    actor_id TEXT NOT NULL,
+    kind TEXT NOT NULL,
+    activity_id TEXT NOT NULL,
+    created_at TIMESTAMP NOT NULL,
+    PRIMARY KEY (chirp_id, actor_id, kind)
+);
+
+CREATE INDEX remote_interactions_activity_idx ON remote_interactions (actor_id, activity_id);
+
+-- Outgoing activities waiting to be delivered, retried with backoff.
+CREATE TABLE deliveries (
+    id UUID PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    inbox TEXT NOT NULL,
+    payload TEXT NOT NULL,
+    attempts INTEGER NOT NULL DEFAULT 0,
+    next_attempt_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX deliveries_due_idx ON deliveries (next_attempt_at);
+
+-- +goose Down
+DROP TABLE deliveries;
+DROP TABLE remote_interactions;
+DROP TABLE remote_followers;
+DROP TABLE actor_keys;
@@ -0,0 +1,20 @@
+-- +goose Up
+-- Previews are cached per URL, shared by every chirp linking to it. Rows
+-- are queued when a chirp links to a new URL and fetched in the background.
+-- A fetched row with an empty title means the URL has no preview.
+CREATE TABLE link_previews (
+    url TEXT PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    fetched_at TIMESTAMP,
+    attempts INTEGER NOT NULL DEFAULT 0,
+    next_attempt_at TIMESTAMP NOT NULL,
+    title TEXT NOT NULL DEFAULT '',
+    description TEXT NOT NULL DEFAULT '',
+    image_url TEXT NOT NULL DEFAULT '',
+    site_name TEXT NOT NULL DEFAULT ''
+);
+
+CREATE INDEX link_previews_due_idx ON link_previews (next_attempt_at) WHERE fetched_at IS NULL;
+
+-- +goose Down
+DROP TABLE link_previews;
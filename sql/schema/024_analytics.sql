@@ -0,0 +1,32 @@
+-- +goose Up
+-- Daily rollups behind author analytics. Days are UTC dates.
+CREATE TABLE chirp_impressions_daily (
+    chirp_id UUID NOT NULL REFERENCES chirps(id) ON DELETE CASCADE,
+    day DATE NOT NULL,
+    impressions BIGINT NOT NULL,
+    PRIMARY KEY (chirp_id, day)
+);
+
+-- Bookmarks, poll votes and remote likes and boosts, by the day they
+-- happened.
+CREATE TABLE chirp_engagements_daily (
+    chirp_id UUID NOT NULL REFERENCES chirps(id) ON DELETE CASCADE,
+    day DATE NOT NULL,
+    engagements BIGINT NOT NULL,
+    PRIMARY KEY (chirp_id, day)
+);
+
+CREATE INDEX chirp_engagements_daily_day_idx ON chirp_engagements_daily (day);
+
+-- Follower counts as of the end of each day.
+CREATE TABLE follower_counts_daily (
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    day DATE NOT NULL,
+    followers BIGINT NOT NULL,
+    PRIMARY KEY (user_id, day)
+);
+
+-- +goose Down
+DROP TABLE follower_counts_daily;
+DROP TABLE chirp_engagements_daily;
+DROP TABLE chirp_impressions_daily;
@@ -0,0 +1,40 @@
+-- +goose Up
+-- A user has at most one active subscription. Rows are kept after they
+-- expire, so past periods stay on record.
+CREATE TABLE subscriptions (
+    id UUID PRIMARY KEY,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    plan TEXT NOT NULL,
+    status TEXT NOT NULL,
+    current_period_start TIMESTAMP NOT NULL,
+    current_period_end TIMESTAMP NOT NULL,
+    source TEXT NOT NULL,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL
+);
+
+CREATE UNIQUE INDEX subscriptions_active_user_idx ON subscriptions (user_id) WHERE status = 'active';
+CREATE INDEX subscriptions_active_end_idx ON subscriptions (current_period_end) WHERE status = 'active';
+
+-- The flag never recorded when users upgraded, so they get a full period
+-- from now.
+INSERT INTO subscriptions (id, user_id, plan, status, current_period_start, current_period_end, source, created_at, updated_at)
+SELECT gen_random_uuid(), id, 'chirpy_red', 'active', NOW(), NOW() + INTERVAL '30 days', 'legacy', NOW(), NOW()
+FROM users
+WHERE is_chirpy_red;
+
+ALTER TABLE users
+DROP COLUMN is_chirpy_red;
+
+-- +goose Down
+ALTER TABLE users
+ADD is_chirpy_red BOOLEAN NOT NULL DEFAULT false;
+
+UPDATE users
+SET is_chirpy_red = true
+WHERE id IN (
+    SELECT user_id FROM subscriptions
+    WHERE status = 'active' AND current_period_end > NOW()
+);
+
+DROP TABLE subscriptions;
\ No newline at end of file
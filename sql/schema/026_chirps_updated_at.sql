@@ -0,0 +1,10 @@
+-- +goose Up
+CREATE INDEX chirps_updated_at_idx ON chirps (updated_at);
+
+CREATE INDEX chirps_published_at_idx ON chirps (published_at)
+WHERE published_at IS NOT NULL;
+
+-- +goose Down
+DROP INDEX chirps_published_at_idx;
+
+DROP INDEX chirps_updated_at_idx;
\ No newline at end of file
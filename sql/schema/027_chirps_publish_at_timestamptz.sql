@@ -0,0 +1,12 @@
+-- +goose Up
+-- publish_at is compared to NOW(), so it has to be a point in time rather
+-- than a wall clock reading in whatever zone the session happens to use.
+-- The API has always stored it in UTC.
+ALTER TABLE chirps
+ALTER COLUMN publish_at TYPE TIMESTAMPTZ
+USING publish_at AT TIME ZONE 'UTC';
+
+-- +goose Down
+ALTER TABLE chirps
+ALTER COLUMN publish_at TYPE TIMESTAMP
+USING publish_at AT TIME ZONE 'UTC';
\ No newline at end of file
@@ -0,0 +1,20 @@
+-- +goose Up
+-- Periods are compared to the current time both in SQL and in Go, so they
+-- have to be points in time. They were written with NOW() in the session's
+-- time zone, which is what the casts read them in.
+ALTER TABLE subscriptions
+ALTER COLUMN current_period_start TYPE TIMESTAMPTZ
+USING current_period_start::timestamptz;
+
+ALTER TABLE subscriptions
+ALTER COLUMN current_period_end TYPE TIMESTAMPTZ
+USING current_period_end::timestamptz;
+
+-- +goose Down
+ALTER TABLE subscriptions
+ALTER COLUMN current_period_end TYPE TIMESTAMP
+USING current_period_end::timestamp;
+
+ALTER TABLE subscriptions
+ALTER COLUMN current_period_start TYPE TIMESTAMP
+USING current_period_start::timestamp;
\ No newline at end of file
@@ -0,0 +1,16 @@
+-- +goose Up
+-- Legacy subscriptions have no period to renew, so they stay active until
+-- Polka sends an event for the user. Bring back the ones that were expired
+-- after their first 30 days.
+UPDATE subscriptions
+SET updated_at = NOW(), status = 'active'
+WHERE source = 'legacy' AND status = 'expired'
+AND NOT EXISTS (
+    SELECT 1 FROM subscriptions AS active
+    WHERE active.user_id = subscriptions.user_id AND active.status = 'active'
+);
+
+-- +goose Down
+UPDATE subscriptions
+SET updated_at = NOW(), status = 'expired'
+WHERE source = 'legacy' AND status = 'active' AND current_period_end <= NOW();
\ No newline at end of file
@@ -0,0 +1,269 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/firerockets/chirpy/internal/activitypub"
+	"github.com/firerockets/chirpy/internal/cursor"
+	"github.com/firerockets/chirpy/internal/database"
+	"github.com/firerockets/chirpy/internal/entities"
+	"github.com/firerockets/chirpy/internal/stream"
+	"github.com/firerockets/chirpy/internal/visibility"
+	"github.com/google/uuid"
+)
+
+const (
+	streamHeartbeat   = 15 * time.Second
+	streamReplayLimit = 100
+)
+
+// chirpPublished runs what follows a chirp going out: mentioned users are
+// notified, every instance is told to stream it and it's queued for remote
+// followers. It's meant to run in the transaction that published the
+// chirp, and PostgreSQL only delivers the announcement once that commits.
+func (apiCfg *apiConfig) chirpPublished(ctx context.Context, q *database.Queries, chirp database.Chirp) error {
+	if !chirp.PublishedAt.Valid {
+		return nil
+	}
+
+	err := notifyMentions(ctx, q, chirp)
+	if err != nil {
+		return err
+	}
+
+	err = apiCfg.federate(ctx, q, chirp.UserID, activitypub.NewCreate(apiCfg.apURLs, chirp))
+	if err != nil {
+		return err
+	}
+
+	return q.AnnounceChirp(ctx, chirp.ID.String())
+}
+
+// publishingStore is the store behind the scheduled chirp publisher. It
+// runs the same follow-ups for the chirps it publishes as posting them
+// right away does.
+type publishingStore struct {
+	apiCfg *apiConfig
+}
+
+// publishBatchSize bounds how many chirps one run publishes. The rest are
+// picked up on the next tick.
+const publishBatchSize = 100
+
+// PublishDueChirps publishes each due chirp in its own transaction along
+// with its follow-ups, so a chirp is never left published without its
+// notifications and deliveries. A chirp whose follow-ups fail stays
+// scheduled and is retried on the next run.
+func (p publishingStore) PublishDueChirps(ctx context.Context) ([]database.Chirp, error) {
+	due, err := p.apiCfg.dbQueries.GetDueChirpIds(ctx, publishBatchSize)
+	if err != nil {
+		return nil, err
+	}
+
+	published := []database.Chirp{}
+
+	for _, id := range due {
+		chirp, err := p.publish(ctx, id)
+		if errors.Is(err, sql.ErrNoRows) {
+			continue
+		} else if err != nil {
+			log.Printf("Error publishing scheduled chirp %s: %s\n", id, err)
+			continue
+		}
+
+		published = append(published, chirp)
+	}
+
+	return published, nil
+}
+
+func (p publishingStore) publish(ctx context.Context, id uuid.UUID) (database.Chirp, error) {
+	tx, err := p.apiCfg.db.BeginTx(ctx, nil)
+	if err != nil {
+		return database.Chirp{}, err
+	}
+	defer tx.Rollback()
+
+	qtx := p.apiCfg.dbQueries.WithTx(tx)
+
+	chirp, err := qtx.PublishDueChirp(ctx, id)
+	if err != nil {
+		return database.Chirp{}, err
+	}
+
+	err = p.apiCfg.chirpPublished(ctx, qtx, chirp)
+	if err != nil {
+		return database.Chirp{}, err
+	}
+
+	return chirp, tx.Commit()
+}
+
+// streamHandler pushes newly published chirps as Server-Sent Events. The
+// feed can be narrowed to an author and/or a hashtag. Each event ID is a
+// cursor, so a client reconnecting with Last-Event-ID first gets what it
+// missed from the database.
+func (apiCfg *apiConfig) streamHandler(w http.ResponseWriter, req *http.Request) {
+	viewerID := apiCfg.viewerID(req)
+	filter := stream.Filter{}
+
+	if author := req.URL.Query().Get("author"); author != "" {
+		usr, err := apiCfg.lookupUser(req.Context(), author)
+
+		if errors.Is(err, sql.ErrNoRows) {
+			respondWithError(w, "User not found", http.StatusNotFound)
+			return
+		} else if err != nil {
+			respondWithError(w, "Something went wrong", http.StatusInternalServerError)
+			log.Printf("Error looking up user: %s\n", err)
+			return
+		}
+
+		filter.AuthorID = uuid.NullUUID{UUID: usr.ID, Valid: true}
+	}
+
+	if tag := req.URL.Query().Get("hashtag"); tag != "" {
+		filter.Hashtag = entities.NormalizeTag(tag)
+	}
+
+	var resumeFrom *cursor.Cursor
+
+	if lastEventID := req.Header.Get("Last-Event-ID"); lastEventID != "" {
+		after, err := cursor.Parse(lastEventID)
+
+		if err != nil {
+			respondWithError(w, "Invalid Last-Event-ID", http.StatusBadRequest)
+			return
+		}
+
+		resumeFrom = &after
+	}
+
+	flusher, ok := w.(http.Flusher)
+
+	if !ok {
+		respondWithError(w, "Streaming is not supported", http.StatusInternalServerError)
+		log.Println("Response writer can't flush")
+		return
+	}
+
+	// Subscribe before replaying so nothing published in between is lost.
+	sub := apiCfg.streamHub.Subscribe(filter)
+	defer apiCfg.streamHub.Unsubscribe(sub)
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	replayed := map[uuid.UUID]bool{}
+
+	if resumeFrom != nil {
+		err := apiCfg.replayChirps(req.Context(), w, filter, viewerID, *resumeFrom, replayed)
+
+		if err != nil {
+			log.Printf("Error replaying chirps: %s\n", err)
+			return
+		}
+
+		flusher.Flush()
+	}
+
+	heartbeat := time.NewTicker(streamHeartbeat)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-req.Context().Done():
+			return
+		case <-heartbeat.C:
+			if _, err := fmt.Fprint(w, ": heartbeat\n\n"); err != nil {
+				return
+			}
+			flusher.Flush()
+		case chirp, ok := <-sub.Chirps():
+			if !ok {
+				return
+			}
+			if replayed[chirp.ID] {
+				continue
+			}
+
+			if err := apiCfg.writeChirpEvents(req.Context(), w, []database.Chirp{chirp}, viewerID); err != nil {
+				log.Printf("Error streaming chirp: %s\n", err)
+				return
+			}
+			flusher.Flush()
+		}
+	}
+}
+
+// replayChirps writes the chirps matching filter that were published after
+// the given cursor, and records them in replayed.
+func (apiCfg *apiConfig) replayChirps(ctx context.Context, w http.ResponseWriter, filter stream.Filter, viewerID uuid.NullUUID, after cursor.Cursor, replayed map[uuid.UUID]bool) error {
+	rel, err := apiCfg.relations(ctx, viewerID)
+	if err != nil {
+		return err
+	}
+
+	for {
+		chirps, err := apiCfg.dbQueries.GetChirpsPublishedAfter(ctx, database.GetChirpsPublishedAfterParams{
+			AfterPublishedAt: after.At,
+			AfterID:          after.ID,
+			AuthorID:         filter.AuthorID,
+			Hashtag:          sql.NullString{String: filter.Hashtag, Valid: filter.Hashtag != ""},
+			ExcludedUserIds:  rel.Excluded(visibility.Feed),
+			Limit:            streamReplayLimit,
+		})
+		if err != nil {
+			return err
+		}
+
+		if err := apiCfg.writeChirpEvents(ctx, w, chirps, viewerID); err != nil {
+			return err
+		}
+
+		for _, c := range chirps {
+			replayed[c.ID] = true
+		}
+
+		if len(chirps) < streamReplayLimit {
+			return nil
+		}
+
+		last := chirps[len(chirps)-1]
+		after = cursor.Cursor{At: last.PublishedAt.Time, ID: last.ID}
+	}
+}
+
+// writeChirpEvents writes one event per chirp the viewer can see.
+func (apiCfg *apiConfig) writeChirpEvents(ctx context.Context, w http.ResponseWriter, chirps []database.Chirp, viewerID uuid.NullUUID) error {
+	chirpsResponse, err := apiCfg.chirpResponses(ctx, chirps, viewerID, visibility.Feed)
+	if err != nil {
+		return err
+	}
+
+	for _, c := range chirpsResponse {
+		data, err := json.Marshal(c)
+		if err != nil {
+			return err
+		}
+
+		eventID := cursor.Cursor{At: *c.PublishedAt, ID: uuid.MustParse(c.ID)}
+
+		_, err = fmt.Fprintf(w, "id: %s\nevent: chirp\ndata: %s\n\n", eventID, data)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/firerockets/chirpy/internal/trending"
+)
+
+type trendingHashtagResponse struct {
+	Tag          string    `json:"tag"`
+	Rank         int32     `json:"rank"`
+	Uses         int64     `json:"uses"`
+	PreviousUses int64     `json:"previous_uses"`
+	Score        float64   `json:"score"`
+	ComputedAt   time.Time `json:"computed_at"`
+}
+
+func (apiCfg *apiConfig) getTrendingHandler(w http.ResponseWriter, req *http.Request) {
+	rows, err := apiCfg.dbQueries.GetTrendingHashtags(req.Context())
+
+	if err != nil {
+		respondWithError(w, "Error getting trending hashtags", http.StatusInternalServerError)
+		log.Printf("Error fetching trending hashtags: %s\n", err)
+		return
+	}
+
+	response := map[string][]trendingHashtagResponse{}
+
+	for _, window := range trending.Windows {
+		response[window.Name] = []trendingHashtagResponse{}
+	}
+
+	for _, r := range rows {
+		response[r.TimeWindow] = append(response[r.TimeWindow], trendingHashtagResponse{
+			Tag:          r.Tag,
+			Rank:         r.Rank,
+			Uses:         r.Uses,
+			PreviousUses: r.PreviousUses,
+			Score:        r.Score,
+			ComputedAt:   r.ComputedAt,
+		})
+	}
+
+	respondWithJSON(w, http.StatusOK, response)
+}
@@ -0,0 +1,399 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/firerockets/chirpy/internal/auth"
+	"github.com/firerockets/chirpy/internal/database"
+	"github.com/firerockets/chirpy/internal/realtime"
+	"github.com/firerockets/chirpy/internal/visibility"
+	"github.com/google/uuid"
+	"github.com/gorilla/websocket"
+)
+
+const (
+	wsWriteWait      = 10 * time.Second
+	wsPongWait       = time.Minute
+	wsPingPeriod     = wsPongWait * 9 / 10
+	wsMaxMessageSize = 4096
+	// wsTypingInterval is how often a client's typing messages are passed
+	// on for the same conversation. The rest are dropped.
+	wsTypingInterval = 3 * time.Second
+)
+
+var wsUpgrader = websocket.Upgrader{
+	ReadBufferSize:  1024,
+	WriteBufferSize: 1024,
+}
+
+// publishRealtime tells every instance about an event. In a transaction
+// it's only delivered once that commits.
+func publishRealtime(ctx context.Context, q *database.Queries, e realtime.Event) error {
+	payload, err := e.Payload()
+	if err != nil {
+		return err
+	}
+
+	return q.PublishRealtimeEvent(ctx, payload)
+}
+
+// wsRequest is a message sent by the client.
+type wsRequest struct {
+	Type           string    `json:"type"`
+	ChirpID        uuid.UUID `json:"chirp_id"`
+	ConversationID uuid.UUID `json:"conversation_id"`
+	Token          string    `json:"token"`
+}
+
+// wsResponse is a message sent to the client. Only the fields that go with
+// its type are set.
+type wsResponse struct {
+	Type           string                `json:"type"`
+	ChirpID        string                `json:"chirp_id,omitempty"`
+	ConversationID string                `json:"conversation_id,omitempty"`
+	UserID         string                `json:"user_id,omitempty"`
+	Notification   *notificationResponse `json:"notification,omitempty"`
+	UnreadCount    *int64                `json:"unread_count,omitempty"`
+	Poll           *pollResponse         `json:"poll,omitempty"`
+	Message        *messageResponse      `json:"message,omitempty"`
+	ExpiresAt      *time.Time            `json:"expires_at,omitempty"`
+	Error          string                `json:"error,omitempty"`
+}
+
+// wsHandler opens the WebSocket a client gets its notifications, direct
+// messages, typing presence and the live counters of the chirps it
+// subscribed to on. The access token is
+// checked again when it expires, so clients send a fresh one with an "auth"
+// message before that or get disconnected.
+func (apiCfg *apiConfig) wsHandler(w http.ResponseWriter, req *http.Request) {
+	usrID, ok := apiCfg.authenticateUser(w, req)
+
+	if !ok {
+		return
+	}
+
+	// authenticateUser already checked the token.
+	token, _ := auth.GetBearerToken(req.Header)
+	expiresAt, err := auth.JWTExpiresAt(token, apiCfg.secret)
+
+	if err != nil {
+		respondWithError(w, "Unauthorized", http.StatusUnauthorized)
+		log.Printf("Error reading token expiration: %s\n", err)
+		return
+	}
+
+	conn, err := wsUpgrader.Upgrade(w, req, nil)
+
+	if err != nil {
+		log.Printf("Error upgrading to websocket: %s\n", err)
+		return
+	}
+	defer conn.Close()
+
+	client := apiCfg.realtimeHub.Register(usrID)
+	defer apiCfg.realtimeHub.Unregister(client)
+
+	replies := make(chan wsResponse, 8)
+	tokens := make(chan string, 1)
+	done := make(chan struct{})
+
+	go func() {
+		defer close(done)
+		apiCfg.readWebSocket(req.Context(), conn, client, replies, tokens)
+	}()
+
+	expiry := time.NewTimer(time.Until(expiresAt))
+	defer expiry.Stop()
+
+	ping := time.NewTicker(wsPingPeriod)
+	defer ping.Stop()
+
+	for {
+		var err error
+
+		select {
+		case <-done:
+			return
+		case e, ok := <-client.Events():
+			if !ok {
+				closeWebSocket(conn, websocket.CloseTryAgainLater, "Too far behind, reconnect")
+				return
+			}
+
+			response, ok, loadErr := apiCfg.realtimeResponse(req.Context(), client.UserID(), e)
+			if loadErr != nil {
+				log.Printf("Error loading realtime event: %s\n", loadErr)
+				continue
+			}
+			if !ok {
+				continue
+			}
+
+			err = writeWebSocket(conn, response)
+		case response := <-replies:
+			err = writeWebSocket(conn, response)
+		case token = <-tokens:
+			expiresAt, _ = auth.JWTExpiresAt(token, apiCfg.secret)
+			expiry.Reset(time.Until(expiresAt))
+			err = writeWebSocket(conn, wsResponse{Type: "auth", ExpiresAt: &expiresAt})
+		case <-expiry.C:
+			if _, err := auth.ValidadeJWT(token, apiCfg.secret); err != nil {
+				closeWebSocket(conn, websocket.ClosePolicyViolation, "Token expired")
+				return
+			}
+			expiry.Reset(time.Second)
+		case <-ping.C:
+			conn.SetWriteDeadline(time.Now().Add(wsWriteWait))
+			err = conn.WriteMessage(websocket.PingMessage, nil)
+		}
+
+		if err != nil {
+			log.Printf("Error writing to websocket: %s\n", err)
+			return
+		}
+	}
+}
+
+// readWebSocket handles the client's messages until the connection fails.
+// Replies are handed to the writing loop, the only one allowed to write.
+func (apiCfg *apiConfig) readWebSocket(ctx context.Context, conn *websocket.Conn, client *realtime.Client, replies chan<- wsResponse, tokens chan string) {
+	conn.SetReadLimit(wsMaxMessageSize)
+	conn.SetReadDeadline(time.Now().Add(wsPongWait))
+	conn.SetPongHandler(func(string) error {
+		return conn.SetReadDeadline(time.Now().Add(wsPongWait))
+	})
+
+	lastTyping := map[uuid.UUID]time.Time{}
+
+	for {
+		var msg wsRequest
+		if err := conn.ReadJSON(&msg); err != nil {
+			return
+		}
+
+		var reply wsResponse
+
+		switch msg.Type {
+		case "subscribe":
+			reply = apiCfg.subscribeChirp(ctx, client, msg.ChirpID)
+		case "unsubscribe":
+			apiCfg.realtimeHub.Unsubscribe(client, msg.ChirpID)
+			reply = wsResponse{Type: "unsubscribed", ChirpID: msg.ChirpID.String()}
+		case "typing":
+			if time.Since(lastTyping[msg.ConversationID]) < wsTypingInterval {
+				continue
+			}
+			lastTyping[msg.ConversationID] = time.Now()
+
+			errMsg := apiCfg.publishTyping(ctx, client.UserID(), msg.ConversationID)
+			if errMsg == "" {
+				continue
+			}
+			reply = wsResponse{Type: "error", ConversationID: msg.ConversationID.String(), Error: errMsg}
+		case "auth":
+			usrID, err := auth.ValidadeJWT(msg.Token, apiCfg.secret)
+			if err != nil || usrID != client.UserID() {
+				reply = wsResponse{Type: "error", Error: "Invalid token"}
+				break
+			}
+
+			// Only the latest token matters, so replace one the writing
+			// loop hasn't picked up yet.
+			select {
+			case <-tokens:
+			default:
+			}
+			tokens <- msg.Token
+			continue
+		default:
+			reply = wsResponse{Type: "error", Error: "Unknown message type"}
+		}
+
+		select {
+		case replies <- reply:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// subscribeChirp follows the counters of a chirp the client can see.
+func (apiCfg *apiConfig) subscribeChirp(ctx context.Context, client *realtime.Client, chirpID uuid.UUID) wsResponse {
+	chirp, err := apiCfg.dbQueries.GetChirpById(ctx, chirpID)
+
+	if errors.Is(err, sql.ErrNoRows) {
+		return wsResponse{Type: "error", ChirpID: chirpID.String(), Error: "Chirp not found"}
+	} else if err != nil {
+		log.Printf("Error fetching chirp: %s\n", err)
+		return wsResponse{Type: "error", ChirpID: chirpID.String(), Error: "Something went wrong"}
+	}
+
+	visible, err := apiCfg.canSeeChirp(ctx, chirp, uuid.NullUUID{UUID: client.UserID(), Valid: true}, visibility.Direct)
+
+	if err != nil {
+		log.Printf("Error loading blocks and mutes: %s\n", err)
+		return wsResponse{Type: "error", ChirpID: chirpID.String(), Error: "Something went wrong"}
+	}
+
+	if !chirp.PublishedAt.Valid || !visible {
+		return wsResponse{Type: "error", ChirpID: chirpID.String(), Error: "Chirp not found"}
+	}
+
+	err = apiCfg.realtimeHub.Subscribe(client, chirpID)
+
+	if errors.Is(err, realtime.ErrTooManySubscriptions) {
+		return wsResponse{Type: "error", ChirpID: chirpID.String(), Error: "Too many subscriptions"}
+	}
+
+	return wsResponse{Type: "subscribed", ChirpID: chirpID.String()}
+}
+
+// publishTyping tells the other members of a conversation that usrID is
+// typing. It returns the error to send back, if any.
+func (apiCfg *apiConfig) publishTyping(ctx context.Context, usrID, conversationID uuid.UUID) string {
+	_, err := apiCfg.dbQueries.GetMemberConversation(ctx, database.GetMemberConversationParams{
+		ID:     conversationID,
+		UserID: usrID,
+	})
+
+	if errors.Is(err, sql.ErrNoRows) {
+		return "Conversation not found"
+	} else if err != nil {
+		log.Printf("Error fetching conversation: %s\n", err)
+		return "Something went wrong"
+	}
+
+	blocked, err := apiCfg.dbQueries.IsBlockedInConversation(ctx, database.IsBlockedInConversationParams{
+		UserID:         usrID,
+		ConversationID: conversationID,
+	})
+
+	if err != nil {
+		log.Printf("Error checking blocks: %s\n", err)
+		return "Something went wrong"
+	}
+
+	if blocked {
+		return "You can't message this conversation"
+	}
+
+	err = publishToMembers(ctx, apiCfg.dbQueries, conversationID, usrID, realtime.Event{
+		Type:           realtime.TypingEvent,
+		ActorID:        uuid.NullUUID{UUID: usrID, Valid: true},
+		ConversationID: uuid.NullUUID{UUID: conversationID, Valid: true},
+	})
+
+	if err != nil {
+		log.Printf("Error publishing typing: %s\n", err)
+		return "Something went wrong"
+	}
+
+	return ""
+}
+
+// realtimeResponse renders an event for usrID. It returns false when there
+// is nothing they may see anymore, e.g. a chirp they have been blocked from
+// since subscribing.
+func (apiCfg *apiConfig) realtimeResponse(ctx context.Context, usrID uuid.UUID, e realtime.Event) (wsResponse, bool, error) {
+	switch e.Type {
+	case realtime.NotificationEvent:
+		n, err := apiCfg.dbQueries.GetNotificationById(ctx, database.GetNotificationByIdParams{
+			ID:     e.NotificationID.UUID,
+			UserID: usrID,
+		})
+		if errors.Is(err, sql.ErrNoRows) {
+			return wsResponse{}, false, nil
+		} else if err != nil {
+			return wsResponse{}, false, err
+		}
+
+		notificationsResponse, err := apiCfg.notificationResponses(ctx, []database.Notification{n}, usrID)
+		if err != nil || len(notificationsResponse) == 0 {
+			return wsResponse{}, false, err
+		}
+
+		unread, err := apiCfg.countUnreadNotifications(ctx, usrID)
+		if err != nil {
+			return wsResponse{}, false, err
+		}
+
+		return wsResponse{
+			Type:         string(e.Type),
+			Notification: &notificationsResponse[0],
+			UnreadCount:  &unread,
+		}, true, nil
+	case realtime.PollVoteEvent:
+		chirp, err := apiCfg.dbQueries.GetChirpById(ctx, e.ChirpID.UUID)
+		if errors.Is(err, sql.ErrNoRows) {
+			return wsResponse{}, false, nil
+		} else if err != nil {
+			return wsResponse{}, false, err
+		}
+
+		viewerID := uuid.NullUUID{UUID: usrID, Valid: true}
+
+		visible, err := apiCfg.canSeeChirp(ctx, chirp, viewerID, visibility.Direct)
+		if err != nil || !visible {
+			return wsResponse{}, false, err
+		}
+
+		polls, err := apiCfg.loadChirpPolls(ctx, []database.Chirp{chirp}, viewerID)
+		if err != nil || polls[chirp.ID] == nil {
+			return wsResponse{}, false, err
+		}
+
+		return wsResponse{
+			Type:    string(e.Type),
+			ChirpID: chirp.ID.String(),
+			Poll:    polls[chirp.ID],
+		}, true, nil
+	case realtime.MessageEvent:
+		message, err := apiCfg.dbQueries.GetMessageById(ctx, e.MessageID.UUID)
+		if errors.Is(err, sql.ErrNoRows) {
+			return wsResponse{}, false, nil
+		} else if err != nil {
+			return wsResponse{}, false, err
+		}
+
+		visible, err := apiCfg.canSeeUser(ctx, message.SenderID, uuid.NullUUID{UUID: usrID, Valid: true}, visibility.Direct)
+		if err != nil || !visible {
+			return wsResponse{}, false, err
+		}
+
+		response := newMessageResponse(message)
+
+		return wsResponse{
+			Type:           string(e.Type),
+			ConversationID: message.ConversationID.String(),
+			Message:        &response,
+		}, true, nil
+	case realtime.TypingEvent:
+		visible, err := apiCfg.canSeeUser(ctx, e.ActorID.UUID, uuid.NullUUID{UUID: usrID, Valid: true}, visibility.Direct)
+		if err != nil || !visible {
+			return wsResponse{}, false, err
+		}
+
+		return wsResponse{
+			Type:           string(e.Type),
+			ConversationID: e.ConversationID.UUID.String(),
+			UserID:         e.ActorID.UUID.String(),
+		}, true, nil
+	default:
+		return wsResponse{}, false, nil
+	}
+}
+
+func writeWebSocket(conn *websocket.Conn, response wsResponse) error {
+	conn.SetWriteDeadline(time.Now().Add(wsWriteWait))
+	return conn.WriteJSON(response)
+}
+
+func closeWebSocket(conn *websocket.Conn, code int, reason string) {
+	msg := websocket.FormatCloseMessage(code, reason)
+	conn.WriteControl(websocket.CloseMessage, msg, time.Now().Add(wsWriteWait))
+}